@@ -0,0 +1,159 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/terraformerstring"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/terraformoutput"
+	"github.com/spf13/cobra"
+)
+
+const driftReportFileName = "drift.json"
+
+// reports written by this run, providers like aws import several times into the same path
+var driftReports = map[string]*terraformutils.DriftReport{}
+var driftReportsLock sync.Mutex
+
+func newDriftCmd() *cobra.Command {
+	options := ImportOptions{
+		Drift: true,
+	}
+	cmd := &cobra.Command{
+		Use:           "drift",
+		Short:         "Report drift between current state and Terraform state",
+		Long:          "Report unmanaged, deleted and changed resources comparing current state with Terraform state",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+	}
+
+	for _, subcommand := range providerImporterSubcommands() {
+		cmd.AddCommand(withRecorder(subcommand(options)))
+	}
+	return cmd
+}
+
+func reportDrift(provider terraformutils.ProviderGenerator, plan *ImportPlan, providerWrapper *providerwrapper.ProviderWrapper) error {
+	options := plan.Options
+	naming, err := terraformutils.NewNaming(options.Naming, options.NamingTemplate)
+	if err != nil {
+		return err
+	}
+	// unmanaged resources are reported with names import would generate
+	importedResource := terraformutils.NameResources(plan.ImportedResource, naming)
+	// services without resources left in the cloud are compared too, their resources in state are deleted
+	services := append([]string{}, options.Resources...)
+	for serviceName := range importedResource {
+		if !terraformerstring.ContainsString(services, serviceName) {
+			services = append(services, serviceName)
+		}
+	}
+	sort.Strings(services)
+
+	report := terraformutils.NewDriftReport()
+	if options.DriftState != "" || !strings.Contains(options.PathPattern, "{service}") {
+		// the state may hold other providers, all resources of this provider in it are expected to be imported
+		var resources []terraformutils.Resource
+		for _, serviceName := range services {
+			resources = append(resources, importedResource[serviceName]...)
+		}
+		state, err := readDriftState(provider, options, "")
+		if err != nil {
+			return err
+		}
+		types := state.ProviderTypes(provider.GetName(), terraformutils.ProviderSource(provider))
+		report.Add(terraformutils.DetectDrift(resources, state, types, providerWrapper.GetSchema()))
+	} else {
+		for _, serviceName := range services {
+			state, err := readDriftState(provider, options, serviceName)
+			if err != nil {
+				return err
+			}
+			report.Add(terraformutils.DetectDrift(importedResource[serviceName], state, nil, providerWrapper.GetSchema()))
+		}
+	}
+
+	if err := report.PrintTable(os.Stdout); err != nil {
+		return err
+	}
+	path := Path(options.PathPattern, provider.GetName(), "terraformer", options.PathOutput)
+	return exportDriftReport(report, path)
+}
+
+// readDriftState reads state given by --tfstate, or state of the service from the state backend
+func readDriftState(provider terraformutils.ProviderGenerator, options ImportOptions, serviceName string) (*terraformutils.StateV4, error) {
+	var data []byte
+	var err error
+	if options.DriftState != "" {
+		data, err = ioutil.ReadFile(options.DriftState)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		path := Path(options.PathPattern, provider.GetName(), serviceName, options.PathOutput)
+		backend, err := newStateBackend(options, provider.GetName(), serviceName)
+		if err != nil {
+			return nil, err
+		}
+		reader, ok := backend.(terraformoutput.StateReader)
+		if !ok {
+			return nil, fmt.Errorf("%s backend doesn't support reading state", backend.BackendName())
+		}
+		data, err = reader.Download(path)
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			log.Println(provider.GetName() + " no state found for " + path + ", all resources are unmanaged")
+			return &terraformutils.StateV4{}, nil
+		}
+	}
+	state, err := terraformutils.ReadTfState(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %v", err)
+	}
+	return state, nil
+}
+
+func exportDriftReport(report *terraformutils.DriftReport, path string) error {
+	reportPath := filepath.Join(path, driftReportFileName)
+	driftReportsLock.Lock()
+	defer driftReportsLock.Unlock()
+	if previous, exist := driftReports[reportPath]; exist {
+		previous.Add(report)
+		report = previous
+	}
+	driftReports[reportPath] = report
+	log.Println("Saving drift report to", reportPath)
+
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(reportPath, append(data, '\n'), os.ModePerm)
+}
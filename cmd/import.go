@@ -14,13 +14,14 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils/terraformerstring"
 
@@ -29,37 +30,67 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils/terraformoutput"
 
 	"github.com/spf13/cobra"
 )
 
 type ImportOptions struct {
-	Resources     []string
-	Excludes      []string
-	PathPattern   string
-	PathOutput    string
-	State         string
-	Bucket        string
-	Profile       string
-	Verbose       bool
-	Zone          string
-	Regions       []string
-	Projects      []string
-	ResourceGroup string
-	Connect       bool
-	Compact       bool
-	Filter        []string
-	Plan          bool `json:"-"`
-	Output        string
-	NoSort        bool
-	RetryCount    int
-	RetrySleepMs  int
+	Resources         []string
+	Excludes          []string
+	PathPattern       string
+	PathOutput        string
+	State             string
+	Bucket            string
+	Profile           string
+	Verbose           bool
+	Zone              string
+	Regions           []string
+	Accounts          []string
+	OrgRoleName       string
+	Projects          []string
+	ResourceGroup     string
+	Connect           bool
+	Link              bool
+	Compact           bool
+	Filter            []string
+	Plan              bool `json:"-"`
+	Output            string
+	NoSort            bool
+	RetryCount        int
+	RetrySleepMs      int
+	ImportMode        string
+	StateConfig       map[string]string
+	Merge             bool
+	Naming            string
+	NamingTemplate    string
+	Parallelism       int
+	RateLimit         float64
+	Sensitive         string
+	HoistMinCount     int
+	HoistAttributes   []string
+	SensitivePatterns []string
+	ProviderVersion   string
+	PluginDirs        []string
+	LockFile          string
+	ProviderConfig    []string      `json:"-"`
+	FailOn            string        `json:"-"`
+	Record            string        `json:"-"`
+	Replay            string        `json:"-"`
+	CacheDir          string        `json:"-"`
+	Resume            bool          `json:"-"`
+	CacheTTL          time.Duration `json:"-"`
+	Drift             bool          `json:"-"`
+	DriftState        string        `json:"-"`
 }
 
 const DefaultPathPattern = "{output}/{provider}/{service}/"
+const DefaultAccountPathPattern = "{output}/{provider}/{account_id}/{region}/{service}/"
 const DefaultPathOutput = "generated"
 const DefaultState = "local"
+const DefaultImportMode = "state"
+const ImportModeBlocks = "blocks"
 
 func newImportCmd() *cobra.Command {
 	options := ImportOptions{}
@@ -79,7 +110,7 @@ func newImportCmd() *cobra.Command {
 		Long:  "Don't sort resources",
 	})
 	for _, subcommand := range providerImporterSubcommands() {
-		providerCommand := subcommand(options)
+		providerCommand := withRecorder(subcommand(options))
 		_ = providerCommand.MarkPersistentFlagRequired("resources")
 		cmd.AddCommand(providerCommand)
 	}
@@ -94,31 +125,89 @@ func Import(provider terraformutils.ProviderGenerator, options ImportOptions, ar
 	}
 	defer providerWrapper.Kill()
 	providerMapping := terraformutils.NewProvidersMapping(provider)
+	scheduler := terraformutils.ProviderScheduler(provider.GetName(), options.Parallelism, options.RateLimit)
+	report := terraformutils.NewReport()
+	cache := terraformutils.NewRefreshCache(options.CacheDir, options.CacheTTL, options.Resume)
+	servicePath := func(service string) string {
+		return Path(options.PathPattern, provider.GetName(), service, options.PathOutput)
+	}
 
-	err = initAllServicesResources(providerMapping, options, args, providerWrapper)
-	if err != nil {
+	err = initAllServicesResources(providerMapping, options, args, providerWrapper, scheduler, cache)
+	var serviceErrors terraformutils.ServiceErrors
+	if errors.As(err, &serviceErrors) {
+		report.AddServiceErrors(provider.GetName(), serviceErrors, servicePath)
+	} else if err != nil {
 		return err
 	}
 
-	err = terraformutils.RefreshResourcesByProvider(providerMapping, providerWrapper)
-	if err != nil {
+	err = terraformutils.RefreshResourcesByProvider(providerMapping, providerWrapper, scheduler, cache)
+	var refreshErrors terraformutils.RefreshErrors
+	if errors.As(err, &refreshErrors) {
+		report.AddRefreshErrors(provider.GetName(), refreshErrors, servicePath)
+	} else if err != nil {
 		return err
 	}
+	report.Retries = providerWrapper.Retries()
 
 	providerMapping.ConvertTFStates(providerWrapper)
 	// change structs with additional data for each resource
 	providerMapping.CleanupProviders()
 
-	err = importFromPlan(providerMapping, options, args)
+	err = importFromPlan(providerMapping, options, args, providerWrapper)
+	if err != nil {
+		return err
+	}
 
-	return err
+	// failures are reported once everything else was generated
+	if err := exportReport(report, servicePath("terraformer")); err != nil {
+		return err
+	}
+	return report.Check(options.FailOn)
 }
 
 func initOptionsAndWrapper(provider terraformutils.ProviderGenerator, options ImportOptions, args []string) (*providerwrapper.ProviderWrapper, ImportOptions, error) {
-	err := provider.Init(args)
+	err := validateImportMode(options.ImportMode)
+	if err != nil {
+		return nil, options, err
+	}
+	if _, err = terraformutils.NewNaming(options.Naming, options.NamingTemplate); err != nil {
+		return nil, options, err
+	}
+	if err = terraformutils.ValidateFailOn(options.FailOn); err != nil {
+		return nil, options, err
+	}
+	options.Filter = terraformutils.SplitFilters(options.Filter)
+	for _, filter := range options.Filter {
+		if err = terraformutils.ValidateFilter(filter); err != nil {
+			return nil, options, err
+		}
+	}
+	if _, err = terraformutils.NewRedactor(options.Sensitive, options.SensitivePatterns, nil); err != nil {
+		return nil, options, err
+	}
+	if _, err = terraformutils.NewHoister(options.HoistMinCount, options.HoistAttributes); err != nil {
+		return nil, options, err
+	}
+	if _, err = newStateBackend(options, provider.GetName(), ""); err != nil {
+		return nil, options, err
+	}
+	if err = setProviderInstallation(provider, options); err != nil {
+		return nil, options, err
+	}
+	if options.Resume && options.CacheDir == "" {
+		return nil, options, errors.New("--resume requires --cache-dir")
+	}
+	overrides, err := providerwrapper.ParseConfigOverrides(options.ProviderConfig)
+	if err != nil {
+		return nil, options, err
+	}
+	err = provider.Init(args)
 	if err != nil {
 		return nil, options, err
 	}
+	if err = providerwrapper.CheckConfigOverrides(provider.GetConfig(), overrides); err != nil {
+		return nil, options, err
+	}
 
 	if terraformerstring.ContainsString(options.Resources, "*") {
 		log.Println("Attempting an import of ALL resources in " + provider.GetName())
@@ -142,7 +231,7 @@ func initOptionsAndWrapper(provider terraformutils.ProviderGenerator, options Im
 		options.Resources = localSlice
 	}
 
-	providerWrapper, err := providerwrapper.NewProviderWrapper(provider.GetName(), provider.GetConfig(), options.Verbose, map[string]int{"retryCount": options.RetryCount, "retrySleepMs": options.RetrySleepMs})
+	providerWrapper, err := providerwrapper.NewProviderWrapper(provider.GetName(), providerwrapper.MergeConfig(provider.GetConfig(), overrides), options.Verbose, map[string]int{"retryCount": options.RetryCount, "retrySleepMs": options.RetrySleepMs})
 	if err != nil {
 		return nil, options, err
 	}
@@ -150,33 +239,133 @@ func initOptionsAndWrapper(provider terraformutils.ProviderGenerator, options Im
 	return providerWrapper, options, nil
 }
 
-func initAllServicesResources(providersMapping *terraformutils.ProvidersMapping, options ImportOptions, args []string, providerWrapper *providerwrapper.ProviderWrapper) error {
-	numOfResources := len(options.Resources)
-	var wg sync.WaitGroup
-	wg.Add(numOfResources)
+// applyProviderConfig passes the --provider-config settings to the SDK clients of the service, settings named
+// like service args left empty by the provider flags, e.g. address or token, set them. Settings conflicting with
+// args set by flags, e.g. the region of each region imported, are rejected.
+func applyProviderConfig(service terraformutils.ServiceGenerator, options ImportOptions) error {
+	overrides, err := providerwrapper.ParseConfigOverrides(options.ProviderConfig)
+	if err != nil || len(overrides) == 0 {
+		return err
+	}
+	args := map[string]interface{}{}
+	for key, value := range service.GetArgs() {
+		args[key] = value
+	}
+	for key, value := range overrides {
+		arg, isString := args[key].(string)
+		switch {
+		case !isString || arg == value:
+		case arg == "":
+			args[key] = value
+		default:
+			return fmt.Errorf("provider setting %s=%s conflicts with %s set by the provider flags", key, value, arg)
+		}
+	}
+	args[terraformutils.ProviderConfigArg] = overrides
+	service.SetArgs(args)
+	return nil
+}
 
-	var failedServices []string
+func setProviderInstallation(provider terraformutils.ProviderGenerator, options ImportOptions) error {
+	return providerwrapper.SetInstallation(provider.GetName(), providerwrapper.Installation{
+		Version:    options.ProviderVersion,
+		PluginDirs: options.PluginDirs,
+		LockFile:   options.LockFile,
+		Source:     terraformutils.ProviderSource(provider),
+	})
+}
 
-	for _, service := range options.Resources {
-		serviceProvider := providersMapping.AddServiceToProvider(service)
-		err := serviceProvider.Init(args)
-		if err != nil {
+// withRecorder records API traffic of the command to or replays it from cassettes, see the recorder package.
+// The recorder runs once for the whole command, providers like aws import several times per command.
+func withRecorder(cmd *cobra.Command) *cobra.Command {
+	runE := cmd.RunE
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		record, _ := cmd.Flags().GetString("record")
+		replay, _ := cmd.Flags().GetString("replay")
+		if record == "" && replay == "" {
+			return runE(cmd, args)
+		}
+		if !usesRecorder(cmd.Name()) {
+			return fmt.Errorf("%s doesn't support --record and --replay, its API clients don't use the recorder", cmd.Name())
+		}
+		if err := startRecorder(record, replay); err != nil {
 			return err
 		}
-		err = initServiceResources(service, serviceProvider, options, providerWrapper)
+		defer stopRecorder()
+		return runE(cmd, args)
+	}
+	return cmd
+}
+
+// usesRecorder is true when the provider's SDK clients record and replay their traffic, replaying other
+// providers would call their APIs
+func usesRecorder(providerName string) bool {
+	providerGen, exist := providerGenerators()[providerName]
+	if !exist {
+		return false
+	}
+	provider, ok := providerGen().(terraformutils.ProviderWithRecorder)
+	return ok && provider.UsesRecorder()
+}
+
+func startRecorder(record, replay string) error {
+	switch {
+	case record != "" && replay != "":
+		return errors.New("--record and --replay can't be used together")
+	case record != "":
+		return recorder.Start(recorder.ModeRecord, record)
+	}
+	return recorder.Start(recorder.ModeReplay, replay)
+}
+
+func stopRecorder() {
+	if err := recorder.Stop(); err != nil {
+		log.Printf("[WARN] failed to save recorded API traffic: %v\n", err)
+	}
+}
+
+func initAllServicesResources(providersMapping *terraformutils.ProvidersMapping, options ImportOptions, args []string, providerWrapper *providerwrapper.ProviderWrapper, scheduler *terraformutils.Scheduler, cache *terraformutils.RefreshCache) error {
+	serviceProviders := make([]terraformutils.ProviderGenerator, len(options.Resources))
+	for i, service := range options.Resources {
+		serviceProviders[i] = providersMapping.AddServiceToProvider(service)
+		err := serviceProviders[i].Init(args)
 		if err != nil {
-			failedServices = append(failedServices, service)
+			return err
 		}
 	}
 
-	// remove providers that failed to init their service
+	var wg sync.WaitGroup
+	var failedServicesLock sync.Mutex
+	var failedServices []string
+	var serviceErrors terraformutils.ServiceErrors
+	for i, service := range options.Resources {
+		wg.Add(1)
+		go func(service string, serviceProvider terraformutils.ProviderGenerator) {
+			defer wg.Done()
+			scheduler.Run(func() {
+				err := initServiceResources(service, serviceProvider, options, providerWrapper, cache)
+				if err != nil {
+					failedServicesLock.Lock()
+					failedServices = append(failedServices, service)
+					serviceErrors = append(serviceErrors, &terraformutils.ServiceError{Service: service, Err: err})
+					failedServicesLock.Unlock()
+				}
+			})
+		}(service, serviceProviders[i])
+	}
+	wg.Wait()
+
+	// remove providers that failed to init their service, resources of other services are still imported
 	providersMapping.RemoveServices(failedServices)
 	providersMapping.ProcessResources(false)
 
+	if len(serviceErrors) > 0 {
+		return serviceErrors
+	}
 	return nil
 }
 
-func importFromPlan(providerMapping *terraformutils.ProvidersMapping, options ImportOptions, args []string) error {
+func importFromPlan(providerMapping *terraformutils.ProvidersMapping, options ImportOptions, args []string, providerWrapper *providerwrapper.ProviderWrapper) error {
 	plan := &ImportPlan{
 		Provider:         providerMapping.GetBaseProvider().GetName(),
 		Options:          options,
@@ -189,23 +378,36 @@ func importFromPlan(providerMapping *terraformutils.ProvidersMapping, options Im
 		plan.ImportedResource[service] = append(plan.ImportedResource[service], resourcesByService[service]...)
 	}
 
+	if options.Drift {
+		return reportDrift(providerMapping.GetBaseProvider(), plan, providerWrapper)
+	}
+
 	if options.Plan {
 		path := Path(options.PathPattern, providerMapping.GetBaseProvider().GetName(), "terraformer", options.PathOutput)
 		return ExportPlanFile(plan, path, "plan.json")
 	}
 
-	return ImportFromPlan(providerMapping.GetBaseProvider(), plan)
+	return ImportFromPlan(providerMapping.GetBaseProvider(), plan, providerWrapper)
 }
 
 func initServiceResources(service string, provider terraformutils.ProviderGenerator,
-	options ImportOptions, providerWrapper *providerwrapper.ProviderWrapper) error {
+	options ImportOptions, providerWrapper *providerwrapper.ProviderWrapper, cache *terraformutils.RefreshCache) error {
 	log.Println(provider.GetName() + " importing... " + service)
 	err := provider.InitService(service, options.Verbose)
 	if err != nil {
 		log.Printf("%s error importing %s, err: %s\n", provider.GetName(), service, err)
 		return err
 	}
+	if err := applyProviderConfig(provider.GetService(), options); err != nil {
+		return err
+	}
 	provider.GetService().ParseFilters(options.Filter)
+	scope := terraformutils.CacheScope(provider, service)
+	if resources, cached := cache.LoadResources(scope); cached {
+		provider.GetService().SetResources(resources)
+		log.Printf("%s resumed %s with %d cached resources\n", provider.GetName(), service, len(resources))
+		return nil
+	}
 	err = provider.GetService().InitResources()
 	if err != nil {
 		log.Printf("%s error initializing resources in service %s, err: %s\n", provider.GetName(), service, err)
@@ -214,33 +416,56 @@ func initServiceResources(service string, provider terraformutils.ProviderGenera
 
 	provider.GetService().PopulateIgnoreKeys(providerWrapper)
 	provider.GetService().InitialCleanup()
+	if err := cache.SaveResources(scope, provider.GetService().GetResources()); err != nil {
+		log.Printf("[WARN] %s failed to cache resources of %s: %v\n", provider.GetName(), service, err)
+	}
 	log.Println(provider.GetName() + " done importing " + service)
 
 	return nil
 }
 
-func ImportFromPlan(provider terraformutils.ProviderGenerator, plan *ImportPlan) error {
+func ImportFromPlan(provider terraformutils.ProviderGenerator, plan *ImportPlan, providerWrapper *providerwrapper.ProviderWrapper) error {
 	options := plan.Options
-	importedResource := plan.ImportedResource
+	if err := validateImportMode(options.ImportMode); err != nil {
+		return err
+	}
+	naming, err := terraformutils.NewNaming(options.Naming, options.NamingTemplate)
+	if err != nil {
+		return err
+	}
+	importedResource := terraformutils.NameResources(plan.ImportedResource, naming)
 	isServicePath := strings.Contains(options.PathPattern, "{service}")
 
+	// resources must get their previous names before connecting them
+	merges := map[string]*serviceMerge{}
+	if options.Merge {
+		importedResource, merges, err = mergePreviousOutput(provider, options, importedResource, isServicePath)
+		if err != nil {
+			return err
+		}
+	}
+
 	if options.Connect {
 		log.Println(provider.GetName() + " Connecting.... ")
 		importedResource = terraformutils.ConnectServices(importedResource, isServicePath, provider.GetResourceConnections())
 	}
+	if options.Link {
+		log.Println(provider.GetName() + " Linking.... ")
+		importedResource = terraformutils.LinkResources(importedResource, isServicePath)
+	}
 
 	if !isServicePath {
 		var compactedResources []terraformutils.Resource
 		for _, resources := range importedResource {
 			compactedResources = append(compactedResources, resources...)
 		}
-		e := printService(provider, "", options, compactedResources, importedResource)
+		e := printService(provider, "", options, compactedResources, importedResource, providerWrapper, merges[""])
 		if e != nil {
 			return e
 		}
 	} else {
 		for serviceName, resources := range importedResource {
-			e := printService(provider, serviceName, options, resources, importedResource)
+			e := printService(provider, serviceName, options, resources, importedResource, providerWrapper, merges[serviceName])
 			if e != nil {
 				return e
 			}
@@ -249,116 +474,312 @@ func ImportFromPlan(provider terraformutils.ProviderGenerator, plan *ImportPlan)
 	return nil
 }
 
-func printService(provider terraformutils.ProviderGenerator, serviceName string, options ImportOptions, resources []terraformutils.Resource, importedResource map[string][]terraformutils.Resource) error {
+// serviceMerge is what a previous run generated in a directory and how resources found now match it
+type serviceMerge struct {
+	previous *terraformoutput.PreviousOutput
+	result   *terraformutils.MergeResult
+}
+
+// keptImports are vanished resources not in the previous state, their code stays so their import blocks must
+// stay too. Vanished resources in state are already imported.
+func (m *serviceMerge) keptImports() []terraformutils.ExistingResource {
+	inState := map[string]struct{}{}
+	if m.previous.State != nil {
+		for _, e := range m.previous.State.ExistingResources() {
+			inState[e.Address()] = struct{}{}
+		}
+	}
+	var kept []terraformutils.ExistingResource
+	for _, e := range m.result.Vanished {
+		if _, exist := inState[e.Address()]; !exist {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func mergePreviousOutput(provider terraformutils.ProviderGenerator, options ImportOptions, importedResource map[string][]terraformutils.Resource, isServicePath bool) (map[string][]terraformutils.Resource, map[string]*serviceMerge, error) {
+	mergedResource := map[string][]terraformutils.Resource{}
+	merges := map[string]*serviceMerge{}
+	if isServicePath {
+		for serviceName, resources := range importedResource {
+			merge, err := mergeService(provider, options, serviceName, resources)
+			if err != nil {
+				return nil, nil, err
+			}
+			merges[serviceName] = merge
+			mergedResource[serviceName] = merge.result.Resources
+		}
+		updateMergedReferences(importedResource, mergedResource)
+		return mergedResource, merges, nil
+	}
+	// all services share one directory, so names must be unique across them
+	var services []string
+	for serviceName := range importedResource {
+		services = append(services, serviceName)
+	}
+	sort.Strings(services)
+	var compactedResources []terraformutils.Resource
+	for _, serviceName := range services {
+		compactedResources = append(compactedResources, importedResource[serviceName]...)
+	}
+	merge, err := mergeService(provider, options, "", compactedResources)
+	if err != nil {
+		return nil, nil, err
+	}
+	merges[""] = merge
+	i := 0
+	for _, serviceName := range services {
+		n := len(importedResource[serviceName])
+		mergedResource[serviceName] = merge.result.Resources[i : i+n]
+		i += n
+	}
+	updateMergedReferences(importedResource, mergedResource)
+	return mergedResource, merges, nil
+}
+
+// resources keeping their previous names are referenced by them
+func updateMergedReferences(importedResource, mergedResource map[string][]terraformutils.Resource) {
+	renamed := map[string]string{}
+	for serviceName, resources := range importedResource {
+		for i, r := range resources {
+			if name := mergedResource[serviceName][i].ResourceName; name != r.ResourceName {
+				renamed[r.InstanceInfo.Type+"."+r.ResourceName] = name
+			}
+		}
+	}
+	terraformutils.UpdateReferences(mergedResource, renamed)
+}
+
+func mergeService(provider terraformutils.ProviderGenerator, options ImportOptions, serviceName string, resources []terraformutils.Resource) (*serviceMerge, error) {
+	path := Path(options.PathPattern, provider.GetName(), serviceName, options.PathOutput)
+	backend, err := newStateBackend(options, provider.GetName(), serviceName)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := terraformoutput.ReadPreviousOutput(path, backend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous output of %s: %v", path, err)
+	}
+	result := terraformutils.MergeResources(resources, previous.Resources, previous.Declared)
+	return &serviceMerge{previous: previous, result: result}, nil
+}
+
+func printService(provider terraformutils.ProviderGenerator, serviceName string, options ImportOptions, resources []terraformutils.Resource, importedResource map[string][]terraformutils.Resource, providerWrapper *providerwrapper.ProviderWrapper, merge *serviceMerge) error {
 	log.Println(provider.GetName() + " save " + serviceName)
 	// Print HCL files for Resources
 	path := Path(options.PathPattern, provider.GetName(), serviceName, options.PathOutput)
-	err := terraformoutput.OutputHclFiles(resources, provider, path, serviceName, options.Compact, options.Output, !options.NoSort)
+	redactor, err := terraformutils.NewRedactor(options.Sensitive, options.SensitivePatterns, providerWrapper.GetSchema())
 	if err != nil {
 		return err
 	}
-	tfStateFile, err := terraformutils.PrintTfState(resources)
+	var sensitiveVariables []terraformutils.SensitiveVariable
+	if redactor != nil {
+		for i := range resources {
+			sensitiveVariables = append(sensitiveVariables, redactor.Redact(&resources[i])...)
+		}
+	}
+	hoister, err := terraformutils.NewHoister(options.HoistMinCount, options.HoistAttributes)
 	if err != nil {
 		return err
 	}
-	// print or upload State file
-	if options.State == "bucket" {
-		log.Println(provider.GetName() + " upload tfstate to  bucket " + options.Bucket)
-		bucket := terraformoutput.BucketState{
-			Name: options.Bucket,
-		}
-		if err := bucket.BucketUpload(path, tfStateFile); err != nil {
-			return err
-		}
-		// create Bucket file
-		if bucketStateDataFile, err := terraformutils.Print(bucket.BucketGetTfData(path), map[string]struct{}{}, options.Output, !options.NoSort); err == nil {
-			terraformoutput.PrintFile(path+"/bucket.tf", bucketStateDataFile)
-		}
-	} else {
-		if serviceName == "" {
-			log.Println(provider.GetName() + " save tfstate")
+	variablesPath := path + "/variables." + terraformoutput.GetFileExtension(options.Output)
+	localsPath := path + "/locals." + terraformoutput.GetFileExtension(options.Output)
+	var hoistedValues []terraformutils.HoistedValue
+	if hoister != nil {
+		if merge != nil && (fileExists(variablesPath) || fileExists(localsPath)) {
+			log.Printf("[WARN] %s keeps %s, repeated values of new resources aren't hoisted\n", provider.GetName(), variablesPath)
 		} else {
-			log.Println(provider.GetName() + " save tfstate for " + serviceName)
-		}
-		if err := ioutil.WriteFile(path+"/terraform.tfstate", tfStateFile, os.ModePerm); err != nil {
-			return err
+			hoistedValues = hoister.Hoist(resources)
 		}
 	}
+	if merge != nil {
+		err = terraformoutput.MergeHclFiles(resources, merge.result, provider, path, serviceName, options.Compact, options.Output, !options.NoSort, providerWrapper.GetSchema())
+		logMerge(provider, serviceName, resources, merge)
+	} else {
+		err = terraformoutput.OutputHclFiles(resources, provider, path, serviceName, options.Compact, options.Output, !options.NoSort, providerWrapper.GetSchema())
+	}
+	if err != nil {
+		return err
+	}
+	lockFile, err := providerwrapper.LockFileData(provider.GetName())
+	if err != nil {
+		return err
+	}
+	if lockFile != nil {
+		terraformoutput.PrintFile(path+"/"+providerwrapper.DefaultLockFile, lockFile)
+	}
+	backend, err := newStateBackend(options, provider.GetName(), serviceName)
+	if err != nil {
+		return err
+	}
+	if options.ImportMode == ImportModeBlocks {
+		err = printImportBlocks(provider, serviceName, options, path, resources, backend, merge)
+	} else {
+		err = printTfState(provider, serviceName, options, path, resources, providerWrapper, backend, merge)
+	}
+	if err != nil {
+		return err
+	}
 	// Print hcl variables.tf
+	variables := map[string]map[string]map[string]interface{}{}
+	variables["data"] = map[string]map[string]interface{}{}
+	variables["data"]["terraform_remote_state"] = map[string]interface{}{}
 	if serviceName != "" {
-		if options.Connect && len(provider.GetResourceConnections()[serviceName]) > 0 {
-			variables := map[string]map[string]map[string]interface{}{}
-			variables["data"] = map[string]map[string]interface{}{}
-			variables["data"]["terraform_remote_state"] = map[string]interface{}{}
-			if options.State == "bucket" {
-				bucket := terraformoutput.BucketState{
-					Name: options.Bucket,
-				}
-				for k := range provider.GetResourceConnections()[serviceName] {
-					if _, exist := importedResource[k]; !exist {
-						continue
-					}
-					variables["data"]["terraform_remote_state"][k] = map[string]interface{}{
-						"backend": "gcs",
-						"config":  bucket.BucketGetTfData(strings.ReplaceAll(path, serviceName, k)),
-					}
-				}
-			} else {
-				for k := range provider.GetResourceConnections()[serviceName] {
-					if _, exist := importedResource[k]; !exist {
-						continue
-					}
-					variables["data"]["terraform_remote_state"][k] = map[string]interface{}{
-						"backend": "local",
-						"config": map[string]interface{}{
-							"path": strings.Repeat("../", strings.Count(path, "/")) + strings.ReplaceAll(path, serviceName, k) + "terraform.tfstate",
-						},
-					}
-				}
-			}
-			// create variables file
-			if len(provider.GetResourceConnections()[serviceName]) > 0 && options.Connect && len(variables["data"]["terraform_remote_state"]) > 0 {
-				variablesFile, err := terraformutils.Print(variables, map[string]struct{}{"config": {}}, options.Output, !options.NoSort)
-				if err != nil {
-					return err
-				}
-				terraformoutput.PrintFile(path+"/variables."+terraformoutput.GetFileExtension(options.Output), variablesFile)
+		var connected []string
+		if options.Connect {
+			for k := range provider.GetResourceConnections()[serviceName] {
+				connected = append(connected, k)
 			}
 		}
-	} else {
-		if options.Connect {
-			variables := map[string]map[string]map[string]interface{}{}
-			variables["data"] = map[string]map[string]interface{}{}
-			variables["data"]["terraform_remote_state"] = map[string]interface{}{}
-			if options.State == "bucket" {
-				bucket := terraformoutput.BucketState{
-					Name: options.Bucket,
-				}
-				variables["data"]["terraform_remote_state"]["local"] = map[string]interface{}{
-					"backend": "gcs",
-					"config":  bucket.BucketGetTfData(path),
-				}
-			} else {
-				variables["data"]["terraform_remote_state"]["local"] = map[string]interface{}{
-					"backend": "local",
-					"config": map[string]interface{}{
-						"path": "terraform.tfstate",
-					},
-				}
+		if options.Link {
+			connected = append(connected, terraformutils.ReferencedRemoteStates(resources)...)
+		}
+		for _, k := range connected {
+			if _, exist := importedResource[k]; !exist {
+				continue
 			}
-			// create variables file
-			if options.Connect {
-				variablesFile, err := terraformutils.Print(variables, map[string]struct{}{"config": {}}, options.Output, !options.NoSort)
-				if err != nil {
-					return err
-				}
-				terraformoutput.PrintFile(path+"/variables."+terraformoutput.GetFileExtension(options.Output), variablesFile)
+			connectedBackend, err := newStateBackend(options, provider.GetName(), k)
+			if err != nil {
+				return err
+			}
+			variables["data"]["terraform_remote_state"][k] = map[string]interface{}{
+				"backend": connectedBackend.BackendName(),
+				"config":  connectedBackend.RemoteStateConfig(strings.ReplaceAll(path, serviceName, k), path),
 			}
 		}
+	} else if options.Connect {
+		variables["data"]["terraform_remote_state"]["local"] = map[string]interface{}{
+			"backend": backend.BackendName(),
+			"config":  backend.RemoteStateConfig(path, path),
+		}
+	}
+	variables["variable"] = terraformutils.SensitiveVariablesData(sensitiveVariables)
+	for name, variable := range terraformutils.HoistedVariablesData(hoistedValues) {
+		variables["variable"][name] = variable
+	}
+	if len(variables["variable"]) == 0 {
+		delete(variables, "variable")
+	}
+	// hoisted maps and lists are printed to locals.tf
+	localsFile, err := terraformutils.PrintHoistedLocals(hoistedValues, options.Output, !options.NoSort)
+	if err != nil {
+		return err
+	}
+	if localsFile != nil {
+		terraformoutput.PrintFile(localsPath, localsFile)
+	}
+	// create variables file, merge keeps the existing one
+	if merge != nil && fileExists(variablesPath) {
+		if len(sensitiveVariables) > 0 {
+			log.Printf("[WARN] %s keeps %s, declare %d sensitive variables there if new resources use them\n", provider.GetName(), variablesPath, len(sensitiveVariables))
+		}
+		return nil
+	}
+	if len(variables["data"]["terraform_remote_state"]) > 0 || len(variables["variable"]) > 0 {
+		variablesFile, err := terraformutils.Print(variables, map[string]struct{}{"config": {}}, options.Output, !options.NoSort)
+		if err != nil {
+			return err
+		}
+		terraformoutput.PrintFile(variablesPath, variablesFile)
 	}
 	return nil
 }
 
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func newStateBackend(options ImportOptions, providerName, serviceName string) (terraformoutput.StateBackend, error) {
+	config := map[string]string{}
+	for k, v := range options.StateConfig {
+		config[k] = Path(v, providerName, serviceName, options.PathOutput)
+	}
+	return terraformoutput.NewStateBackend(options.State, options.Bucket, config)
+}
+
+func logMerge(provider terraformutils.ProviderGenerator, serviceName string, resources []terraformutils.Resource, merge *serviceMerge) {
+	newResources := 0
+	for _, r := range resources {
+		if merge.result.IsNew(r) {
+			newResources++
+		}
+	}
+	for _, e := range merge.result.Vanished {
+		log.Printf("[WARN] %s %s (id %s) was not found anymore, remove it manually if it was deleted\n", provider.GetName(), e.Address(), e.ID)
+	}
+	log.Printf("%s merge %s: %d unchanged, %d new, %d vanished\n", provider.GetName(), serviceName, len(resources)-newResources, newResources, len(merge.result.Vanished))
+}
+
+func printTfState(provider terraformutils.ProviderGenerator, serviceName string, options ImportOptions, path string, resources []terraformutils.Resource, providerWrapper *providerwrapper.ProviderWrapper, backend terraformoutput.StateBackend, merge *serviceMerge) error {
+	providerSource := terraformutils.ProviderSource(provider)
+	state, err := terraformutils.NewTfStateV4(resources, providerSource, providerWrapper.GetSchema())
+	if err != nil {
+		return err
+	}
+	// vanished resources stay in state as long as they are declared
+	if merge != nil && merge.previous.State != nil {
+		state.KeepPrevious(merge.previous.State, merge.result.Vanished, providerSource)
+	}
+	tfStateFile, err := state.Print()
+	if err != nil {
+		return err
+	}
+	// print or upload State file
+	if serviceName == "" {
+		log.Println(provider.GetName() + " save tfstate to " + backend.BackendName() + " backend")
+	} else {
+		log.Println(provider.GetName() + " save tfstate for " + serviceName + " to " + backend.BackendName() + " backend")
+	}
+	if err := backend.Upload(path, tfStateFile); err != nil {
+		return err
+	}
+	return printBackend(options, path, backend)
+}
+
+func printImportBlocks(provider terraformutils.ProviderGenerator, serviceName string, options ImportOptions, path string, resources []terraformutils.Resource, backend terraformoutput.StateBackend, merge *serviceMerge) error {
+	if serviceName == "" {
+		log.Println(provider.GetName() + " save import blocks")
+	} else {
+		log.Println(provider.GetName() + " save import blocks for " + serviceName)
+	}
+	var kept []terraformutils.ExistingResource
+	if merge != nil {
+		kept = merge.keptImports()
+	}
+	importsFile, err := terraformutils.PrintImportBlocks(resources, kept, options.Output, !options.NoSort)
+	if err != nil {
+		return err
+	}
+	terraformoutput.PrintFile(path+"/imports."+terraformoutput.GetFileExtension(options.Output), importsFile)
+	// state is created by terraform itself, so only the backend for it is needed
+	return printBackend(options, path, backend)
+}
+
+// create backend file, not needed when state is kept in the default local terraform.tfstate. It keeps the name
+// bucket.tf of the former bucket state, so code generated before isn't left with a second backend block.
+func printBackend(options ImportOptions, path string, backend terraformoutput.StateBackend) error {
+	backendData := terraformoutput.BackendGetTfData(backend, path)
+	if backendData == nil {
+		return nil
+	}
+	backendFile, err := terraformutils.Print(backendData, map[string]struct{}{}, options.Output, !options.NoSort)
+	if err != nil {
+		return err
+	}
+	terraformoutput.PrintFile(path+"/bucket."+terraformoutput.GetFileExtension(options.Output), backendFile)
+	return nil
+}
+
+func validateImportMode(importMode string) error {
+	switch importMode {
+	case "", DefaultImportMode, ImportModeBlocks:
+		return nil
+	}
+	return fmt.Errorf("unsupported import mode: %s", importMode)
+}
+
 func Path(pathPattern, providerName, serviceName, output string) string {
 	return strings.NewReplacer(
 		"{provider}", providerName,
@@ -396,16 +817,41 @@ func providerServices(provider terraformutils.ProviderGenerator) []string {
 func baseProviderFlags(flag *pflag.FlagSet, options *ImportOptions, sampleRes, sampleFilters string) {
 	flag.BoolVarP(&options.Connect, "connect", "c", true, "")
 	flag.BoolVarP(&options.Compact, "compact", "C", false, "")
+	flag.BoolVarP(&options.Link, "link", "", false, "reference resources whose ID, ARN, self_link or name is used by other resources")
 	flag.StringSliceVarP(&options.Resources, "resources", "r", []string{}, sampleRes)
 	flag.StringSliceVarP(&options.Excludes, "excludes", "x", []string{}, sampleRes)
 	flag.StringVarP(&options.PathPattern, "path-pattern", "p", DefaultPathPattern, "{output}/{provider}/")
 	flag.StringVarP(&options.PathOutput, "path-output", "o", DefaultPathOutput, "")
-	flag.StringVarP(&options.State, "state", "s", DefaultState, "local or bucket")
-	flag.StringVarP(&options.Bucket, "bucket", "b", "", "gs://terraform-state")
-	flag.StringSliceVarP(&options.Filter, "filter", "f", []string{}, sampleFilters)
+	flag.StringVarP(&options.State, "state", "s", DefaultState, "local, bucket (gcs), s3, azurerm or http")
+	flag.StringVarP(&options.Bucket, "bucket", "b", "", "gs://terraform-state or s3://terraform-state")
+	flag.StringToStringVarP(&options.StateConfig, "state-config", "", map[string]string{}, "key=value backend settings, e.g. region=eu-west-1,key={path}/terraform.tfstate")
+	flag.StringArrayVarP(&options.Filter, "filter", "f", []string{}, sampleFilters)
 	flag.BoolVarP(&options.Verbose, "verbose", "v", false, "")
 	flag.BoolVarP(&options.NoSort, "no-sort", "S", false, "set to disable sorting of HCL")
-	flag.StringVarP(&options.Output, "output", "O", "hcl", "output format hcl or json")
+	flag.StringVarP(&options.Output, "output", "O", "hcl", "output format hcl, hcl2 or json")
 	flag.IntVarP(&options.RetryCount, "retry-number", "n", 5, "number of retries to perform when refresh fails")
 	flag.IntVarP(&options.RetrySleepMs, "retry-sleep-ms", "m", 300, "time in ms to sleep between retries")
+	flag.StringVarP(&options.ImportMode, "import-mode", "", DefaultImportMode, "state or blocks (Terraform 1.5+ import blocks instead of tfstate)")
+	flag.BoolVarP(&options.Merge, "merge", "", false, "merge into previously generated files, keeping names of existing resources")
+	flag.IntVarP(&options.Parallelism, "parallelism", "", terraformutils.DefaultParallelism, "number of services, regions and resources processed concurrently")
+	flag.Float64VarP(&options.RateLimit, "task-rate-limit", "", 0, "max service enumerations and resource refreshes started per second for the provider, 0 for no limit")
+	flag.StringVarP(&options.Naming, "naming", "", terraformutils.NamingLegacy, "legacy, tags or template")
+	flag.StringVarP(&options.NamingTemplate, "naming-template", "", "", "e.g. {tag:Name|name} or {type_short}_{id}")
+	flag.StringVarP(&options.Sensitive, "sensitive", "", terraformutils.SensitiveKeep, "keep sensitive values, redact them from HCL into variables or strip them from HCL and state")
+	flag.StringArrayVarP(&options.SensitivePatterns, "sensitive-pattern", "", []string{}, "regular expression of attributes handled as sensitive, e.g. password$ or aws_db_instance.password")
+	flag.IntVarP(&options.HoistMinCount, "hoist-min-count", "", 0, "hoist values of --hoist-attributes used by at least this many resources of a service into variables and locals, 0 to keep them")
+	flag.StringSliceVarP(&options.HoistAttributes, "hoist-attributes", "", terraformutils.DefaultHoistAttributes, "attributes whose repeated values are hoisted")
+	flag.StringVarP(&options.ProviderVersion, "provider-version", "", "", "provider version constraint, e.g. ~> 4.0, the highest matching version is used")
+	flag.StringArrayVarP(&options.PluginDirs, "plugin-dir", "", []string{}, "filesystem mirror of providers searched instead of .terraform and ~/.terraform.d")
+	flag.StringVarP(&options.LockFile, "lock-file", "", providerwrapper.DefaultLockFile, "dependency lock file with provider versions and checksums to verify")
+	flag.StringArrayVarP(&options.ProviderConfig, "provider-config", "", []string{}, "key=value provider setting checked against the provider schema, e.g. endpoints.s3=http://localhost:4566")
+	flag.StringVarP(&options.Record, "record", "", "", "record API traffic of the provider to cassettes in this directory, supported by aws")
+	flag.StringVarP(&options.Replay, "replay", "", "", "replay API traffic from cassettes in this directory instead of calling APIs, supported by aws")
+	flag.StringVarP(&options.CacheDir, "cache-dir", "", "", "directory caching enumerated resources and refreshed states as they complete")
+	flag.BoolVarP(&options.Resume, "resume", "", false, "use resources and states cached in --cache-dir instead of enumerating and refreshing them again")
+	flag.DurationVarP(&options.CacheTTL, "cache-ttl", "", terraformutils.DefaultCacheTTL, "max age of cached entries used by --resume, 0 for no limit")
+	flag.StringVarP(&options.FailOn, "fail-on", "", terraformutils.FailOnNone, "exit with an error when a service fails (service), also when a resource is dropped (resource) or never (none)")
+	if options.Drift {
+		flag.StringVarP(&options.DriftState, "tfstate", "", "", "state file to compare with, by default state of the state backend")
+	}
 }
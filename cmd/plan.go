@@ -16,11 +16,13 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
 	"github.com/spf13/cobra"
 )
 
@@ -46,8 +48,9 @@ func newPlanCmd() *cobra.Command {
 	}
 
 	for _, subcommand := range providerImporterSubcommands() {
-		cmd.AddCommand(subcommand(options))
+		cmd.AddCommand(withRecorder(subcommand(options)))
 	}
+	cmd.AddCommand(newPlanShowCmd(), newPlanSelectCmd(false), newPlanSelectCmd(true), newPlanRenameCmd())
 	return cmd
 }
 
@@ -80,9 +83,24 @@ func newCmdPlanImporter(options ImportOptions) *cobra.Command {
 				}
 			}
 
-			return ImportFromPlan(provider, plan)
+			if err = setProviderInstallation(provider, plan.Options); err != nil {
+				return err
+			}
+			// provider settings aren't saved in plans as they may hold credentials
+			overrides, err := providerwrapper.ParseConfigOverrides(options.ProviderConfig)
+			if err != nil {
+				return err
+			}
+			providerWrapper, err := providerwrapper.NewProviderWrapper(provider.GetName(), providerwrapper.MergeConfig(provider.GetConfig(), overrides), plan.Options.Verbose, map[string]int{"retryCount": plan.Options.RetryCount, "retrySleepMs": plan.Options.RetrySleepMs})
+			if err != nil {
+				return err
+			}
+			defer providerWrapper.Kill()
+
+			return ImportFromPlan(provider, plan, providerWrapper)
 		},
 	}
+	cmd.Flags().StringArrayVarP(&options.ProviderConfig, "provider-config", "", []string{}, "key=value provider setting checked against the provider schema")
 	return cmd
 }
 
@@ -108,22 +126,36 @@ func LoadPlanfile(path string) (*ImportPlan, error) {
 }
 
 func ExportPlanFile(plan *ImportPlan, path, filename string) error {
-	plan.Version = version
-
 	planfilePath := filepath.Join(path, filename)
 	log.Println("Saving planfile to", planfilePath)
 
 	if err := os.MkdirAll(path, os.ModePerm); err != nil {
 		return err
 	}
+	return savePlanfile(plan, planfilePath)
+}
+
+// savePlanfile replaces the planfile atomically, so an interrupted edit doesn't leave a broken plan
+func savePlanfile(plan *ImportPlan, planfilePath string) error {
+	plan.Version = version
 
-	f, err := os.OpenFile(planfilePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
+	f, err := ioutil.TempFile(filepath.Dir(planfilePath), "."+filepath.Base(planfilePath)+".*")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	defer os.Remove(f.Name())
 
 	enc := json.NewEncoder(f)
 	enc.SetIndent("", "\t")
-	return enc.Encode(plan)
+	if err := enc.Encode(plan); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), planfilePath)
 }
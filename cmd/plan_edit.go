@@ -0,0 +1,161 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/spf13/cobra"
+)
+
+func newPlanShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show PLANFILE",
+		Short: "Show resources of a planfile",
+		Long:  "Show resources of a planfile grouped by service, with names the import will generate",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := LoadPlanfile(args[0])
+			if err != nil {
+				return err
+			}
+			naming, err := terraformutils.NewNaming(plan.Options.Naming, plan.Options.NamingTemplate)
+			if err != nil {
+				return err
+			}
+			return printPlan(cmd.OutOrStdout(), terraformutils.NameResources(plan.ImportedResource, naming))
+		},
+	}
+}
+
+// newPlanSelectCmd returns the select command keeping resources passing the filters, or the exclude
+// command removing resources matching them
+func newPlanSelectCmd(exclude bool) *cobra.Command {
+	var filters []string
+	use, short := "select", "Keep resources of a planfile matching filters"
+	if exclude {
+		use, short = "exclude", "Remove resources of a planfile matching filters"
+	}
+	cmd := &cobra.Command{
+		Use:   use + " PLANFILE",
+		Short: short,
+		Long:  short + ", filters have the same syntax as --filter of import",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(filters) == 0 {
+				return fmt.Errorf("at least one filter is required")
+			}
+			for _, filter := range filters {
+				if err := terraformutils.ValidateFilter(filter); err != nil {
+					return err
+				}
+			}
+			plan, err := LoadPlanfile(args[0])
+			if err != nil {
+				return err
+			}
+			if err := nameResources(plan); err != nil {
+				return err
+			}
+			service := terraformutils.Service{}
+			service.ParseFilters(terraformutils.SplitFilters(filters))
+
+			total, kept := 0, 0
+			for serviceName, resources := range plan.ImportedResource {
+				selected := terraformutils.SelectResources(resources, service.Filter, exclude)
+				total += len(resources)
+				kept += len(selected)
+				if len(selected) == 0 {
+					delete(plan.ImportedResource, serviceName)
+					continue
+				}
+				plan.ImportedResource[serviceName] = selected
+			}
+			log.Printf("%d of %d resources kept\n", kept, total)
+			return savePlanfile(plan, args[0])
+		},
+	}
+	cmd.Flags().StringArrayVarP(&filters, "filter", "f", []string{}, `e.g. tags.env == "prod" or Type=sg;Name=vpc_id;Value=VPC_ID`)
+	return cmd
+}
+
+func newPlanRenameCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rename PLANFILE ADDRESS NAME",
+		Short: "Rename a resource of a planfile",
+		Long:  "Rename the resource at ADDRESS, e.g. aws_instance.web, as shown by plan show",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := LoadPlanfile(args[0])
+			if err != nil {
+				return err
+			}
+			if err := nameResources(plan); err != nil {
+				return err
+			}
+			if err := terraformutils.RenameResource(plan.ImportedResource, args[1], args[2]); err != nil {
+				return err
+			}
+			return savePlanfile(plan, args[0])
+		},
+	}
+}
+
+// nameResources names resources like import would, so filters and renames refer to names shown by plan
+// show. Import keeps the names from now on.
+func nameResources(plan *ImportPlan) error {
+	if plan.Options.Naming == terraformutils.NamingLegacy {
+		return nil
+	}
+	naming, err := terraformutils.NewNaming(plan.Options.Naming, plan.Options.NamingTemplate)
+	if err != nil {
+		return err
+	}
+	plan.ImportedResource = terraformutils.NameResources(plan.ImportedResource, naming)
+	plan.Options.Naming = terraformutils.NamingLegacy
+	plan.Options.NamingTemplate = ""
+	return nil
+}
+
+func printPlan(w io.Writer, resources map[string][]terraformutils.Resource) error {
+	var services []string
+	for serviceName := range resources {
+		services = append(services, serviceName)
+	}
+	sort.Strings(services)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tRESOURCE\tID")
+	count := 0
+	for _, serviceName := range services {
+		serviceResources := append([]terraformutils.Resource{}, resources[serviceName]...)
+		sort.Slice(serviceResources, func(i, j int) bool {
+			return serviceResources[i].InstanceInfo.Id < serviceResources[j].InstanceInfo.Id
+		})
+		for _, r := range serviceResources {
+			fmt.Fprintf(tw, "%s\t%s.%s\t%s\n", serviceName, r.InstanceInfo.Type, r.ResourceName, r.InstanceState.ID)
+		}
+		count += len(serviceResources)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d resources in %d services\n", count, len(services))
+	return err
+}
@@ -14,7 +14,10 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"strings"
+	"sync"
 
 	awsterraformer "github.com/GoogleCloudPlatform/terraformer/providers/aws"
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
@@ -27,47 +30,10 @@ func newCmdAwsImporter(options ImportOptions) *cobra.Command {
 		Short: "Import current state to Terraform configuration from AWS",
 		Long:  "Import current state to Terraform configuration from AWS",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			originalResources := options.Resources
-			originalRegions := options.Regions
-			originalPathPattern := options.PathPattern
-
-			if len(options.Regions) > 0 {
-				shouldSpecifyPathRegion := len(options.Regions) > 1
-				globalResources, eastOnlyResources, regionalResources := parseAndGroupResources(originalResources)
-				options.Resources = globalResources
-				options.Regions = []string{awsterraformer.GlobalRegion}
-				e := importGlobalResources(options)
-				if e != nil {
-					return e
-				}
-
-				options.Resources = eastOnlyResources
-				options.Regions = []string{awsterraformer.MainRegionPublicPartition}
-				e = importEastOnlyResources(options)
-				if e != nil {
-					return e
-				}
-
-				options.Resources = regionalResources
-				options.Regions = originalRegions
-				if len(options.Resources) > 0 { // don't import anything and potentially override global resources
-					if len(globalResources) > 0 {
-						shouldSpecifyPathRegion = true // we should keep global resources away from regional
-					}
-					for _, region := range originalRegions {
-						e := importRegionResources(options, originalPathPattern, region, shouldSpecifyPathRegion)
-						if e != nil {
-							return e
-						}
-					}
-				}
-				return nil
+			if len(options.Accounts) > 0 {
+				return importAccounts(options)
 			}
-			err := importRegionResources(options, options.PathPattern, awsterraformer.NoRegion, false)
-			if err != nil {
-				return err
-			}
-			return nil
+			return importAccountResources(options, awsterraformer.Account{})
 		},
 	}
 	cmd.AddCommand(listCmd(newAWSProvider()))
@@ -75,9 +41,84 @@ func newCmdAwsImporter(options ImportOptions) *cobra.Command {
 
 	cmd.PersistentFlags().StringVarP(&options.Profile, "profile", "", "default", "prod")
 	cmd.PersistentFlags().StringSliceVarP(&options.Regions, "regions", "", []string{}, "eu-west-1,eu-west-2,us-east-1")
+	cmd.PersistentFlags().StringSliceVarP(&options.Accounts, "accounts", "", []string{}, "IDs of accounts to import, or * for all accounts of the organization")
+	cmd.PersistentFlags().StringVarP(&options.OrgRoleName, "org-role-name", "", awsterraformer.DefaultOrgRoleName, "role assumed in each account of --accounts")
 	return cmd
 }
 
+// importAccounts imports each account with its role into {account_id} of the path pattern, an account
+// which fails doesn't stop the others
+func importAccounts(options ImportOptions) error {
+	if options.PathPattern == DefaultPathPattern {
+		options.PathPattern = DefaultAccountPathPattern
+	}
+	if !strings.Contains(options.PathPattern, "{account_id}") {
+		return fmt.Errorf("--path-pattern must contain {account_id} to import several accounts, e.g. %s", DefaultAccountPathPattern)
+	}
+	region := ""
+	if len(options.Regions) > 0 {
+		region = options.Regions[0]
+	}
+	accounts, err := awsterraformer.ResolveAccounts(options.Profile, region, options.Accounts, options.OrgRoleName)
+	if err != nil {
+		return err
+	}
+	incomplete := &incompleteImport{}
+	var failed []string
+	for _, account := range accounts {
+		log.Println("aws importing account " + account.ID)
+		err := incomplete.keep(importAccountResources(options, account))
+		if err != nil {
+			log.Printf("aws error importing account %s: %v\n", account.ID, err)
+			failed = append(failed, account.ID)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d accounts failed: %s", len(failed), len(accounts), strings.Join(failed, ", "))
+	}
+	return incomplete.err
+}
+
+func importAccountResources(options ImportOptions, account awsterraformer.Account) error {
+	originalResources := options.Resources
+	originalRegions := options.Regions
+	originalPathPattern := strings.ReplaceAll(options.PathPattern, "{account_id}", account.ID)
+	options.PathPattern = originalPathPattern
+
+	if len(options.Regions) > 0 {
+		shouldSpecifyPathRegion := len(options.Regions) > 1
+		globalResources, eastOnlyResources, regionalResources := parseAndGroupResources(originalResources)
+		incomplete := &incompleteImport{}
+		options.Resources = globalResources
+		options.Regions = []string{awsterraformer.GlobalRegion}
+		e := incomplete.keep(importGlobalResources(options, account))
+		if e != nil {
+			return e
+		}
+
+		options.Resources = eastOnlyResources
+		options.Regions = []string{awsterraformer.MainRegionPublicPartition}
+		e = incomplete.keep(importEastOnlyResources(options, account))
+		if e != nil {
+			return e
+		}
+
+		options.Resources = regionalResources
+		options.Regions = originalRegions
+		if len(options.Resources) > 0 { // don't import anything and potentially override global resources
+			if len(globalResources) > 0 {
+				shouldSpecifyPathRegion = true // we should keep global resources away from regional
+			}
+			e = incomplete.keep(importRegionsResources(options, originalPathPattern, originalRegions, shouldSpecifyPathRegion, account))
+			if e != nil {
+				return e
+			}
+		}
+		return incomplete.err
+	}
+	return importRegionResources(options, options.PathPattern, awsterraformer.NoRegion, false, account)
+}
+
 // returns global, east-only, regional resources
 func parseAndGroupResources(allResources []string) ([]string, []string, []string) {
 	var globalResources, eastOnlyResources, regionalResources []string
@@ -93,38 +134,85 @@ func parseAndGroupResources(allResources []string) ([]string, []string, []string
 	return globalResources, eastOnlyResources, regionalResources
 }
 
-func importGlobalResources(options ImportOptions) error {
+func importGlobalResources(options ImportOptions, account awsterraformer.Account) error {
 	if len(options.Resources) > 0 {
-		return importRegionResources(options, options.PathPattern, awsterraformer.GlobalRegion, false)
+		return importRegionResources(options, options.PathPattern, awsterraformer.GlobalRegion, false, account)
 	}
 	return nil
 }
 
-func importEastOnlyResources(options ImportOptions) error {
+func importEastOnlyResources(options ImportOptions, account awsterraformer.Account) error {
 	if len(options.Resources) > 0 {
-		return importRegionResources(options, options.PathPattern, awsterraformer.MainRegionPublicPartition, false)
+		return importRegionResources(options, options.PathPattern, awsterraformer.MainRegionPublicPartition, false, account)
 	}
 	return nil
 }
 
-func importRegionResources(options ImportOptions, originalPathPattern string, region string, shouldSpecifyPathRegion bool) error {
+// regions are imported concurrently, they share the scheduler of the aws provider
+func importRegionsResources(options ImportOptions, originalPathPattern string, regions []string, shouldSpecifyPathRegion bool, account awsterraformer.Account) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(regions))
+	parallelism := options.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	slots := make(chan struct{}, parallelism)
+	for i, region := range regions {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, region string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			errs[i] = importRegionResources(options, originalPathPattern, region, shouldSpecifyPathRegion, account)
+		}(i, region)
+	}
+	wg.Wait()
+	incomplete := &incompleteImport{}
+	for _, err := range errs {
+		if err := incomplete.keep(err); err != nil {
+			return err
+		}
+	}
+	return incomplete.err
+}
+
+func importRegionResources(options ImportOptions, originalPathPattern string, region string, shouldSpecifyPathRegion bool, account awsterraformer.Account) error {
 	provider := newAWSProvider()
 	options.PathPattern = originalPathPattern
+	if strings.Contains(originalPathPattern, "{region}") {
+		options.PathPattern = strings.ReplaceAll(originalPathPattern, "{region}", pathRegion(region))
+	} else if region != awsterraformer.GlobalRegion && region != awsterraformer.NoRegion && shouldSpecifyPathRegion {
+		options.PathPattern += region + "/"
+	}
 	if region != awsterraformer.GlobalRegion && region != awsterraformer.NoRegion {
-		if shouldSpecifyPathRegion {
-			options.PathPattern += region + "/"
-		}
 		log.Println(provider.GetName() + " importing region " + region)
 	} else {
 		log.Println(provider.GetName() + " importing default region")
 	}
-	err := Import(provider, options, []string{region, options.Profile})
+	args := []string{region, options.Profile}
+	if account.ID != "" {
+		args = append(args, account.ID, account.RoleARN)
+	}
+	err := Import(provider, options, args)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// pathRegion names the directory of a region in path patterns with {region}
+func pathRegion(region string) string {
+	switch region {
+	case awsterraformer.GlobalRegion:
+		return "global"
+	case awsterraformer.NoRegion:
+		return "default"
+	}
+	return region
+}
+
 func newAWSProvider() terraformutils.ProviderGenerator {
 	return &awsterraformer.AWSProvider{}
 }
@@ -0,0 +1,74 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+)
+
+const reportFileName = "terraformer-report.json"
+
+// reports written by this run, providers like aws import several times into the same path
+var reports = map[string]*terraformutils.Report{}
+var reportsLock sync.Mutex
+
+func exportReport(report *terraformutils.Report, path string) error {
+	for _, s := range report.FailedServices {
+		log.Printf("[WARN] %s service %s failed: %s\n", s.Provider, s.Service, s.Reason)
+	}
+	reportPath := filepath.Join(path, reportFileName)
+	reportsLock.Lock()
+	defer reportsLock.Unlock()
+	if previous, exist := reports[reportPath]; exist {
+		previous.Add(report)
+		report = previous
+	}
+	reports[reportPath] = report
+	log.Printf("%d services failed, %d resources dropped, %d retries, saving report to %s\n",
+		len(report.FailedServices), len(report.DroppedResources), report.Retries, reportPath)
+
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(reportPath, append(data, '\n'), os.ModePerm)
+}
+
+// incompleteImport keeps the first IncompleteImportError of imports running one after another, so later
+// imports still run and the error is returned at the end
+type incompleteImport struct {
+	err error
+}
+
+func (i *incompleteImport) keep(err error) error {
+	var incomplete *terraformutils.IncompleteImportError
+	if errors.As(err, &incomplete) {
+		if i.err == nil {
+			i.err = err
+		}
+		return nil
+	}
+	return err
+}
@@ -27,6 +27,8 @@ func NewCmdRoot() *cobra.Command {
 	}
 	cmd.AddCommand(newImportCmd())
 	cmd.AddCommand(newPlanCmd())
+	cmd.AddCommand(newDriftCmd())
+	cmd.AddCommand(newRunCmd())
 	cmd.AddCommand(versionCmd)
 	return cmd
 }
@@ -0,0 +1,346 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const DefaultRunConfig = "terraformer.yaml"
+
+// RunConfig declares import jobs, read from terraformer.yaml
+type RunConfig struct {
+	// Parallel runs jobs of different providers concurrently, jobs of one provider always run in sequence
+	// as providers read credentials from the environment. Jobs recording or replaying run alone, the
+	// recorder applies to all API clients of the process.
+	Parallel bool     `yaml:"parallel"`
+	Jobs     []RunJob `yaml:"jobs"`
+}
+
+// RunJob is one import, options are mapped onto the flags of the provider command. Strings may refer to
+// environment variables as ${NAME}, e.g. for tokens.
+type RunJob struct {
+	Name     string `yaml:"name"`
+	Provider string `yaml:"provider"`
+	// Args are provider specific flags like regions, projects, profile or token
+	Args        map[string]interface{} `yaml:"args"`
+	Resources   []string               `yaml:"resources"`
+	Excludes    []string               `yaml:"excludes"`
+	Filters     []string               `yaml:"filters"`
+	PathPattern string                 `yaml:"path_pattern"`
+	PathOutput  string                 `yaml:"path_output"`
+	Output      string                 `yaml:"output"`
+	State       string                 `yaml:"state"`
+	Bucket      string                 `yaml:"bucket"`
+	StateConfig map[string]string      `yaml:"state_config"`
+	// ProviderConfig are provider settings like endpoints, nested maps address blocks as with --provider-config
+	ProviderConfig map[string]interface{} `yaml:"provider_config"`
+	// Flags are other import flags, e.g. compact, connect or naming
+	Flags map[string]interface{} `yaml:"flags"`
+}
+
+func newRunCmd() *cobra.Command {
+	configPath := DefaultRunConfig
+	cmd := &cobra.Command{
+		Use:           "run",
+		Short:         "Run imports declared in a config file",
+		Long:          "Run imports of several providers declared as jobs in a config file, e.g. terraformer.yaml",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := LoadRunConfig(configPath)
+			if err != nil {
+				return err
+			}
+			return runJobs(config)
+		},
+	}
+	cmd.Flags().StringVarP(&configPath, "file", "f", DefaultRunConfig, "config file declaring import jobs")
+	return cmd
+}
+
+func LoadRunConfig(path string) (*RunConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	config := &RunConfig{}
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(config.Jobs) == 0 {
+		return nil, fmt.Errorf("no jobs declared in %s", path)
+	}
+	names := map[string]struct{}{}
+	for i := range config.Jobs {
+		job := &config.Jobs[i]
+		if job.Name == "" {
+			job.Name = fmt.Sprintf("%s-%d", job.Provider, i+1)
+		}
+		if _, exist := names[job.Name]; exist {
+			return nil, fmt.Errorf("job %s is declared twice", job.Name)
+		}
+		names[job.Name] = struct{}{}
+	}
+	return config, nil
+}
+
+// runJobs validates all jobs before running any of them, failed jobs don't stop other jobs
+func runJobs(config *RunConfig) error {
+	jobArgs := make([][]string, len(config.Jobs))
+	for i, job := range config.Jobs {
+		args, err := job.validate()
+		if err != nil {
+			return fmt.Errorf("job %s: %v", job.Name, err)
+		}
+		jobArgs[i] = args
+	}
+
+	errs := make([]error, len(config.Jobs))
+	var recorderLock sync.RWMutex
+	run := func(i int) {
+		job := config.Jobs[i]
+		if job.usesRecorder() {
+			recorderLock.Lock()
+			defer recorderLock.Unlock()
+		} else {
+			recorderLock.RLock()
+			defer recorderLock.RUnlock()
+		}
+		log.Printf("job %s: importing %s\n", job.Name, job.Provider)
+		errs[i] = newJobCmd(jobArgs[i]).Execute()
+		if errs[i] != nil {
+			log.Printf("job %s failed: %v\n", job.Name, errs[i])
+			return
+		}
+		log.Printf("job %s: done\n", job.Name)
+	}
+	if config.Parallel {
+		byProvider := map[string][]int{}
+		var providers []string
+		for i, job := range config.Jobs {
+			if _, exist := byProvider[job.Provider]; !exist {
+				providers = append(providers, job.Provider)
+			}
+			byProvider[job.Provider] = append(byProvider[job.Provider], i)
+		}
+		var wg sync.WaitGroup
+		for _, provider := range providers {
+			wg.Add(1)
+			go func(jobs []int) {
+				defer wg.Done()
+				for _, i := range jobs {
+					run(i)
+				}
+			}(byProvider[provider])
+		}
+		wg.Wait()
+	} else {
+		for i := range config.Jobs {
+			run(i)
+		}
+	}
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, config.Jobs[i].Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d jobs failed: %s", len(failed), len(config.Jobs), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// newJobCmd returns the import command with its own options, so jobs don't share state
+func newJobCmd(args []string) *cobra.Command {
+	cmd := newImportCmd()
+	cmd.SetArgs(args)
+	return cmd
+}
+
+// validate checks the job against the provider command and its supported services, it returns the
+// arguments of the import command
+func (job RunJob) validate() ([]string, error) {
+	if job.Provider == "" {
+		return nil, fmt.Errorf("provider is required")
+	}
+	if len(job.Resources) == 0 {
+		return nil, fmt.Errorf("resources are required")
+	}
+	args := append([]string{job.Provider}, job.flags()...)
+	cmd, _, err := newImportCmd().Find(args)
+	if err != nil || cmd.Name() != job.Provider || cmd.PersistentFlags().Lookup("resources") == nil {
+		return nil, fmt.Errorf("unsupported provider: %s", job.Provider)
+	}
+	if err := cmd.ParseFlags(args[1:]); err != nil {
+		return nil, err
+	}
+	if job.usesRecorder() && !usesRecorder(job.Provider) {
+		return nil, fmt.Errorf("%s doesn't support record and replay", job.Provider)
+	}
+	if job.Output != "" && job.Output != "hcl" && job.Output != "hcl2" && job.Output != "json" {
+		return nil, fmt.Errorf("unsupported output format: %s", job.Output)
+	}
+	for _, filter := range job.Filters {
+		if err := terraformutils.ValidateFilter(filter); err != nil {
+			return nil, err
+		}
+	}
+
+	providerGen, exist := providerGenerators()[job.Provider]
+	if !exist {
+		log.Printf("[WARN] job %s: resources of %s are checked when importing\n", job.Name, job.Provider)
+		return args, nil
+	}
+	supported := providerGen().GetSupportedService()
+	for _, services := range [][]string{job.Resources, job.Excludes} {
+		for _, service := range services {
+			if _, exist := supported[service]; !exist && service != "*" {
+				return nil, fmt.Errorf("%s doesn't support resource %s", job.Provider, service)
+			}
+		}
+	}
+	return args, nil
+}
+
+// usesRecorder is true when the job records or replays API traffic
+func (job RunJob) usesRecorder() bool {
+	for _, options := range []map[string]interface{}{job.Args, job.Flags} {
+		for _, name := range []string{"record", "replay"} {
+			if value, exist := options[name]; exist && flagValue(value) != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flags maps the job onto flags of the provider command
+func (job RunJob) flags() []string {
+	var flags []string
+	add := func(name string, value interface{}) {
+		flags = append(flags, "--"+name+"="+flagValue(value))
+	}
+	add("resources", job.Resources)
+	if len(job.Excludes) > 0 {
+		add("excludes", job.Excludes)
+	}
+	for _, filter := range job.Filters {
+		add("filter", filter)
+	}
+	if job.PathPattern != "" {
+		add("path-pattern", job.PathPattern)
+	}
+	if job.PathOutput != "" {
+		add("path-output", job.PathOutput)
+	}
+	if job.Output != "" {
+		add("output", job.Output)
+	}
+	if job.State != "" {
+		add("state", job.State)
+	}
+	if job.Bucket != "" {
+		add("bucket", job.Bucket)
+	}
+	if len(job.StateConfig) > 0 {
+		add("state-config", job.StateConfig)
+	}
+	var settings []string
+	for key, value := range flattenSettings("", job.ProviderConfig) {
+		settings = append(settings, key+"="+value)
+	}
+	sort.Strings(settings)
+	for _, setting := range settings {
+		add("provider-config", setting)
+	}
+	for _, options := range []map[string]interface{}{job.Args, job.Flags} {
+		var names []string
+		for name := range options {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			add(strings.ReplaceAll(name, "_", "-"), options[name])
+		}
+	}
+	return flags
+}
+
+// flattenSettings joins keys of nested maps with dots, endpoints: {s3: url} is endpoints.s3=url
+func flattenSettings(prefix string, settings map[string]interface{}) map[string]string {
+	flat := map[string]string{}
+	for key, value := range settings {
+		if nested, isMap := value.(map[string]interface{}); isMap {
+			for nestedKey, nestedValue := range flattenSettings(prefix+key+".", nested) {
+				flat[nestedKey] = nestedValue
+			}
+			continue
+		}
+		flat[prefix+key] = flagValue(value)
+	}
+	return flat
+}
+
+// flagValue formats lists as comma separated and maps as key=value pairs, as pflag parses them
+func flagValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return os.ExpandEnv(v)
+	case []string:
+		var values []string
+		for _, s := range v {
+			values = append(values, flagValue(s))
+		}
+		return strings.Join(values, ",")
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			values = append(values, flagValue(item))
+		}
+		return strings.Join(values, ",")
+	case map[string]string:
+		var pairs []string
+		for k, s := range v {
+			pairs = append(pairs, k+"="+flagValue(s))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case map[string]interface{}:
+		var pairs []string
+		for k, item := range v {
+			pairs = append(pairs, k+"="+flagValue(item))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// AllAccounts imports all active accounts of the organization
+const AllAccounts = "*"
+
+// DefaultOrgRoleName is the role AWS Organizations creates in member accounts
+const DefaultOrgRoleName = "OrganizationAccountAccessRole"
+
+var accountID = regexp.MustCompile(`^[0-9]{12}$`)
+
+// Account is imported with the credentials of the role, the account of the credentials is imported
+// without role
+type Account struct {
+	ID      string
+	RoleARN string
+}
+
+// ResolveAccounts returns the accounts to import from IDs or AllAccounts, with the ARN of the role named
+// roleName in each. All accounts are listed through Organizations with the credentials of the profile.
+func ResolveAccounts(profile, region string, ids []string, roleName string) ([]Account, error) {
+	service := &AWSService{Service: terraformutils.Service{Args: map[string]interface{}{
+		"region":  region,
+		"profile": profile,
+	}}}
+	config, err := service.generateConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config.Region == "" {
+		config.Region = MainRegionPublicPartition
+	}
+	identity, err := sts.NewFromConfig(config).GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, err
+	}
+	caller, err := arn.Parse(StringValue(identity.Arn))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 1 && ids[0] == AllAccounts {
+		ids = nil
+		p := organizations.NewListAccountsPaginator(organizations.NewFromConfig(config), &organizations.ListAccountsInput{})
+		for p.HasMorePages() {
+			page, err := p.NextPage(context.TODO())
+			if err != nil {
+				return nil, fmt.Errorf("failed to list accounts of the organization: %v", err)
+			}
+			for _, account := range page.Accounts {
+				if account.Status == types.AccountStatusActive {
+					ids = append(ids, StringValue(account.Id))
+				}
+			}
+		}
+	}
+
+	var accounts []Account
+	seen := map[string]struct{}{}
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if !accountID.MatchString(id) {
+			return nil, fmt.Errorf("invalid account ID %q, 12 digits or %s for all accounts are expected", id, AllAccounts)
+		}
+		if _, exist := seen[id]; exist {
+			continue
+		}
+		seen[id] = struct{}{}
+		account := Account{ID: id}
+		if id != StringValue(identity.Account) {
+			account.RoleARN = arn.ARN{Partition: caller.Partition, Service: "iam", AccountID: id, Resource: "role/" + roleName}.String()
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
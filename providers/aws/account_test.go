@@ -0,0 +1,56 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
+)
+
+func TestResolveAccounts(t *testing.T) {
+	if err := recorder.Start(recorder.ModeReplay, filepath.Join("test_data", "accounts")); err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Stop() //nolint
+
+	accounts, err := ResolveAccounts("", "eu-west-1", []string{AllAccounts}, DefaultOrgRoleName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Account{
+		// the account of the credentials is imported without role
+		{ID: "111111111111"},
+		{ID: "222222222222", RoleARN: "arn:aws:iam::222222222222:role/OrganizationAccountAccessRole"},
+	}
+	if !reflect.DeepEqual(accounts, expected) {
+		t.Errorf("expected %v, got %v", expected, accounts)
+	}
+
+	accounts, err = ResolveAccounts("", "eu-west-1", []string{"444444444444", "444444444444"}, "Audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []Account{{ID: "444444444444", RoleARN: "arn:aws:iam::444444444444:role/Audit"}}
+	if !reflect.DeepEqual(accounts, expected) {
+		t.Errorf("expected %v, got %v", expected, accounts)
+	}
+
+	if _, err := ResolveAccounts("", "eu-west-1", []string{"prod"}, DefaultOrgRoleName); err == nil {
+		t.Errorf("expected an error for an invalid account ID")
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+)
+
+var apiGatewayV2AllowEmptyValues = []string{"tags."}
+
+// APIGatewayV2Generator imports HTTP and WebSocket APIs, resources created by API Gateway for quick create
+// APIs are part of the API
+type APIGatewayV2Generator struct {
+	AWSService
+}
+
+func (g *APIGatewayV2Generator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := apigatewayv2.NewFromConfig(config)
+
+	if err := g.loadApis(svc); err != nil {
+		return err
+	}
+	if err := g.loadDomainNames(svc); err != nil {
+		return err
+	}
+	if err := g.loadVpcLinks(svc); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (g *APIGatewayV2Generator) loadApis(svc *apigatewayv2.Client) error {
+	var nextToken *string
+	for {
+		output, err := svc.GetApis(context.TODO(), &apigatewayv2.GetApisInput{NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+		for _, api := range output.Items {
+			if api.ApiGatewayManaged {
+				continue
+			}
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(api.ApiId),
+				StringValue(api.ApiId)+"_"+StringValue(api.Name),
+				"aws_apigatewayv2_api",
+				"aws",
+				apiGatewayV2AllowEmptyValues))
+			if err := g.loadStages(svc, api.ApiId); err != nil {
+				return err
+			}
+			if err := g.loadRoutes(svc, api.ApiId); err != nil {
+				return err
+			}
+			if err := g.loadIntegrations(svc, api.ApiId); err != nil {
+				return err
+			}
+			if err := g.loadAuthorizers(svc, api.ApiId); err != nil {
+				return err
+			}
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return nil
+}
+
+// apiResource creates a resource of an API, its ID is only unique in the API
+func (g *APIGatewayV2Generator) apiResource(apiID *string, id *string, resourceType string, attributes map[string]string) terraformutils.Resource {
+	attributes["api_id"] = StringValue(apiID)
+	return terraformutils.NewResource(
+		StringValue(id),
+		StringValue(apiID)+"/"+StringValue(id),
+		resourceType,
+		"aws",
+		attributes,
+		apiGatewayV2AllowEmptyValues,
+		map[string]interface{}{},
+	)
+}
+
+func (g *APIGatewayV2Generator) loadStages(svc *apigatewayv2.Client, apiID *string) error {
+	var nextToken *string
+	for {
+		output, err := svc.GetStages(context.TODO(), &apigatewayv2.GetStagesInput{ApiId: apiID, NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+		for _, stage := range output.Items {
+			if stage.ApiGatewayManaged {
+				continue
+			}
+			g.Resources = append(g.Resources, g.apiResource(apiID, stage.StageName, "aws_apigatewayv2_stage", map[string]string{
+				"name": StringValue(stage.StageName),
+			}))
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (g *APIGatewayV2Generator) loadRoutes(svc *apigatewayv2.Client, apiID *string) error {
+	var nextToken *string
+	for {
+		output, err := svc.GetRoutes(context.TODO(), &apigatewayv2.GetRoutesInput{ApiId: apiID, NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+		for _, route := range output.Items {
+			if route.ApiGatewayManaged {
+				continue
+			}
+			g.Resources = append(g.Resources, g.apiResource(apiID, route.RouteId, "aws_apigatewayv2_route", map[string]string{
+				"route_key": StringValue(route.RouteKey),
+			}))
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (g *APIGatewayV2Generator) loadIntegrations(svc *apigatewayv2.Client, apiID *string) error {
+	var nextToken *string
+	for {
+		output, err := svc.GetIntegrations(context.TODO(), &apigatewayv2.GetIntegrationsInput{ApiId: apiID, NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+		for _, integration := range output.Items {
+			if integration.ApiGatewayManaged {
+				continue
+			}
+			g.Resources = append(g.Resources, g.apiResource(apiID, integration.IntegrationId, "aws_apigatewayv2_integration", map[string]string{
+				"integration_type": string(integration.IntegrationType),
+			}))
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (g *APIGatewayV2Generator) loadAuthorizers(svc *apigatewayv2.Client, apiID *string) error {
+	var nextToken *string
+	for {
+		output, err := svc.GetAuthorizers(context.TODO(), &apigatewayv2.GetAuthorizersInput{ApiId: apiID, NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+		for _, authorizer := range output.Items {
+			g.Resources = append(g.Resources, g.apiResource(apiID, authorizer.AuthorizerId, "aws_apigatewayv2_authorizer", map[string]string{
+				"name": StringValue(authorizer.Name),
+			}))
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (g *APIGatewayV2Generator) loadDomainNames(svc *apigatewayv2.Client) error {
+	var nextToken *string
+	for {
+		output, err := svc.GetDomainNames(context.TODO(), &apigatewayv2.GetDomainNamesInput{NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+		for _, domainName := range output.Items {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(domainName.DomainName),
+				StringValue(domainName.DomainName),
+				"aws_apigatewayv2_domain_name",
+				"aws",
+				apiGatewayV2AllowEmptyValues))
+			if err := g.loadAPIMappings(svc, domainName.DomainName); err != nil {
+				return err
+			}
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (g *APIGatewayV2Generator) loadAPIMappings(svc *apigatewayv2.Client, domainName *string) error {
+	var nextToken *string
+	for {
+		output, err := svc.GetApiMappings(context.TODO(), &apigatewayv2.GetApiMappingsInput{DomainName: domainName, NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+		for _, mapping := range output.Items {
+			g.Resources = append(g.Resources, terraformutils.NewResource(
+				StringValue(mapping.ApiMappingId),
+				StringValue(domainName)+"/"+StringValue(mapping.ApiMappingId),
+				"aws_apigatewayv2_api_mapping",
+				"aws",
+				map[string]string{
+					"api_id":      StringValue(mapping.ApiId),
+					"domain_name": StringValue(domainName),
+					"stage":       StringValue(mapping.Stage),
+				},
+				apiGatewayV2AllowEmptyValues,
+				map[string]interface{}{},
+			))
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (g *APIGatewayV2Generator) loadVpcLinks(svc *apigatewayv2.Client) error {
+	var nextToken *string
+	for {
+		output, err := svc.GetVpcLinks(context.TODO(), &apigatewayv2.GetVpcLinksInput{NextToken: nextToken})
+		if err != nil {
+			return err
+		}
+		for _, vpcLink := range output.Items {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(vpcLink.VpcLinkId),
+				StringValue(vpcLink.VpcLinkId)+"_"+StringValue(vpcLink.Name),
+				"aws_apigatewayv2_vpc_link",
+				"aws",
+				apiGatewayV2AllowEmptyValues))
+		}
+		nextToken = output.NextToken
+		if nextToken == nil {
+			break
+		}
+	}
+	return nil
+}
@@ -15,18 +15,22 @@
 package aws
 
 import (
-	"os"
-	"strconv"
+	"context"
+	"log"
 
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
+	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/pkg/errors"
 	"github.com/zclconf/go-cty/cty"
 )
 
 type AWSProvider struct { //nolint
 	terraformutils.Provider
-	region  string
-	profile string
+	region    string
+	profile   string
+	accountID string
+	roleARN   string
 }
 
 const GlobalRegion = "aws-global"
@@ -42,6 +46,7 @@ var SupportedGlobalResources = []string{
 	"iam",
 	"organization",
 	"route53",
+	"shield",
 	"waf",
 }
 
@@ -61,6 +66,23 @@ func (p AWSProvider) GetResourceConnections() map[string]map[string][]string {
 				// TF ALB TG attachment logic doesn't work well with references (doesn't interpolate)
 			},
 		},
+		"api_gateway_v2": {
+			// domain names aren't referenced by mappings, a domain name would reference itself
+			"api_gateway_v2": []string{
+				"api_id", "id",
+				"connection_id", "id",
+			},
+			// Lambda integrations and authorizers are given the function's ARN or invoke ARN
+			"lambda": []string{
+				"integration_uri", "arn",
+				"integration_uri", "invoke_arn",
+				"authorizer_uri", "invoke_arn",
+			},
+			// private integrations are given the listener's ARN
+			"alb":    []string{"integration_uri", "id"},
+			"subnet": []string{"subnet_ids", "id"},
+			"sg":     []string{"security_group_ids", "id"},
+		},
 		"auto_scaling": {
 			"sg":     []string{"security_groups", "id"},
 			"subnet": []string{"vpc_zone_identifier", "id"},
@@ -75,6 +97,10 @@ func (p AWSProvider) GetResourceConnections() map[string]map[string][]string {
 			"subnet": []string{"subnet_ids", "id"},
 			"sg":     []string{"security_group_ids", "id"},
 		},
+		"dhcp_options": {
+			"dhcp_options": []string{"dhcp_options_id", "id"},
+			"vpc":          []string{"vpc_id", "id"},
+		},
 		"ebs": {
 			// TF EBS attachment logic doesn't work well with references (doesn't interpolate)
 		},
@@ -83,6 +109,7 @@ func (p AWSProvider) GetResourceConnections() map[string]map[string][]string {
 			"subnet": []string{"network_configuration.subnets", "id"},
 			"sg":     []string{"network_configuration.security_groups", "id"},
 		},
+		"egress_only_igw": {"vpc": []string{"vpc_id", "id"}},
 		"eks": {
 			"subnet": []string{"vpc_config.subnet_ids", "id"},
 			"sg":     []string{"vpc_config.security_group_ids", "id"},
@@ -91,7 +118,13 @@ func (p AWSProvider) GetResourceConnections() map[string]map[string][]string {
 			"sg":     []string{"security_groups", "id"},
 			"subnet": []string{"subnets", "id"},
 		},
-		"igw": {"vpc": []string{"vpc_id", "id"}},
+		"flow_log": {
+			"vpc":    []string{"vpc_id", "id"},
+			"subnet": []string{"subnet_id", "id"},
+			"eni":    []string{"eni_id", "id"},
+		},
+		"guardduty": {"guardduty": []string{"detector_id", "id"}},
+		"igw":       {"vpc": []string{"vpc_id", "id"}},
 		"identitystore": {
 			"identitystore": []string{
 				"group_id", "id",
@@ -106,6 +139,15 @@ func (p AWSProvider) GetResourceConnections() map[string]map[string][]string {
 			"subnet": []string{"subnet_ids", "id"},
 			"vpc":    []string{"vpc_id", "id"},
 		},
+		"networkfirewall": {
+			"networkfirewall": []string{
+				"firewall_policy_arn", "id",
+				"firewall_policy.stateful_rule_group_reference.resource_arn", "id",
+				"firewall_policy.stateless_rule_group_reference.resource_arn", "id",
+			},
+			"vpc":    []string{"vpc_id", "id"},
+			"subnet": []string{"subnet_mapping.subnet_id", "id"},
+		},
 		"organization": {
 			"organization": []string{
 				"policy_id", "id",
@@ -113,15 +155,26 @@ func (p AWSProvider) GetResourceConnections() map[string]map[string][]string {
 				"target_id", "id",
 			},
 		},
+		"ram": {
+			"ram": []string{"resource_share_arn", "id"},
+			// resources shared with other accounts, mostly networks
+			"subnet":              []string{"resource_arn", "arn"},
+			"transit_gateway":     []string{"resource_arn", "arn"},
+			"managed_prefix_list": []string{"resource_arn", "arn"},
+		},
 		"rds": {
 			"subnet": []string{"subnet_ids", "id"},
 			"sg":     []string{"vpc_security_group_ids", "id"},
 		},
 		"route_table": {
-			"route_table": []string{"route_table_id", "id"},
-			"subnet":      []string{"subnet_id", "id"},
-			"vpc":         []string{"vpc_id", "id"},
+			"route_table":         []string{"route_table_id", "id"},
+			"subnet":              []string{"subnet_id", "id"},
+			"vpc":                 []string{"vpc_id", "id"},
+			"egress_only_igw":     []string{"route.egress_only_gateway_id", "id"},
+			"managed_prefix_list": []string{"route.destination_prefix_list_id", "id"},
+			"vpc_endpoint":        []string{"route.vpc_endpoint_id", "id"},
 		},
+		"shield": {"cloudfront": []string{"resource_arn", "arn"}},
 		"sns": {
 			"sns": []string{"topic_arn", "id"},
 			"sqs": []string{"endpoint", "arn"},
@@ -133,13 +186,42 @@ func (p AWSProvider) GetResourceConnections() map[string]map[string][]string {
 				"security_group_id", "id",
 				"source_security_group_id", "id",
 			},
+			"managed_prefix_list": []string{
+				"egress.prefix_list_ids", "id",
+				"ingress.prefix_list_ids", "id",
+				"prefix_list_ids", "id",
+			},
+		},
+		"ssoadmin": {
+			"ssoadmin": []string{"permission_set_arn", "arn"},
+			"identitystore": []string{
+				"principal_id", "group_id",
+				"principal_id", "user_id",
+			},
 		},
 		"subnet": {"vpc": []string{"vpc_id", "id"}},
 		"transit_gateway": {
-			"vpc":             []string{"vpc_id", "id"},
-			"transit_gateway": []string{"transit_gateway_id", "id"},
-			"subnet":          []string{"subnet_ids", "id"},
-			"vpn_connection":  []string{"vpn_connection_id", "id"},
+			"vpc":            []string{"vpc_id", "id"},
+			"subnet":         []string{"subnet_ids", "id"},
+			"vpn_connection": []string{"vpn_connection_id", "id"},
+			"transit_gateway": []string{
+				"transit_gateway_id", "id",
+				"transit_gateway_route_table_id", "id",
+				"transit_gateway_attachment_id", "id",
+			},
+		},
+		"vpc_cidr_block_association": {"vpc": []string{"vpc_id", "id"}},
+		"vpc_endpoint": {
+			"vpc":         []string{"vpc_id", "id"},
+			"subnet":      []string{"subnet_ids", "id"},
+			"sg":          []string{"security_group_ids", "id"},
+			"route_table": []string{"route_table_ids", "id"},
+		},
+		"vpc_endpoint_service": {
+			"alb": []string{
+				"gateway_load_balancer_arns", "id",
+				"network_load_balancer_arns", "id",
+			},
 		},
 		"vpn_gateway": {"vpc": []string{"vpc_id", "id"}},
 		"vpn_connection": {
@@ -157,6 +239,11 @@ func (p AWSProvider) GetProviderData(arg ...string) map[string]interface{} {
 	} else if p.region != NoRegion {
 		awsConfig["region"] = p.region
 	}
+	if p.roleARN != "" {
+		awsConfig["assume_role"] = map[string]interface{}{
+			"role_arn": p.roleARN,
+		}
+	}
 
 	return map[string]interface{}{
 		"provider": map[string]interface{}{
@@ -165,51 +252,71 @@ func (p AWSProvider) GetProviderData(arg ...string) map[string]interface{} {
 	}
 }
 
+// GetConfig configures the provider like GetProviderData, with the profile and the role of the account imported,
+// so the provider refreshes its credentials itself. The provider can't ask for MFA tokens, profiles asking for one
+// pass the credentials resolved by Terraformer instead, they expire like the session of the token.
 func (p *AWSProvider) GetConfig() cty.Value {
-	if p.region != GlobalRegion {
-		return cty.ObjectVal(map[string]cty.Value{
-			"region":                 cty.StringVal(p.region),
-			"skip_region_validation": cty.True,
-		})
-	}
-	return cty.ObjectVal(map[string]cty.Value{
+	config := map[string]cty.Value{
 		"region":                 cty.StringVal(""),
 		"skip_region_validation": cty.True,
-	})
+	}
+	if p.region != GlobalRegion {
+		config["region"] = cty.StringVal(p.region)
+	}
+	if recorder.Replaying() {
+		return cty.ObjectVal(config)
+	}
+	if !p.requiresMFA() {
+		if p.profile != "default" && p.profile != "" {
+			config["profile"] = cty.StringVal(p.profile)
+		}
+		if p.roleARN != "" {
+			config["assume_role"] = cty.ListVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{
+				"role_arn": cty.StringVal(p.roleARN),
+			})})
+		}
+		return cty.ObjectVal(config)
+	}
+	service := &AWSService{}
+	service.SetArgs(p.serviceArgs())
+	awsConfig, err := service.generateConfig()
+	if err != nil {
+		log.Printf("[WARN] aws: the provider uses its own credentials, %v\n", err)
+		return cty.ObjectVal(config)
+	}
+	creds, err := awsConfig.Credentials.Retrieve(context.TODO())
+	if err != nil {
+		return cty.ObjectVal(config)
+	}
+	config["access_key"] = cty.StringVal(creds.AccessKeyID)
+	config["secret_key"] = cty.StringVal(creds.SecretAccessKey)
+	if creds.SessionToken != "" {
+		config["token"] = cty.StringVal(creds.SessionToken)
+	}
+	return cty.ObjectVal(config)
+}
+
+// requiresMFA is true when the shared config profile assumes a role with an MFA token
+func (p *AWSProvider) requiresMFA() bool {
+	profile := p.profile
+	if profile == "" {
+		profile = "default"
+	}
+	sharedConfig, err := config.LoadSharedConfigProfile(context.TODO(), profile)
+	return err == nil && sharedConfig.MFASerial != ""
 }
 
 func (p *AWSProvider) GetBasicConfig() cty.Value {
 	return p.GetConfig()
 }
 
-// check projectName in env params
+// Init takes the region and profile, and the ID of an account with the role assumed to import it
 func (p *AWSProvider) Init(args []string) error {
 	p.region = args[0]
 	p.profile = args[1]
-
-	// Terraformer accepts region and profile configuration, so we must detect what env variables to adjust to make Go SDK rely on them. AWS_SDK_LOAD_CONFIG here must be checked to determine correct variable to set.
-	enableSharedConfig, _ := strconv.ParseBool(os.Getenv("AWS_SDK_LOAD_CONFIG"))
-	var err error
-	if p.region != GlobalRegion && p.region != NoRegion {
-		if enableSharedConfig {
-			err = os.Setenv("AWS_DEFAULT_REGION", p.region)
-		} else {
-			err = os.Setenv("AWS_REGION", p.region)
-		}
-		if err != nil {
-			return err
-		}
-	}
-
-	if p.profile != "default" && p.profile != "" {
-		if enableSharedConfig {
-			err = os.Setenv("AWS_DEFAULT_PROFILE", p.profile)
-		} else {
-			err = os.Setenv("AWS_PROFILE", p.profile)
-		}
-		if err != nil {
-			return err
-		}
+	if len(args) > 3 {
+		p.accountID = args[2]
+		p.roleARN = args[3]
 	}
 	return nil
 }
@@ -218,6 +325,11 @@ func (p *AWSProvider) GetName() string {
 	return "aws"
 }
 
+// UsesRecorder is true as SDK clients of all services are configured in AWSService.generateConfig
+func (p *AWSProvider) UsesRecorder() bool {
+	return true
+}
+
 func (p *AWSProvider) InitService(serviceName string, verbose bool) error {
 	var isSupported bool
 	if _, isSupported = p.GetSupportedService()[serviceName]; !isSupported {
@@ -227,103 +339,123 @@ func (p *AWSProvider) InitService(serviceName string, verbose bool) error {
 	p.Service.SetName(serviceName)
 	p.Service.SetVerbose(verbose)
 	p.Service.SetProviderName(p.GetName())
-	p.Service.SetArgs(map[string]interface{}{
+	p.Service.SetArgs(p.serviceArgs())
+	return nil
+}
+
+func (p *AWSProvider) serviceArgs() map[string]interface{} {
+	return map[string]interface{}{
 		"region":                 p.region,
 		"profile":                p.profile,
+		"account_id":             p.accountID,
+		"role_arn":               p.roleARN,
 		"skip_region_validation": true,
-	})
-	return nil
+	}
 }
 
 // GetAWSSupportService return map of support service for AWS
 func (p *AWSProvider) GetSupportedService() map[string]terraformutils.ServiceGenerator {
 	return map[string]terraformutils.ServiceGenerator{
-		"accessanalyzer":    &AwsFacade{service: &AccessAnalyzerGenerator{}},
-		"acm":               &AwsFacade{service: &ACMGenerator{}},
-		"alb":               &AwsFacade{service: &AlbGenerator{}},
-		"api_gateway":       &AwsFacade{service: &APIGatewayGenerator{}},
-		"appsync":           &AwsFacade{service: &AppSyncGenerator{}},
-		"auto_scaling":      &AwsFacade{service: &AutoScalingGenerator{}},
-		"batch":             &AwsFacade{service: &BatchGenerator{}},
-		"budgets":           &AwsFacade{service: &BudgetsGenerator{}},
-		"cloud9":            &AwsFacade{service: &Cloud9Generator{}},
-		"cloudformation":    &AwsFacade{service: &CloudFormationGenerator{}},
-		"cloudfront":        &AwsFacade{service: &CloudFrontGenerator{}},
-		"cloudhsm":          &AwsFacade{service: &CloudHsmGenerator{}},
-		"cloudtrail":        &AwsFacade{service: &CloudTrailGenerator{}},
-		"cloudwatch":        &AwsFacade{service: &CloudWatchGenerator{}},
-		"codebuild":         &AwsFacade{service: &CodeBuildGenerator{}},
-		"codecommit":        &AwsFacade{service: &CodeCommitGenerator{}},
-		"codedeploy":        &AwsFacade{service: &CodeDeployGenerator{}},
-		"codepipeline":      &AwsFacade{service: &CodePipelineGenerator{}},
-		"cognito":           &AwsFacade{service: &CognitoGenerator{}},
-		"config":            &AwsFacade{service: &ConfigGenerator{}},
-		"customer_gateway":  &AwsFacade{service: &CustomerGatewayGenerator{}},
-		"datapipeline":      &AwsFacade{service: &DataPipelineGenerator{}},
-		"devicefarm":        &AwsFacade{service: &DeviceFarmGenerator{}},
-		"docdb":             &AwsFacade{service: &DocDBGenerator{}},
-		"dynamodb":          &AwsFacade{service: &DynamoDbGenerator{}},
-		"ebs":               &AwsFacade{service: &EbsGenerator{}},
-		"ec2_instance":      &AwsFacade{service: &Ec2Generator{}},
-		"ecr":               &AwsFacade{service: &EcrGenerator{}},
-		"ecrpublic":         &AwsFacade{service: &EcrPublicGenerator{}},
-		"ecs":               &AwsFacade{service: &EcsGenerator{}},
-		"efs":               &AwsFacade{service: &EfsGenerator{}},
-		"eks":               &AwsFacade{service: &EksGenerator{}},
-		"eip":               &AwsFacade{service: &ElasticIPGenerator{}},
-		"elasticache":       &AwsFacade{service: &ElastiCacheGenerator{}},
-		"elastic_beanstalk": &AwsFacade{service: &BeanstalkGenerator{}},
-		"elb":               &AwsFacade{service: &ElbGenerator{}},
-		"emr":               &AwsFacade{service: &EmrGenerator{}},
-		"eni":               &AwsFacade{service: &EniGenerator{}},
-		"es":                &AwsFacade{service: &EsGenerator{}},
-		"firehose":          &AwsFacade{service: &FirehoseGenerator{}},
-		"glue":              &AwsFacade{service: &GlueGenerator{}},
-		"iam":               &AwsFacade{service: &IamGenerator{}},
-		"identitystore":     &AwsFacade{service: &IdentityStoreGenerator{}},
-		"igw":               &AwsFacade{service: &IgwGenerator{}},
-		"iot":               &AwsFacade{service: &IotGenerator{}},
-		"kinesis":           &AwsFacade{service: &KinesisGenerator{}},
-		"kms":               &AwsFacade{service: &KmsGenerator{}},
-		"lambda":            &AwsFacade{service: &LambdaGenerator{}},
-		"logs":              &AwsFacade{service: &LogsGenerator{}},
-		"media_package":     &AwsFacade{service: &MediaPackageGenerator{}},
-		"media_store":       &AwsFacade{service: &MediaStoreGenerator{}},
-		"medialive":         &AwsFacade{service: &MediaLiveGenerator{}},
-		"msk":               &AwsFacade{service: &MskGenerator{}},
-		"nacl":              &AwsFacade{service: &NaclGenerator{}},
-		"nat":               &AwsFacade{service: &NatGatewayGenerator{}},
-		"opsworks":          &AwsFacade{service: &OpsworksGenerator{}},
-		"organization":      &AwsFacade{service: &OrganizationGenerator{}},
-		"qldb":              &AwsFacade{service: &QLDBGenerator{}},
-		"rds":               &AwsFacade{service: &RDSGenerator{}},
-		"redshift":          &AwsFacade{service: &RedshiftGenerator{}},
-		"resourcegroups":    &AwsFacade{service: &ResourceGroupsGenerator{}},
-		"route53":           &AwsFacade{service: &Route53Generator{}},
-		"route_table":       &AwsFacade{service: &RouteTableGenerator{}},
-		"s3":                &AwsFacade{service: &S3Generator{}},
-		"secretsmanager":    &AwsFacade{service: &SecretsManagerGenerator{}},
-		"securityhub":       &AwsFacade{service: &SecurityhubGenerator{}},
-		"servicecatalog":    &AwsFacade{service: &ServiceCatalogGenerator{}},
-		"ses":               &AwsFacade{service: &SesGenerator{}},
-		"sfn":               &AwsFacade{service: &SfnGenerator{}},
-		"sg":                &AwsFacade{service: &SecurityGenerator{}},
-		"sqs":               &AwsFacade{service: &SqsGenerator{}},
-		"sns":               &AwsFacade{service: &SnsGenerator{}},
-		"ssm":               &AwsFacade{service: &SsmGenerator{}},
-		"subnet":            &AwsFacade{service: &SubnetGenerator{}},
-		"swf":               &AwsFacade{service: &SWFGenerator{}},
-		"transit_gateway":   &AwsFacade{service: &TransitGatewayGenerator{}},
-		"waf":               &AwsFacade{service: &WafGenerator{}},
-		"waf_regional":      &AwsFacade{service: &WafRegionalGenerator{}},
-		"wafv2_cloudfront":  &AwsFacade{service: NewWafv2CloudfrontGenerator()},
-		"wafv2_regional":    &AwsFacade{service: NewWafv2RegionalGenerator()},
-		"vpc":               &AwsFacade{service: &VpcGenerator{}},
-		"vpc_peering":       &AwsFacade{service: &VpcPeeringConnectionGenerator{}},
-		"vpn_connection":    &AwsFacade{service: &VpnConnectionGenerator{}},
-		"vpn_gateway":       &AwsFacade{service: &VpnGatewayGenerator{}},
-		"workspaces":        &AwsFacade{service: &WorkspacesGenerator{}},
-		"xray":              &AwsFacade{service: &XrayGenerator{}},
+		"accessanalyzer":             &AwsFacade{service: &AccessAnalyzerGenerator{}},
+		"acm":                        &AwsFacade{service: &ACMGenerator{}},
+		"alb":                        &AwsFacade{service: &AlbGenerator{}},
+		"api_gateway":                &AwsFacade{service: &APIGatewayGenerator{}},
+		"api_gateway_v2":             &AwsFacade{service: &APIGatewayV2Generator{}},
+		"appsync":                    &AwsFacade{service: &AppSyncGenerator{}},
+		"auto_scaling":               &AwsFacade{service: &AutoScalingGenerator{}},
+		"batch":                      &AwsFacade{service: &BatchGenerator{}},
+		"budgets":                    &AwsFacade{service: &BudgetsGenerator{}},
+		"cloud9":                     &AwsFacade{service: &Cloud9Generator{}},
+		"cloudformation":             &AwsFacade{service: &CloudFormationGenerator{}},
+		"cloudfront":                 &AwsFacade{service: &CloudFrontGenerator{}},
+		"cloudhsm":                   &AwsFacade{service: &CloudHsmGenerator{}},
+		"cloudtrail":                 &AwsFacade{service: &CloudTrailGenerator{}},
+		"cloudwatch":                 &AwsFacade{service: &CloudWatchGenerator{}},
+		"codebuild":                  &AwsFacade{service: &CodeBuildGenerator{}},
+		"codecommit":                 &AwsFacade{service: &CodeCommitGenerator{}},
+		"codedeploy":                 &AwsFacade{service: &CodeDeployGenerator{}},
+		"codepipeline":               &AwsFacade{service: &CodePipelineGenerator{}},
+		"cognito":                    &AwsFacade{service: &CognitoGenerator{}},
+		"config":                     &AwsFacade{service: &ConfigGenerator{}},
+		"customer_gateway":           &AwsFacade{service: &CustomerGatewayGenerator{}},
+		"dhcp_options":               &AwsFacade{service: &DhcpOptionsGenerator{}},
+		"datapipeline":               &AwsFacade{service: &DataPipelineGenerator{}},
+		"devicefarm":                 &AwsFacade{service: &DeviceFarmGenerator{}},
+		"docdb":                      &AwsFacade{service: &DocDBGenerator{}},
+		"dynamodb":                   &AwsFacade{service: &DynamoDbGenerator{}},
+		"ebs":                        &AwsFacade{service: &EbsGenerator{}},
+		"ec2_instance":               &AwsFacade{service: &Ec2Generator{}},
+		"ecr":                        &AwsFacade{service: &EcrGenerator{}},
+		"ecrpublic":                  &AwsFacade{service: &EcrPublicGenerator{}},
+		"ecs":                        &AwsFacade{service: &EcsGenerator{}},
+		"egress_only_igw":            &AwsFacade{service: &EgressOnlyIgwGenerator{}},
+		"efs":                        &AwsFacade{service: &EfsGenerator{}},
+		"eks":                        &AwsFacade{service: &EksGenerator{}},
+		"eip":                        &AwsFacade{service: &ElasticIPGenerator{}},
+		"elasticache":                &AwsFacade{service: &ElastiCacheGenerator{}},
+		"elastic_beanstalk":          &AwsFacade{service: &BeanstalkGenerator{}},
+		"elb":                        &AwsFacade{service: &ElbGenerator{}},
+		"emr":                        &AwsFacade{service: &EmrGenerator{}},
+		"eni":                        &AwsFacade{service: &EniGenerator{}},
+		"es":                         &AwsFacade{service: &EsGenerator{}},
+		"firehose":                   &AwsFacade{service: &FirehoseGenerator{}},
+		"flow_log":                   &AwsFacade{service: &FlowLogGenerator{}},
+		"glue":                       &AwsFacade{service: &GlueGenerator{}},
+		"guardduty":                  &AwsFacade{service: &GuardDutyGenerator{}},
+		"iam":                        &AwsFacade{service: &IamGenerator{}},
+		"identitystore":              &AwsFacade{service: &IdentityStoreGenerator{}},
+		"igw":                        &AwsFacade{service: &IgwGenerator{}},
+		"iot":                        &AwsFacade{service: &IotGenerator{}},
+		"kinesis":                    &AwsFacade{service: &KinesisGenerator{}},
+		"kms":                        &AwsFacade{service: &KmsGenerator{}},
+		"lambda":                     &AwsFacade{service: &LambdaGenerator{}},
+		"logs":                       &AwsFacade{service: &LogsGenerator{}},
+		"macie":                      &AwsFacade{service: &MacieGenerator{}},
+		"managed_prefix_list":        &AwsFacade{service: &ManagedPrefixListGenerator{}},
+		"media_package":              &AwsFacade{service: &MediaPackageGenerator{}},
+		"media_store":                &AwsFacade{service: &MediaStoreGenerator{}},
+		"medialive":                  &AwsFacade{service: &MediaLiveGenerator{}},
+		"msk":                        &AwsFacade{service: &MskGenerator{}},
+		"nacl":                       &AwsFacade{service: &NaclGenerator{}},
+		"nat":                        &AwsFacade{service: &NatGatewayGenerator{}},
+		"networkfirewall":            &AwsFacade{service: &NetworkFirewallGenerator{}},
+		"opsworks":                   &AwsFacade{service: &OpsworksGenerator{}},
+		"organization":               &AwsFacade{service: &OrganizationGenerator{}},
+		"qldb":                       &AwsFacade{service: &QLDBGenerator{}},
+		"ram":                        &AwsFacade{service: &RAMGenerator{}},
+		"rds":                        &AwsFacade{service: &RDSGenerator{}},
+		"redshift":                   &AwsFacade{service: &RedshiftGenerator{}},
+		"resourcegroups":             &AwsFacade{service: &ResourceGroupsGenerator{}},
+		"route53":                    &AwsFacade{service: &Route53Generator{}},
+		"route_table":                &AwsFacade{service: &RouteTableGenerator{}},
+		"s3":                         &AwsFacade{service: &S3Generator{}},
+		"secretsmanager":             &AwsFacade{service: &SecretsManagerGenerator{}},
+		"securityhub":                &AwsFacade{service: &SecurityhubGenerator{}},
+		"servicecatalog":             &AwsFacade{service: &ServiceCatalogGenerator{}},
+		"ses":                        &AwsFacade{service: &SesGenerator{}},
+		"sfn":                        &AwsFacade{service: &SfnGenerator{}},
+		"sg":                         &AwsFacade{service: &SecurityGenerator{}},
+		"shield":                     &AwsFacade{service: &ShieldGenerator{}},
+		"sqs":                        &AwsFacade{service: &SqsGenerator{}},
+		"sns":                        &AwsFacade{service: &SnsGenerator{}},
+		"ssm":                        &AwsFacade{service: &SsmGenerator{}},
+		"ssoadmin":                   &AwsFacade{service: &SSOAdminGenerator{}},
+		"subnet":                     &AwsFacade{service: &SubnetGenerator{}},
+		"swf":                        &AwsFacade{service: &SWFGenerator{}},
+		"transit_gateway":            &AwsFacade{service: &TransitGatewayGenerator{}},
+		"waf":                        &AwsFacade{service: &WafGenerator{}},
+		"waf_regional":               &AwsFacade{service: &WafRegionalGenerator{}},
+		"wafv2_cloudfront":           &AwsFacade{service: NewWafv2CloudfrontGenerator()},
+		"wafv2_regional":             &AwsFacade{service: NewWafv2RegionalGenerator()},
+		"vpc":                        &AwsFacade{service: &VpcGenerator{}},
+		"vpc_cidr_block_association": &AwsFacade{service: &VpcCidrBlockAssociationGenerator{}},
+		"vpc_endpoint":               &AwsFacade{service: &VpcEndpointGenerator{}},
+		"vpc_endpoint_service":       &AwsFacade{service: &VpcEndpointServiceGenerator{}},
+		"vpc_peering":                &AwsFacade{service: &VpcPeeringConnectionGenerator{}},
+		"vpn_connection":             &AwsFacade{service: &VpnConnectionGenerator{}},
+		"vpn_gateway":                &AwsFacade{service: &VpnGatewayGenerator{}},
+		"workspaces":                 &AwsFacade{service: &WorkspacesGenerator{}},
+		"xray":                       &AwsFacade{service: &XrayGenerator{}},
 	}
 }
 
@@ -16,16 +16,24 @@ package aws
 
 import (
 	"context"
-	"os"
+	"errors"
+	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
 )
 
 type AWSService struct { //nolint
@@ -34,49 +42,83 @@ type AWSService struct { //nolint
 
 var awsVariable = regexp.MustCompile(`(\${[0-9A-Za-z:]+})`)
 
-var configCache *aws.Config
+// credentials of each account are shared by its regions and services, so a role is assumed and an MFA token
+// asked for once per account
+var accountCredentials = map[string]aws.CredentialsProvider{}
+var accountCredentialsLock sync.Mutex
 
 func (s *AWSService) generateConfig() (aws.Config, error) {
-	if configCache != nil {
-		return *configCache, nil
-	}
-
 	baseConfig, e := s.buildBaseConfig()
 
 	if e != nil {
 		return baseConfig, e
 	}
+	if endpoints := s.endpoints(); len(endpoints) > 0 {
+		baseConfig.EndpointResolverWithOptions = endpointResolver(endpoints)
+	}
 	if s.Verbose {
 		baseConfig.ClientLogMode = aws.LogRequestWithBody & aws.LogResponseWithBody
 	}
+	if recorder.Replaying() {
+		return baseConfig, nil
+	}
 
-	creds, e := baseConfig.Credentials.Retrieve(context.TODO())
-
-	if e != nil {
+	baseConfig.Credentials = s.accountCredentials(baseConfig)
+	if baseConfig.Credentials == nil {
+		return baseConfig, errors.New("aws: no credentials found")
+	}
+	if _, e := baseConfig.Credentials.Retrieve(context.TODO()); e != nil {
 		return baseConfig, e
 	}
+	return baseConfig, nil
+}
 
-	// terraform cannot ask for MFA token, so we need to pass STS session token, which might contain credentials with MFA requirement
-	accessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	if accessKey == "" {
-		os.Setenv("AWS_ACCESS_KEY_ID", creds.AccessKeyID)
-		os.Setenv("AWS_SECRET_ACCESS_KEY", creds.SecretAccessKey)
-
-		if creds.SessionToken != "" {
-			os.Setenv("AWS_SESSION_TOKEN", creds.SessionToken)
-		}
+// accountCredentials returns the credentials of the account imported, assuming role_arn with the
+// credentials of the profile
+func (s *AWSService) accountCredentials(baseConfig aws.Config) aws.CredentialsProvider {
+	accountCredentialsLock.Lock()
+	defer accountCredentialsLock.Unlock()
+	roleARN, _ := s.GetArgs()["role_arn"].(string)
+	cacheKey := s.GetArgs()["profile"].(string) + "/" + roleARN + "/" + fmt.Sprint(s.GetProviderConfig())
+	if cached, exist := accountCredentials[cacheKey]; exist {
+		return cached
 	}
-	configCache = &baseConfig
-	return baseConfig, nil
+	credentialsProvider := baseConfig.Credentials
+	if roleARN != "" && credentialsProvider != nil {
+		credentialsProvider = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(baseConfig), roleARN, func(options *stscreds.AssumeRoleOptions) {
+			options.RoleSessionName = "terraformer"
+		}))
+	}
+	accountCredentials[cacheKey] = credentialsProvider
+	return credentialsProvider
 }
 
 func (s *AWSService) buildBaseConfig() (aws.Config, error) {
 	var loadOptions []func(*config.LoadOptions) error
+	switch recorder.Mode() {
+	case recorder.ModeReplay:
+		// replayed runs need neither credentials nor profiles
+		return aws.Config{
+			Region:      s.GetArgs()["region"].(string),
+			Credentials: credentials.NewStaticCredentialsProvider("replay", "replay", ""),
+			HTTPClient:  recorder.HTTPClient(),
+			Retryer: func() aws.Retryer {
+				return aws.NopRetryer{}
+			},
+		}, nil
+	case recorder.ModeRecord:
+		loadOptions = append(loadOptions, config.WithHTTPClient(recorder.HTTPClient()))
+	}
 	if s.GetArgs()["profile"].(string) != "" {
 		loadOptions = append(loadOptions, config.WithSharedConfigProfile(s.GetArgs()["profile"].(string)))
 	}
 	if s.GetArgs()["region"].(string) != "" {
-		os.Setenv("AWS_REGION", s.GetArgs()["region"].(string))
+		loadOptions = append(loadOptions, config.WithRegion(s.GetArgs()["region"].(string)))
+	}
+	providerConfig := s.GetProviderConfig()
+	if providerConfig["access_key"] != "" && providerConfig["secret_key"] != "" {
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			providerConfig["access_key"], providerConfig["secret_key"], providerConfig["token"])))
 	}
 	loadOptions = append(loadOptions, config.WithAssumeRoleCredentialOptions(func(options *stscreds.AssumeRoleOptions) {
 		options.TokenProvider = stscreds.StdinTokenProvider
@@ -84,6 +126,65 @@ func (s *AWSService) buildBaseConfig() (aws.Config, error) {
 	return config.LoadDefaultConfig(context.TODO(), loadOptions...)
 }
 
+// endpointAliases maps SDK service IDs to the names of the provider's endpoints block where they differ
+var endpointAliases = map[string][]string{
+	"cloudwatchevents":         {"cloudwatchevents", "events"},
+	"cloudwatchlogs":           {"cloudwatchlogs", "logs"},
+	"cognitoidentityprovider":  {"cognitoidp"},
+	"databasemigrationservice": {"dms"},
+	"elasticloadbalancing":     {"elb"},
+	"elasticloadbalancingv2":   {"elbv2"},
+	"elasticsearchservice":     {"es", "elasticsearch"},
+	"eventbridge":              {"events", "cloudwatchevents"},
+	"sfn":                      {"sfn", "stepfunctions"},
+}
+
+// endpoints returns the custom endpoints of --provider-config endpoints.<service>, e.g. for LocalStack
+func (s *AWSService) endpoints() map[string]string {
+	endpoints := map[string]string{}
+	for key, url := range s.GetProviderConfig() {
+		if strings.HasPrefix(key, "endpoints.") {
+			endpoints[strings.TrimPrefix(key, "endpoints.")] = url
+		}
+	}
+	return endpoints
+}
+
+func endpointResolver(endpoints map[string]string) aws.EndpointResolverWithOptions {
+	return aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		id := strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(service))
+		names, exist := endpointAliases[id]
+		if !exist {
+			names = []string{id}
+		}
+		for _, name := range names {
+			if url, exist := endpoints[name]; exist {
+				return aws.Endpoint{URL: url, SigningRegion: region, Source: aws.EndpointSourceCustom}, nil
+			}
+		}
+		// other services keep their default endpoints
+		return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+	})
+}
+
+// s3Options addresses buckets by path with s3_use_path_style, as S3 compatible stores like MinIO require
+func (s *AWSService) s3Options(options *s3.Options) {
+	for _, key := range []string{"s3_use_path_style", "s3_force_path_style"} {
+		if pathStyle, err := strconv.ParseBool(s.GetProviderConfig()[key]); err == nil && pathStyle {
+			options.UsePathStyle = true
+		}
+	}
+}
+
+// providerMajorVersion returns the major version of the AWS provider in use, 0 when it's unknown
+func providerMajorVersion() int {
+	pkg, err := providerwrapper.FindProvider("aws")
+	if err != nil || pkg.Version == nil {
+		return 0
+	}
+	return pkg.Version.Segments()[0]
+}
+
 // for CF interpolation and IAM Policy variables
 func (*AWSService) escapeAwsInterpolation(str string) string {
 	return awsVariable.ReplaceAllString(str, "$$$1")
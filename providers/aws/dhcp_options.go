@@ -0,0 +1,95 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+var dhcpOptionsAllowEmptyValues = []string{"tags."}
+
+type DhcpOptionsGenerator struct {
+	AWSService
+}
+
+func (g *DhcpOptionsGenerator) getDhcpOptions(svc *ec2.Client) error {
+	p := ec2.NewDescribeDhcpOptionsPaginator(svc, &ec2.DescribeDhcpOptionsInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, options := range page.DhcpOptions {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(options.DhcpOptionsId),
+				StringValue(options.DhcpOptionsId),
+				"aws_vpc_dhcp_options",
+				"aws",
+				dhcpOptionsAllowEmptyValues,
+			))
+		}
+	}
+	return nil
+}
+
+// getDhcpOptionsAssociations creates an association for each VPC using DHCP options, VPCs without any use
+// the "default" ones of AWS
+func (g *DhcpOptionsGenerator) getDhcpOptionsAssociations(svc *ec2.Client) error {
+	p := ec2.NewDescribeVpcsPaginator(svc, &ec2.DescribeVpcsInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, vpc := range page.Vpcs {
+			optionsID := StringValue(vpc.DhcpOptionsId)
+			if optionsID == "" || optionsID == "default" {
+				continue
+			}
+			g.Resources = append(g.Resources, terraformutils.NewResource(
+				optionsID+"-"+StringValue(vpc.VpcId),
+				StringValue(vpc.VpcId),
+				"aws_vpc_dhcp_options_association",
+				"aws",
+				map[string]string{
+					"dhcp_options_id": optionsID,
+					"vpc_id":          StringValue(vpc.VpcId),
+				},
+				dhcpOptionsAllowEmptyValues,
+				map[string]interface{}{},
+			))
+		}
+	}
+	return nil
+}
+
+// Generate TerraformResources from AWS API,
+// from each DHCP options set create 1 TerraformResource and 1 per VPC associated with it.
+// Need DhcpOptionsId as ID for terraform resource
+func (g *DhcpOptionsGenerator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := ec2.NewFromConfig(config)
+	if err := g.getDhcpOptions(svc); err != nil {
+		return err
+	}
+	return g.getDhcpOptionsAssociations(svc)
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/guardduty"
+)
+
+var guardDutyAllowEmptyValues = []string{"tags."}
+
+type GuardDutyGenerator struct {
+	AWSService
+}
+
+func (g *GuardDutyGenerator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := guardduty.NewFromConfig(config)
+
+	p := guardduty.NewListDetectorsPaginator(svc, &guardduty.ListDetectorsInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, detectorID := range page.DetectorIds {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				detectorID,
+				detectorID,
+				"aws_guardduty_detector",
+				"aws",
+				guardDutyAllowEmptyValues,
+			))
+			if err := g.loadFilters(svc, detectorID); err != nil {
+				return err
+			}
+			if err := g.loadIPSets(svc, detectorID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *GuardDutyGenerator) loadFilters(svc *guardduty.Client, detectorID string) error {
+	p := guardduty.NewListFiltersPaginator(svc, &guardduty.ListFiltersInput{DetectorId: aws.String(detectorID)})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, filterName := range page.FilterNames {
+			g.Resources = append(g.Resources, terraformutils.NewResource(
+				detectorID+":"+filterName,
+				filterName,
+				"aws_guardduty_filter",
+				"aws",
+				map[string]string{
+					"detector_id": detectorID,
+					"name":        filterName,
+				},
+				guardDutyAllowEmptyValues,
+				map[string]interface{}{},
+			))
+		}
+	}
+	return nil
+}
+
+func (g *GuardDutyGenerator) loadIPSets(svc *guardduty.Client, detectorID string) error {
+	p := guardduty.NewListIPSetsPaginator(svc, &guardduty.ListIPSetsInput{DetectorId: aws.String(detectorID)})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, ipSetID := range page.IpSetIds {
+			g.Resources = append(g.Resources, terraformutils.NewResource(
+				detectorID+":"+ipSetID,
+				ipSetID,
+				"aws_guardduty_ipset",
+				"aws",
+				map[string]string{
+					"detector_id": detectorID,
+				},
+				guardDutyAllowEmptyValues,
+				map[string]interface{}{},
+			))
+		}
+	}
+	return nil
+}
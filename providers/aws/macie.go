@@ -0,0 +1,127 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"errors"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/aws/aws-sdk-go-v2/service/macie2"
+	"github.com/aws/aws-sdk-go-v2/service/macie2/types"
+)
+
+var macieAllowEmptyValues = []string{"tags."}
+
+type MacieGenerator struct {
+	AWSService
+}
+
+func (g *MacieGenerator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := macie2.NewFromConfig(config)
+
+	// accounts without Macie are denied access to the session
+	_, err := svc.GetMacieSession(context.TODO(), &macie2.GetMacieSessionInput{})
+	var accessDenied *types.AccessDeniedException
+	if errors.As(err, &accessDenied) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	account, err := g.getAccountNumber(config)
+	if err != nil {
+		return err
+	}
+	g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+		*account,
+		*account,
+		"aws_macie2_account",
+		"aws",
+		macieAllowEmptyValues,
+	))
+
+	if err := g.loadClassificationJobs(svc); err != nil {
+		return err
+	}
+	if err := g.loadCustomDataIdentifiers(svc); err != nil {
+		return err
+	}
+	return g.loadFindingsFilters(svc)
+}
+
+func (g *MacieGenerator) loadClassificationJobs(svc *macie2.Client) error {
+	p := macie2.NewListClassificationJobsPaginator(svc, &macie2.ListClassificationJobsInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, job := range page.Items {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(job.JobId),
+				StringValue(job.Name),
+				"aws_macie2_classification_job",
+				"aws",
+				macieAllowEmptyValues,
+			))
+		}
+	}
+	return nil
+}
+
+func (g *MacieGenerator) loadCustomDataIdentifiers(svc *macie2.Client) error {
+	p := macie2.NewListCustomDataIdentifiersPaginator(svc, &macie2.ListCustomDataIdentifiersInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, identifier := range page.Items {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(identifier.Id),
+				StringValue(identifier.Name),
+				"aws_macie2_custom_data_identifier",
+				"aws",
+				macieAllowEmptyValues,
+			))
+		}
+	}
+	return nil
+}
+
+func (g *MacieGenerator) loadFindingsFilters(svc *macie2.Client) error {
+	p := macie2.NewListFindingsFiltersPaginator(svc, &macie2.ListFindingsFiltersInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, filter := range page.FindingsFilterListItems {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(filter.Id),
+				StringValue(filter.Name),
+				"aws_macie2_findings_filter",
+				"aws",
+				macieAllowEmptyValues,
+			))
+		}
+	}
+	return nil
+}
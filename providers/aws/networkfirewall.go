@@ -0,0 +1,113 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/networkfirewall"
+)
+
+var networkFirewallAllowEmptyValues = []string{"tags."}
+
+type NetworkFirewallGenerator struct {
+	AWSService
+}
+
+// Generate TerraformResources from AWS API,
+// firewalls, firewall policies and rule groups of the account are identified by their ARN
+func (g *NetworkFirewallGenerator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := networkfirewall.NewFromConfig(config)
+
+	if err := g.loadFirewalls(svc); err != nil {
+		return err
+	}
+	if err := g.loadFirewallPolicies(svc); err != nil {
+		return err
+	}
+	return g.loadRuleGroups(svc)
+}
+
+func (g *NetworkFirewallGenerator) loadFirewalls(svc *networkfirewall.Client) error {
+	p := networkfirewall.NewListFirewallsPaginator(svc, &networkfirewall.ListFirewallsInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, firewall := range page.Firewalls {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(firewall.FirewallArn),
+				StringValue(firewall.FirewallName),
+				"aws_networkfirewall_firewall",
+				"aws",
+				networkFirewallAllowEmptyValues,
+			))
+		}
+	}
+	return nil
+}
+
+func (g *NetworkFirewallGenerator) loadFirewallPolicies(svc *networkfirewall.Client) error {
+	p := networkfirewall.NewListFirewallPoliciesPaginator(svc, &networkfirewall.ListFirewallPoliciesInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, policy := range page.FirewallPolicies {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(policy.Arn),
+				StringValue(policy.Name),
+				"aws_networkfirewall_firewall_policy",
+				"aws",
+				networkFirewallAllowEmptyValues,
+			))
+		}
+	}
+	return nil
+}
+
+// loadRuleGroups lists rule groups of the account, managed ones are referenced by policies only. Stateful and
+// stateless rule groups may have the same name, they are named after the resource of their ARN.
+func (g *NetworkFirewallGenerator) loadRuleGroups(svc *networkfirewall.Client) error {
+	p := networkfirewall.NewListRuleGroupsPaginator(svc, &networkfirewall.ListRuleGroupsInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, ruleGroup := range page.RuleGroups {
+			resourceName := StringValue(ruleGroup.Name)
+			if ruleGroupARN, err := arn.Parse(StringValue(ruleGroup.Arn)); err == nil {
+				resourceName = ruleGroupARN.Resource
+			}
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(ruleGroup.Arn),
+				resourceName,
+				"aws_networkfirewall_rule_group",
+				"aws",
+				networkFirewallAllowEmptyValues,
+			))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/aws/aws-sdk-go-v2/service/ram"
+	"github.com/aws/aws-sdk-go-v2/service/ram/types"
+)
+
+var ramAllowEmptyValues = []string{"tags."}
+
+type RAMGenerator struct {
+	AWSService
+}
+
+// Generate TerraformResources from AWS API,
+// from each active resource share of the account create 1 TerraformResource and 1 per resource and principal
+// associated with it
+func (g *RAMGenerator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := ram.NewFromConfig(config)
+
+	p := ram.NewGetResourceSharesPaginator(svc, &ram.GetResourceSharesInput{
+		ResourceOwner:       types.ResourceOwnerSelf,
+		ResourceShareStatus: types.ResourceShareStatusActive,
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, share := range page.ResourceShares {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(share.ResourceShareArn),
+				StringValue(share.Name),
+				"aws_ram_resource_share",
+				"aws",
+				ramAllowEmptyValues,
+			))
+			if err := g.loadResourceAssociations(svc, share); err != nil {
+				return err
+			}
+			if err := g.loadPrincipalAssociations(svc, share); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *RAMGenerator) loadResourceAssociations(svc *ram.Client, share types.ResourceShare) error {
+	p := ram.NewListResourcesPaginator(svc, &ram.ListResourcesInput{
+		ResourceOwner:     types.ResourceOwnerSelf,
+		ResourceShareArns: []string{StringValue(share.ResourceShareArn)},
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, resource := range page.Resources {
+			if resource.Status != types.ResourceStatusAvailable {
+				continue
+			}
+			g.Resources = append(g.Resources, terraformutils.NewResource(
+				StringValue(share.ResourceShareArn)+","+StringValue(resource.Arn),
+				StringValue(share.Name)+"_"+StringValue(resource.Arn),
+				"aws_ram_resource_association",
+				"aws",
+				map[string]string{
+					"resource_arn":       StringValue(resource.Arn),
+					"resource_share_arn": StringValue(share.ResourceShareArn),
+				},
+				ramAllowEmptyValues,
+				map[string]interface{}{},
+			))
+		}
+	}
+	return nil
+}
+
+func (g *RAMGenerator) loadPrincipalAssociations(svc *ram.Client, share types.ResourceShare) error {
+	p := ram.NewListPrincipalsPaginator(svc, &ram.ListPrincipalsInput{
+		ResourceOwner:     types.ResourceOwnerSelf,
+		ResourceShareArns: []string{StringValue(share.ResourceShareArn)},
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, principal := range page.Principals {
+			g.Resources = append(g.Resources, terraformutils.NewResource(
+				StringValue(share.ResourceShareArn)+","+StringValue(principal.Id),
+				StringValue(share.Name)+"_"+StringValue(principal.Id),
+				"aws_ram_principal_association",
+				"aws",
+				map[string]string{
+					"principal":          StringValue(principal.Id),
+					"resource_share_arn": StringValue(share.ResourceShareArn),
+				},
+				ramAllowEmptyValues,
+				map[string]interface{}{},
+			))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files of replayed generators")
+
+// testReplay imports a service from cassettes in test_data/<service>, recorded with
+// terraformer import aws --resources=<service> --regions=eu-west-1 --record=providers/aws/test_data/<service>,
+// and compares the HCL to test_data/<service>/golden.tf
+func testReplay(t *testing.T, service string, g terraformutils.ServiceGenerator) {
+	dir := filepath.Join("test_data", service)
+	if err := recorder.Start(recorder.ModeReplay, dir); err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Stop() //nolint
+
+	g.SetName(service)
+	g.SetProviderName("aws")
+	g.SetArgs(map[string]interface{}{"region": "eu-west-1", "profile": ""})
+	if err := g.InitResources(); err != nil {
+		t.Fatal(err)
+	}
+	providerWrapper, err := providerwrapper.NewProviderWrapper("aws", cty.NilVal, false, map[string]int{"retryCount": 1, "retrySleepMs": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer providerWrapper.Kill()
+	g.PopulateIgnoreKeys(providerWrapper)
+	resources := g.GetResources()
+	for i := range resources {
+		if err := resources[i].Refresh(providerWrapper); err != nil {
+			t.Fatal(err)
+		}
+		if err := resources[i].ConvertTFstate(providerWrapper); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hcl, err := terraformutils.HclPrintResource(resources, map[string]interface{}{}, "hcl", true, providerWrapper.GetSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join(dir, "golden.tf")
+	if *updateGolden {
+		if err := os.WriteFile(golden, hcl, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(hcl) != string(expected) {
+		t.Errorf("%s differs from %s, run go test -update if the change is expected:\n%s", service, golden, hcl)
+	}
+}
+
+func TestIgwReplay(t *testing.T) {
+	testReplay(t, "igw", &IgwGenerator{})
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 var S3AllowEmptyValues = []string{"tags."}
@@ -33,12 +34,99 @@ type S3Generator struct {
 	AWSService
 }
 
+// s3BucketConfigurations are the resources of bucket settings of AWS provider v4+, a bucket has one of each
+// type when the setting is configured
+var s3BucketConfigurations = []struct {
+	resourceType string
+	configured   func(svc *s3.Client, bucket *string) (bool, error)
+}{
+	{"aws_s3_bucket_versioning", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketVersioning(context.TODO(), &s3.GetBucketVersioningInput{Bucket: bucket})
+		return err == nil && output.Status != "", err
+	}},
+	{"aws_s3_bucket_lifecycle_configuration", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketLifecycleConfiguration(context.TODO(), &s3.GetBucketLifecycleConfigurationInput{Bucket: bucket})
+		return err == nil && len(output.Rules) > 0, err
+	}},
+	{"aws_s3_bucket_server_side_encryption_configuration", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketEncryption(context.TODO(), &s3.GetBucketEncryptionInput{Bucket: bucket})
+		return err == nil && output.ServerSideEncryptionConfiguration != nil, err
+	}},
+	{"aws_s3_bucket_logging", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketLogging(context.TODO(), &s3.GetBucketLoggingInput{Bucket: bucket})
+		return err == nil && output.LoggingEnabled != nil, err
+	}},
+	{"aws_s3_bucket_cors_configuration", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketCors(context.TODO(), &s3.GetBucketCorsInput{Bucket: bucket})
+		return err == nil && len(output.CORSRules) > 0, err
+	}},
+	{"aws_s3_bucket_website_configuration", func(svc *s3.Client, bucket *string) (bool, error) {
+		_, err := svc.GetBucketWebsite(context.TODO(), &s3.GetBucketWebsiteInput{Bucket: bucket})
+		return err == nil, err
+	}},
+	{"aws_s3_bucket_ownership_controls", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketOwnershipControls(context.TODO(), &s3.GetBucketOwnershipControlsInput{Bucket: bucket})
+		return err == nil && output.OwnershipControls != nil, err
+	}},
+	// ACLs are only imported when they grant more than the owner's full control and aren't disabled
+	{"aws_s3_bucket_acl", func(svc *s3.Client, bucket *string) (bool, error) {
+		ownership, err := svc.GetBucketOwnershipControls(context.TODO(), &s3.GetBucketOwnershipControlsInput{Bucket: bucket})
+		if err == nil && ownership.OwnershipControls != nil {
+			for _, rule := range ownership.OwnershipControls.Rules {
+				if string(rule.ObjectOwnership) == "BucketOwnerEnforced" {
+					return false, nil
+				}
+			}
+		}
+		output, err := svc.GetBucketAcl(context.TODO(), &s3.GetBucketAclInput{Bucket: bucket})
+		if err != nil {
+			return false, err
+		}
+		for _, grant := range output.Grants {
+			if grant.Permission != types.PermissionFullControl || grant.Grantee == nil || output.Owner == nil ||
+				StringValue(grant.Grantee.ID) != StringValue(output.Owner.ID) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}},
+	{"aws_s3_bucket_public_access_block", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetPublicAccessBlock(context.TODO(), &s3.GetPublicAccessBlockInput{Bucket: bucket})
+		return err == nil && output.PublicAccessBlockConfiguration != nil, err
+	}},
+	{"aws_s3_bucket_replication_configuration", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketReplication(context.TODO(), &s3.GetBucketReplicationInput{Bucket: bucket})
+		return err == nil && output.ReplicationConfiguration != nil, err
+	}},
+	{"aws_s3_bucket_notification", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketNotificationConfiguration(context.TODO(), &s3.GetBucketNotificationConfigurationInput{Bucket: bucket})
+		return err == nil && len(output.LambdaFunctionConfigurations)+len(output.QueueConfigurations)+len(output.TopicConfigurations) > 0, err
+	}},
+	{"aws_s3_bucket_accelerate_configuration", func(svc *s3.Client, bucket *string) (bool, error) {
+		output, err := svc.GetBucketAccelerateConfiguration(context.TODO(), &s3.GetBucketAccelerateConfigurationInput{Bucket: bucket})
+		return err == nil && output.Status != "", err
+	}},
+}
+
+// s3InlineAttributes are settings of aws_s3_bucket deprecated by the resources of s3BucketConfigurations
+var s3InlineAttributes = []string{
+	"acceleration_status", "acl", "cors_rule", "grant", "lifecycle_rule", "logging", "policy",
+	"replication_configuration", "server_side_encryption_configuration", "versioning", "website",
+}
+
+// splitBucketResources is true when settings of buckets are separate resources, as of AWS provider v4
+func (g *S3Generator) splitBucketResources() bool {
+	major := providerMajorVersion()
+	return major == 0 || major >= 4
+}
+
 // createResources iterate on all buckets
 // for each bucket we check region and choose only bucket from set region
 // for each bucket try get bucket policy, if policy exist create additional NewTerraformResource for policy
 func (g *S3Generator) createResources(config aws.Config, buckets *s3.ListBucketsOutput, region string) []terraformutils.Resource {
 	var resources []terraformutils.Resource
-	svc := s3.NewFromConfig(config)
+	svc := s3.NewFromConfig(config, g.s3Options)
+	split := g.splitBucketResources()
 	for _, bucket := range buckets.Buckets {
 		resourceName := StringValue(bucket.Name)
 		location, err := svc.GetBucketLocation(context.TODO(), &s3.GetBucketLocationInput{Bucket: bucket.Name})
@@ -51,7 +139,9 @@ func (g *S3Generator) createResources(config aws.Config, buckets *s3.ListBuckets
 		if constraintString == region || (constraintString == "" && region == "us-east-1") {
 			attributes := map[string]string{
 				"force_destroy": "false",
-				"acl":           "private",
+			}
+			if !split {
+				attributes["acl"] = "private"
 			}
 			// try get policy
 			var policy *s3.GetBucketPolicyOutput
@@ -60,7 +150,9 @@ func (g *S3Generator) createResources(config aws.Config, buckets *s3.ListBuckets
 			})
 
 			if err == nil && policy.Policy != nil {
-				attributes["policy"] = *policy.Policy
+				if !split {
+					attributes["policy"] = *policy.Policy
+				}
 				resources = append(resources, terraformutils.NewResource(
 					resourceName,
 					resourceName,
@@ -78,11 +170,35 @@ func (g *S3Generator) createResources(config aws.Config, buckets *s3.ListBuckets
 				attributes,
 				S3AllowEmptyValues,
 				S3AdditionalFields))
+			if split {
+				resources = append(resources, g.bucketConfigurations(svc, bucket.Name)...)
+			}
 		}
 	}
 	return resources
 }
 
+func (g *S3Generator) bucketConfigurations(svc *s3.Client, bucket *string) []terraformutils.Resource {
+	var resources []terraformutils.Resource
+	for _, configuration := range s3BucketConfigurations {
+		configured, err := configuration.configured(svc, bucket)
+		if err != nil && g.Verbose {
+			// buckets without the setting return errors like NoSuchCORSConfiguration
+			log.Printf("%s of %s: %v\n", configuration.resourceType, StringValue(bucket), err)
+		}
+		if !configured {
+			continue
+		}
+		resources = append(resources, terraformutils.NewSimpleResource(
+			StringValue(bucket),
+			StringValue(bucket),
+			configuration.resourceType,
+			"aws",
+			S3AllowEmptyValues))
+	}
+	return resources
+}
+
 // Generate TerraformResources from AWS API,
 // Need bucket name as ID for terraform resource
 func (g *S3Generator) InitResources() error {
@@ -90,7 +206,7 @@ func (g *S3Generator) InitResources() error {
 	if e != nil {
 		return e
 	}
-	svc := s3.NewFromConfig(config)
+	svc := s3.NewFromConfig(config, g.s3Options)
 
 	buckets, err := svc.ListBuckets(context.TODO(), nil)
 	if err != nil {
@@ -103,17 +219,34 @@ func (g *S3Generator) InitResources() error {
 // PostGenerateHook for add bucket policy json as heredoc
 // support only bucket with policy
 func (g *S3Generator) PostConvertHook() error {
+	split := g.splitBucketResources()
 	for i, resource := range g.Resources {
-		if resource.InstanceInfo.Type == "aws_s3_bucket" {
+		switch resource.InstanceInfo.Type {
+		case "aws_s3_bucket":
+			if split {
+				// settings are managed by their own resources, inline ones would conflict with them
+				for _, attribute := range s3InlineAttributes {
+					delete(g.Resources[i].Item, attribute)
+				}
+				continue
+			}
 			if val, ok := g.Resources[i].Item["acl"]; ok && val == "private" {
 				delete(g.Resources[i].Item, "acl")
 			}
 			if val, ok := g.Resources[i].Item["policy"]; ok {
-				g.Resources[i].Item["policy"] = fmt.Sprintf(`<<POLICY
-%s
-POLICY`, g.escapeAwsInterpolation(val.(string)))
+				g.Resources[i].Item["policy"] = g.policyHeredoc(val.(string))
+			}
+		case "aws_s3_bucket_policy":
+			if val, ok := g.Resources[i].Item["policy"]; ok && split {
+				g.Resources[i].Item["policy"] = g.policyHeredoc(val.(string))
 			}
 		}
 	}
 	return nil
 }
+
+func (g *S3Generator) policyHeredoc(policy string) string {
+	return fmt.Sprintf(`<<POLICY
+%s
+POLICY`, g.escapeAwsInterpolation(policy))
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"errors"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/aws/aws-sdk-go-v2/service/shield"
+	"github.com/aws/aws-sdk-go-v2/service/shield/types"
+)
+
+var shieldAllowEmptyValues = []string{"tags."}
+
+type ShieldGenerator struct {
+	AWSService
+}
+
+// Generate TerraformResources from AWS API,
+// from each Shield Advanced protection and protection group create 1 TerraformResource.
+// Accounts without protections or groups get ResourceNotFoundException.
+func (g *ShieldGenerator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := shield.NewFromConfig(config)
+
+	p := shield.NewListProtectionsPaginator(svc, &shield.ListProtectionsInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if isShieldNotFound(err) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, protection := range page.Protections {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(protection.Id),
+				StringValue(protection.Name),
+				"aws_shield_protection",
+				"aws",
+				shieldAllowEmptyValues,
+			))
+		}
+	}
+
+	groups := shield.NewListProtectionGroupsPaginator(svc, &shield.ListProtectionGroupsInput{})
+	for groups.HasMorePages() {
+		page, err := groups.NextPage(context.TODO())
+		if isShieldNotFound(err) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, group := range page.ProtectionGroups {
+			g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+				StringValue(group.ProtectionGroupId),
+				StringValue(group.ProtectionGroupId),
+				"aws_shield_protection_group",
+				"aws",
+				shieldAllowEmptyValues,
+			))
+		}
+	}
+	return nil
+}
+
+func isShieldNotFound(err error) bool {
+	var notFound *types.ResourceNotFoundException
+	return errors.As(err, &notFound)
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/aws/aws-sdk-go-v2/service/ssoadmin"
+)
+
+var ssoAdminAllowEmptyValues = []string{"tags."}
+
+// SSOAdminGenerator imports the permission sets of IAM Identity Center with their assignments to accounts,
+// users and groups are imported by identitystore
+type SSOAdminGenerator struct {
+	AWSService
+}
+
+func (g *SSOAdminGenerator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := ssoadmin.NewFromConfig(config)
+
+	p := ssoadmin.NewListInstancesPaginator(svc, &ssoadmin.ListInstancesInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, instance := range page.Instances {
+			if err := g.loadPermissionSets(svc, StringValue(instance.InstanceArn)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *SSOAdminGenerator) loadPermissionSets(svc *ssoadmin.Client, instanceArn string) error {
+	p := ssoadmin.NewListPermissionSetsPaginator(svc, &ssoadmin.ListPermissionSetsInput{InstanceArn: &instanceArn})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, permissionSetArn := range page.PermissionSets {
+			permissionSetArn := permissionSetArn
+			permissionSet, err := svc.DescribePermissionSet(context.TODO(), &ssoadmin.DescribePermissionSetInput{
+				InstanceArn:      &instanceArn,
+				PermissionSetArn: &permissionSetArn,
+			})
+			if err != nil {
+				return err
+			}
+			name := StringValue(permissionSet.PermissionSet.Name)
+			g.Resources = append(g.Resources, terraformutils.NewResource(
+				permissionSetArn+","+instanceArn,
+				name,
+				"aws_ssoadmin_permission_set",
+				"aws",
+				map[string]string{
+					"instance_arn": instanceArn,
+					"name":         name,
+				},
+				ssoAdminAllowEmptyValues,
+				map[string]interface{}{},
+			))
+			if err := g.loadManagedPolicyAttachments(svc, instanceArn, permissionSetArn, name); err != nil {
+				return err
+			}
+			if err := g.loadAccountAssignments(svc, instanceArn, permissionSetArn, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (g *SSOAdminGenerator) loadManagedPolicyAttachments(svc *ssoadmin.Client, instanceArn, permissionSetArn, permissionSetName string) error {
+	p := ssoadmin.NewListManagedPoliciesInPermissionSetPaginator(svc, &ssoadmin.ListManagedPoliciesInPermissionSetInput{
+		InstanceArn:      &instanceArn,
+		PermissionSetArn: &permissionSetArn,
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, policy := range page.AttachedManagedPolicies {
+			g.Resources = append(g.Resources, terraformutils.NewResource(
+				strings.Join([]string{StringValue(policy.Arn), permissionSetArn, instanceArn}, ","),
+				permissionSetName+"_"+StringValue(policy.Name),
+				"aws_ssoadmin_managed_policy_attachment",
+				"aws",
+				map[string]string{
+					"instance_arn":       instanceArn,
+					"managed_policy_arn": StringValue(policy.Arn),
+					"permission_set_arn": permissionSetArn,
+				},
+				ssoAdminAllowEmptyValues,
+				map[string]interface{}{},
+			))
+		}
+	}
+	return nil
+}
+
+// loadAccountAssignments creates the assignments of users and groups to the accounts the permission set is
+// provisioned to
+func (g *SSOAdminGenerator) loadAccountAssignments(svc *ssoadmin.Client, instanceArn, permissionSetArn, permissionSetName string) error {
+	p := ssoadmin.NewListAccountsForProvisionedPermissionSetPaginator(svc, &ssoadmin.ListAccountsForProvisionedPermissionSetInput{
+		InstanceArn:      &instanceArn,
+		PermissionSetArn: &permissionSetArn,
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, accountID := range page.AccountIds {
+			accountID := accountID
+			assignments := ssoadmin.NewListAccountAssignmentsPaginator(svc, &ssoadmin.ListAccountAssignmentsInput{
+				AccountId:        &accountID,
+				InstanceArn:      &instanceArn,
+				PermissionSetArn: &permissionSetArn,
+			})
+			for assignments.HasMorePages() {
+				assignmentsPage, err := assignments.NextPage(context.TODO())
+				if err != nil {
+					return err
+				}
+				for _, assignment := range assignmentsPage.AccountAssignments {
+					principalID := StringValue(assignment.PrincipalId)
+					principalType := string(assignment.PrincipalType)
+					g.Resources = append(g.Resources, terraformutils.NewResource(
+						strings.Join([]string{principalID, principalType, accountID, "AWS_ACCOUNT", permissionSetArn, instanceArn}, ","),
+						strings.Join([]string{permissionSetName, accountID, principalType, principalID}, "_"),
+						"aws_ssoadmin_account_assignment",
+						"aws",
+						map[string]string{
+							"instance_arn":       instanceArn,
+							"permission_set_arn": permissionSetArn,
+							"principal_id":       principalID,
+							"principal_type":     principalType,
+							"target_id":          accountID,
+							"target_type":        "AWS_ACCOUNT",
+						},
+						ssoAdminAllowEmptyValues,
+						map[string]interface{}{},
+					))
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+resource "aws_internet_gateway" "tfer--igw-0a1b2c3d" {
+  tags = {
+    Name = "main"
+  }
+
+  tags_all = {
+    Name = "main"
+  }
+
+  vpc_id = "vpc-0a1b2c3d"
+}
@@ -20,7 +20,9 @@ import (
 
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
 var tgwAllowEmptyValues = []string{"tags."}
@@ -94,6 +96,45 @@ func (g *TransitGatewayGenerator) getTransitGatewayVpcAttachments(svc *ec2.Clien
 	return nil
 }
 
+// getTransitGatewayRoutes creates the static routes of all route tables, default ones included, propagated
+// routes are managed by AWS
+func (g *TransitGatewayGenerator) getTransitGatewayRoutes(svc *ec2.Client) error {
+	p := ec2.NewDescribeTransitGatewayRouteTablesPaginator(svc, &ec2.DescribeTransitGatewayRouteTablesInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		for _, tgwrt := range page.TransitGatewayRouteTables {
+			routes, err := svc.SearchTransitGatewayRoutes(context.TODO(), &ec2.SearchTransitGatewayRoutesInput{
+				TransitGatewayRouteTableId: tgwrt.TransitGatewayRouteTableId,
+				Filters:                    []types.Filter{{Name: aws.String("type"), Values: []string{"static"}}},
+			})
+			if err != nil {
+				return err
+			}
+			if aws.ToBool(routes.AdditionalRoutesAvailable) {
+				log.Printf("[WARN] only the first %d static routes of %s are imported\n", len(routes.Routes), StringValue(tgwrt.TransitGatewayRouteTableId))
+			}
+			for _, route := range routes.Routes {
+				// routes to prefix lists are aws_ec2_transit_gateway_prefix_list_reference
+				if route.DestinationCidrBlock == nil {
+					continue
+				}
+				routeID := StringValue(tgwrt.TransitGatewayRouteTableId) + "_" + StringValue(route.DestinationCidrBlock)
+				g.Resources = append(g.Resources, terraformutils.NewSimpleResource(
+					routeID,
+					routeID,
+					"aws_ec2_transit_gateway_route",
+					"aws",
+					tgwAllowEmptyValues,
+				))
+			}
+		}
+	}
+	return nil
+}
+
 // Generate TerraformResources from AWS API,
 // from each customer gateway create 1 TerraformResource.
 // Need CustomerGatewayId as ID for terraform resource
@@ -119,5 +160,10 @@ func (g *TransitGatewayGenerator) InitResources() error {
 		log.Println(err)
 	}
 
+	err = g.getTransitGatewayRoutes(svc)
+	if err != nil {
+		log.Println(err)
+	}
+
 	return nil
 }
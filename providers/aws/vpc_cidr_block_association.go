@@ -0,0 +1,71 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aws
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+var vpcCidrBlockAssociationAllowEmptyValues = []string{"tags."}
+
+type VpcCidrBlockAssociationGenerator struct {
+	AWSService
+}
+
+func (VpcCidrBlockAssociationGenerator) createResources(vpcs *ec2.DescribeVpcsOutput) []terraformutils.Resource {
+	var resources []terraformutils.Resource
+	for _, vpc := range vpcs.Vpcs {
+		for _, association := range vpc.CidrBlockAssociationSet {
+			// the primary CIDR block is part of aws_vpc
+			if StringValue(association.CidrBlock) == StringValue(vpc.CidrBlock) ||
+				association.CidrBlockState == nil || association.CidrBlockState.State != types.VpcCidrBlockStateCodeAssociated {
+				continue
+			}
+			resources = append(resources, terraformutils.NewSimpleResource(
+				StringValue(association.AssociationId),
+				StringValue(association.AssociationId),
+				"aws_vpc_ipv4_cidr_block_association",
+				"aws",
+				vpcCidrBlockAssociationAllowEmptyValues,
+			))
+		}
+	}
+	return resources
+}
+
+// Generate TerraformResources from AWS API,
+// from each secondary IPv4 CIDR block of VPCs create 1 TerraformResource.
+// Need AssociationId as ID for terraform resource
+func (g *VpcCidrBlockAssociationGenerator) InitResources() error {
+	config, e := g.generateConfig()
+	if e != nil {
+		return e
+	}
+	svc := ec2.NewFromConfig(config)
+	p := ec2.NewDescribeVpcsPaginator(svc, &ec2.DescribeVpcsInput{})
+	for p.HasMorePages() {
+		page, err := p.NextPage(context.TODO())
+		if err != nil {
+			return err
+		}
+		g.Resources = append(g.Resources, g.createResources(page)...)
+	}
+	return nil
+}
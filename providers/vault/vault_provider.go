@@ -57,9 +57,6 @@ func (p *Provider) InitService(serviceName string, verbose bool) error {
 			"token":   p.token,
 			"address": p.address,
 		})
-		if err := service.(*ServiceGenerator).setVaultClient(); err != nil {
-			return err
-		}
 		return nil
 	}
 	return errors.New(p.GetName() + ": " + serviceName + " not supported service")
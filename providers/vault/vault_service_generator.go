@@ -32,6 +32,10 @@ func (g *ServiceGenerator) setVaultClient() error {
 }
 
 func (g *ServiceGenerator) InitResources() error {
+	// the client is created from the final args, --provider-config may replace address and token
+	if err := g.setVaultClient(); err != nil {
+		return err
+	}
 	switch g.resource {
 	case "secret_backend":
 		return g.createSecretBackendResources()
@@ -36,6 +36,12 @@ type ProviderWithSource interface {
 	GetSource() string
 }
 
+// ProviderWithRecorder is implemented by providers whose SDK clients use the recorder transport, only
+// their API traffic can be recorded and replayed
+type ProviderWithRecorder interface {
+	UsesRecorder() bool
+}
+
 type Provider struct {
 	Service ServiceGenerator
 	Config  cty.Value
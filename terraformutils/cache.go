@@ -0,0 +1,148 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// DefaultCacheTTL bounds the age of cached resources used by --resume
+const DefaultCacheTTL = 24 * time.Hour
+
+// RefreshCache persists the resources enumerated by each service and each refreshed state as soon as they
+// are known, so an import can be resumed after a crash. Entries are stored under
+// provider/region/service/, or provider/account/region/service/, states by type and ID.
+type RefreshCache struct {
+	dir    string
+	ttl    time.Duration
+	resume bool
+}
+
+// NewRefreshCache returns a cache in dir, nil when dir is empty. Cached entries are only read with resume,
+// as long as they are younger than ttl, 0 for no limit.
+func NewRefreshCache(dir string, ttl time.Duration, resume bool) *RefreshCache {
+	if dir == "" {
+		return nil
+	}
+	return &RefreshCache{dir: dir, ttl: ttl, resume: resume}
+}
+
+// CacheScope returns the cache directory of a service imported by provider, relative to the cache dir.
+// Services imported from an account of several are cached under the account ID.
+func CacheScope(provider ProviderGenerator, service string) string {
+	region := "global"
+	account := ""
+	if provider.GetService() != nil {
+		if r, ok := provider.GetService().GetArgs()["region"].(string); ok && r != "" {
+			region = r
+		}
+		account, _ = provider.GetService().GetArgs()["account_id"].(string)
+	}
+	return filepath.Join(provider.GetName(), account, region, service)
+}
+
+// LoadResources returns the resources cached for a service when resuming
+func (c *RefreshCache) LoadResources(scope string) ([]Resource, bool) {
+	var resources []Resource
+	if !c.load(filepath.Join(scope, "resources.json"), &resources) {
+		return nil, false
+	}
+	return resources, true
+}
+
+// SaveResources caches the resources enumerated by a service
+func (c *RefreshCache) SaveResources(scope string, resources []Resource) error {
+	if c == nil {
+		return nil
+	}
+	return c.save(filepath.Join(scope, "resources.json"), resources)
+}
+
+// Refresh refreshes the resource, with resume a cached state is used instead. Refreshed states are cached.
+func (c *RefreshCache) Refresh(r *Resource, scope string, provider *providerwrapper.ProviderWrapper) error {
+	if c == nil {
+		return r.Refresh(provider)
+	}
+	path := c.statePath(r, scope)
+	state := &terraform.InstanceState{}
+	if c.load(path, state) && state.ID != "" {
+		r.InstanceState = state
+		return nil
+	}
+	if err := r.Refresh(provider); err != nil {
+		return err
+	}
+	if err := c.save(path, r.InstanceState); err != nil {
+		log.Printf("[WARN] failed to cache state of %s: %v\n", r.InstanceInfo.Id, err)
+	}
+	return nil
+}
+
+func (c *RefreshCache) statePath(r *Resource, scope string) string {
+	id := sha256.Sum256([]byte(r.InstanceInfo.Id))
+	return filepath.Join(scope, "states", r.InstanceInfo.Type, hex.EncodeToString(id[:])+".json")
+}
+
+func (c *RefreshCache) load(path string, v interface{}) bool {
+	if c == nil || !c.resume {
+		return false
+	}
+	path = filepath.Join(c.dir, path)
+	info, err := os.Stat(path)
+	if err != nil || (c.ttl > 0 && time.Since(info.ModTime()) > c.ttl) {
+		return false
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(content, v) == nil
+}
+
+// save writes to a temporary file renamed into place, a crash never leaves a partial entry
+func (c *RefreshCache) save(path string, v interface{}) error {
+	path = filepath.Join(c.dir, path)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), ".cache-")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestRefreshCacheResources(t *testing.T) {
+	dir := t.TempDir()
+	scope := filepath.Join("aws", "eu-west-1", "vpc")
+	resources := []Resource{NewSimpleResource("vpc-1", "vpc-1", "aws_vpc", "aws", []string{})}
+
+	if err := NewRefreshCache(dir, DefaultCacheTTL, false).SaveResources(scope, resources); err != nil {
+		t.Fatal(err)
+	}
+	if _, cached := NewRefreshCache(dir, DefaultCacheTTL, false).LoadResources(scope); cached {
+		t.Errorf("cached resources are only used when resuming")
+	}
+	cached, exist := NewRefreshCache(dir, DefaultCacheTTL, true).LoadResources(scope)
+	if !exist || len(cached) != 1 || cached[0].InstanceState.ID != "vpc-1" || cached[0].InstanceInfo.Type != "aws_vpc" {
+		t.Errorf("expected the saved resources, got %v", cached)
+	}
+
+	old := time.Now().Add(-2 * DefaultCacheTTL)
+	if err := os.Chtimes(filepath.Join(dir, scope, "resources.json"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, exist := NewRefreshCache(dir, DefaultCacheTTL, true).LoadResources(scope); exist {
+		t.Errorf("expired resources should not be used")
+	}
+	if _, exist := NewRefreshCache(dir, 0, true).LoadResources(scope); !exist {
+		t.Errorf("entries never expire without ttl")
+	}
+	if _, exist := NewRefreshCache("", DefaultCacheTTL, true).LoadResources(scope); exist {
+		t.Errorf("no cache without dir")
+	}
+}
+
+func TestRefreshCacheStates(t *testing.T) {
+	dir := t.TempDir()
+	scope := filepath.Join("aws", "eu-west-1", "vpc")
+	cache := NewRefreshCache(dir, DefaultCacheTTL, true)
+	r := NewSimpleResource("vpc-1", "vpc-1", "aws_vpc", "aws", []string{})
+	state := &terraform.InstanceState{ID: "vpc-1", Attributes: map[string]string{"id": "vpc-1", "cidr_block": "10.0.0.0/16"}}
+	if err := cache.save(cache.statePath(&r, scope), state); err != nil {
+		t.Fatal(err)
+	}
+
+	// a cached state is used without provider
+	if err := cache.Refresh(&r, scope, nil); err != nil {
+		t.Fatal(err)
+	}
+	if r.InstanceState.Attributes["cidr_block"] != "10.0.0.0/16" {
+		t.Errorf("expected the cached state, got %v", r.InstanceState)
+	}
+	other := NewSimpleResource("vpc-1", "vpc-1", "aws_vpc", "aws", []string{})
+	if path := cache.statePath(&other, filepath.Join("aws", "us-east-1", "vpc")); path == cache.statePath(&r, scope) {
+		t.Errorf("states of regions should not collide")
+	}
+}
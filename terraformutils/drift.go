@@ -0,0 +1,308 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/terraform"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// DriftReport compares resources found in the cloud with a Terraform state
+type DriftReport struct {
+	// Unmanaged resources are found in the cloud but not in state
+	Unmanaged []DriftResource `json:"unmanaged"`
+	// Deleted resources are in state but not found in the cloud
+	Deleted []DriftResource `json:"deleted"`
+	// Drifted resources are in both, with different attributes
+	Drifted []ResourceDrift `json:"drifted"`
+
+	// found are the resources found in the cloud by type and ID, resources in state reported deleted by the
+	// import of one region are found by another, e.g. with one state for all regions
+	found map[string]struct{}
+}
+
+type DriftResource struct {
+	Type string `json:"type"`
+	// Name is the resource name in state, or the name Terraformer would generate for unmanaged resources
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+type ResourceDrift struct {
+	DriftResource
+	Attributes []AttributeDrift `json:"attributes"`
+}
+
+// AttributeDrift is a flatmap attribute changed outside of Terraform, missing values are empty
+type AttributeDrift struct {
+	Attribute string `json:"attribute"`
+	State     string `json:"state"`
+	Actual    string `json:"actual"`
+}
+
+func NewDriftReport() *DriftReport {
+	return &DriftReport{
+		Unmanaged: []DriftResource{},
+		Deleted:   []DriftResource{},
+		Drifted:   []ResourceDrift{},
+		found:     map[string]struct{}{},
+	}
+}
+
+func (d *DriftReport) HasDrift() bool {
+	return len(d.Unmanaged) > 0 || len(d.Deleted) > 0 || len(d.Drifted) > 0
+}
+
+// Add appends other report, e.g. of another service or region, resources found by one of them aren't deleted
+func (d *DriftReport) Add(other *DriftReport) {
+	d.Unmanaged = append(d.Unmanaged, other.Unmanaged...)
+	d.Drifted = append(d.Drifted, other.Drifted...)
+	if d.found == nil {
+		d.found = map[string]struct{}{}
+	}
+	for key := range other.found {
+		d.found[key] = struct{}{}
+	}
+	// imports of several regions report deleted resources of a state once each
+	deleted := []DriftResource{}
+	reported := map[string]struct{}{}
+	for _, r := range append(d.Deleted, other.Deleted...) {
+		key := mergeKey(r.Type, r.ID)
+		_, found := d.found[key]
+		if _, exist := reported[key]; !found && !exist {
+			reported[key] = struct{}{}
+			deleted = append(deleted, r)
+		}
+	}
+	d.Deleted = deleted
+	d.sort()
+}
+
+// DetectDrift compares refreshed resources with a state by type and ID. Read-only attributes in IgnoreKeys
+// of the resources are skipped as they are in generated code. Resources in state of the given types are reported
+// deleted when they weren't found in the cloud, so state of other providers and services can be used as well.
+// All types of the state are checked when types is nil, e.g. for state of one service, see also ProviderTypes.
+func DetectDrift(resources []Resource, state *StateV4, types []string, schema *providers.GetSchemaResponse) *DriftReport {
+	report := NewDriftReport()
+	checkedTypes := map[string]struct{}{}
+	for _, resourceType := range types {
+		checkedTypes[resourceType] = struct{}{}
+	}
+	stateByKey := map[string]ResourceStateV4{}
+	for _, r := range state.Resources {
+		if r.Mode != "managed" || len(r.Instances) == 0 {
+			continue
+		}
+		stateByKey[mergeKey(r.Type, r.Instances[0].ID())] = r
+	}
+
+	found := map[string]struct{}{}
+	for _, r := range resources {
+		checkedTypes[r.InstanceInfo.Type] = struct{}{}
+		key := mergeKey(r.InstanceInfo.Type, r.InstanceState.ID)
+		report.found[key] = struct{}{}
+		stateResource, exist := stateByKey[key]
+		if !exist {
+			report.Unmanaged = append(report.Unmanaged, DriftResource{
+				Type: r.InstanceInfo.Type,
+				Name: r.ResourceName,
+				ID:   r.InstanceState.ID,
+			})
+			continue
+		}
+		found[key] = struct{}{}
+		stateAttributes, err := stateResource.Instances[0].flatAttributes(stateResource.Type, schema)
+		if err != nil {
+			log.Printf("[WARN] skip attributes of %s.%s, failed to read its state: %v\n", stateResource.Type, stateResource.Name, err)
+			continue
+		}
+		attributes := attributesDrift(stateAttributes, r.InstanceState.Attributes, r.IgnoreKeys)
+		if len(attributes) > 0 {
+			report.Drifted = append(report.Drifted, ResourceDrift{
+				DriftResource: DriftResource{
+					Type: stateResource.Type,
+					Name: stateResource.Name,
+					ID:   r.InstanceState.ID,
+				},
+				Attributes: attributes,
+			})
+		}
+	}
+
+	for key, r := range stateByKey {
+		if _, exist := checkedTypes[r.Type]; types != nil && !exist {
+			continue
+		}
+		if _, exist := found[key]; !exist {
+			report.Deleted = append(report.Deleted, DriftResource{
+				Type: r.Type,
+				Name: r.Name,
+				ID:   r.Instances[0].ID(),
+			})
+		}
+	}
+	report.sort()
+	return report
+}
+
+// ProviderTypes returns the types of managed resources in state of the provider, by the provider address of
+// the resources, or by the type prefix for resources without one
+func (s *StateV4) ProviderTypes(providerName, providerSource string) []string {
+	address := ProviderAddress(providerSource)
+	legacyAddress := "provider." + providerName
+	unique := map[string]struct{}{}
+	types := []string{}
+	for _, r := range s.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		var ofProvider bool
+		switch {
+		case r.Provider == "":
+			ofProvider = strings.HasPrefix(r.Type, providerName+"_")
+		default:
+			// module resources and aliases are prefixed and suffixed, e.g. module.vpc.provider["..."].eu
+			ofProvider = strings.Contains(r.Provider, address) || strings.HasSuffix(r.Provider, legacyAddress) ||
+				strings.Contains(r.Provider, legacyAddress+".")
+		}
+		if _, exist := unique[r.Type]; ofProvider && !exist {
+			unique[r.Type] = struct{}{}
+			types = append(types, r.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (d *DriftReport) sort() {
+	less := func(resources []DriftResource) func(i, j int) bool {
+		return func(i, j int) bool {
+			if resources[i].Type != resources[j].Type {
+				return resources[i].Type < resources[j].Type
+			}
+			return resources[i].Name < resources[j].Name
+		}
+	}
+	sort.Slice(d.Unmanaged, less(d.Unmanaged))
+	sort.Slice(d.Deleted, less(d.Deleted))
+	sort.Slice(d.Drifted, func(i, j int) bool {
+		if d.Drifted[i].Type != d.Drifted[j].Type {
+			return d.Drifted[i].Type < d.Drifted[j].Type
+		}
+		return d.Drifted[i].Name < d.Drifted[j].Name
+	})
+}
+
+// flatAttributes returns attributes in the flatmap format returned by refresh
+func (i InstanceStateV4) flatAttributes(resourceType string, schema *providers.GetSchemaResponse) (map[string]string, error) {
+	if i.AttributesFlat != nil {
+		return i.AttributesFlat, nil
+	}
+	if schema == nil {
+		return nil, fmt.Errorf("provider schema is required to read attributes")
+	}
+	resourceSchema, exist := schema.ResourceTypes[resourceType]
+	if !exist || resourceSchema.Block == nil {
+		return nil, fmt.Errorf("resource type %s not found in provider schema", resourceType)
+	}
+	value, err := ctyjson.Unmarshal(i.Attributes, resourceSchema.Block.ImpliedType())
+	if err != nil {
+		return nil, err
+	}
+	return terraform.NewInstanceStateShimmedFromValue(value, int(resourceSchema.Version)).Attributes, nil
+}
+
+func attributesDrift(stateAttributes, actualAttributes map[string]string, ignoreKeys []string) []AttributeDrift {
+	var ignoreRegexps []*regexp.Regexp
+	for _, pattern := range ignoreKeys {
+		ignoreRegexps = append(ignoreRegexps, regexp.MustCompile(pattern))
+	}
+	keys := map[string]struct{}{}
+	for k := range stateAttributes {
+		keys[k] = struct{}{}
+	}
+	for k := range actualAttributes {
+		keys[k] = struct{}{}
+	}
+	var drift []AttributeDrift
+	for k := range keys {
+		if isIgnoredDriftKey(k, ignoreRegexps) || stateAttributes[k] == actualAttributes[k] {
+			continue
+		}
+		drift = append(drift, AttributeDrift{
+			Attribute: k,
+			State:     stateAttributes[k],
+			Actual:    actualAttributes[k],
+		})
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		return drift[i].Attribute < drift[j].Attribute
+	})
+	return drift
+}
+
+// counts of lists and maps change with their elements, which are reported anyway
+func isIgnoredDriftKey(key string, ignoreRegexps []*regexp.Regexp) bool {
+	if key == "id" || strings.HasSuffix(key, ".#") || strings.HasSuffix(key, ".%") {
+		return true
+	}
+	for _, r := range ignoreRegexps {
+		if r.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintTable writes a human readable summary of the report
+func (d *DriftReport) PrintTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tRESOURCE\tID\tATTRIBUTE\tSTATE\tACTUAL")
+	for _, r := range d.Unmanaged {
+		fmt.Fprintf(tw, "unmanaged\t%s.%s\t%s\t\t\t\n", r.Type, r.Name, r.ID)
+	}
+	for _, r := range d.Deleted {
+		fmt.Fprintf(tw, "deleted\t%s.%s\t%s\t\t\t\n", r.Type, r.Name, r.ID)
+	}
+	for _, r := range d.Drifted {
+		for _, a := range r.Attributes {
+			fmt.Fprintf(tw, "drifted\t%s.%s\t%s\t%s\t%s\t%s\n", r.Type, r.Name, r.ID, a.Attribute, tableValue(a.State), tableValue(a.Actual))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\n%d unmanaged, %d deleted, %d drifted\n", len(d.Unmanaged), len(d.Deleted), len(d.Drifted))
+	return err
+}
+
+// long values like policies are kept in full in the JSON report only
+func tableValue(value string) string {
+	const maxLength = 60
+	value = strings.NewReplacer("\n", "\\n", "\t", " ").Replace(value)
+	if len(value) > maxLength {
+		return value[:maxLength-3] + "..."
+	}
+	return value
+}
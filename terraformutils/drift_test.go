@@ -0,0 +1,121 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectDrift(t *testing.T) {
+	vpc := func(id, cidr, arn string) Resource {
+		return NewResource(id, id, "aws_vpc", "aws", map[string]string{
+			"id":         id,
+			"cidr_block": cidr,
+			"arn":        arn,
+			"tags.%":     "1",
+			"tags.Name":  id,
+		}, []string{}, map[string]interface{}{})
+	}
+	data, err := PrintTfStateV4([]Resource{
+		vpc("vpc-1", "10.0.0.0/16", "arn-1"),
+		vpc("vpc-2", "10.1.0.0/16", "arn-2"),
+		NewResource("sg-1", "sg-1", "aws_security_group", "aws", map[string]string{"id": "sg-1"}, []string{}, map[string]interface{}{}),
+	}, "hashicorp/aws", testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := ReadTfState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := vpc("vpc-1", "10.2.0.0/16", "arn-changed")
+	changed.IgnoreKeys = []string{"^arn$"}
+	report := DetectDrift([]Resource{changed, vpc("vpc-3", "10.3.0.0/16", "arn-3")}, state, []string{}, testSchema())
+
+	if expected := []DriftResource{{Type: "aws_vpc", Name: "tfer--vpc-3", ID: "vpc-3"}}; !reflect.DeepEqual(report.Unmanaged, expected) {
+		t.Errorf("expected unmanaged %v, got %v", expected, report.Unmanaged)
+	}
+	// security groups were not imported, so they are not reported as deleted
+	if expected := []DriftResource{{Type: "aws_vpc", Name: "tfer--vpc-2", ID: "vpc-2"}}; !reflect.DeepEqual(report.Deleted, expected) {
+		t.Errorf("expected deleted %v, got %v", expected, report.Deleted)
+	}
+	expected := []ResourceDrift{{
+		DriftResource: DriftResource{Type: "aws_vpc", Name: "tfer--vpc-1", ID: "vpc-1"},
+		Attributes:    []AttributeDrift{{Attribute: "cidr_block", State: "10.0.0.0/16", Actual: "10.2.0.0/16"}},
+	}}
+	if !reflect.DeepEqual(report.Drifted, expected) {
+		t.Errorf("expected drifted %v, got %v", expected, report.Drifted)
+	}
+
+	var table bytes.Buffer
+	if err := report.PrintTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), "1 unmanaged, 1 deleted, 1 drifted") {
+		t.Errorf("unexpected table %s", table.String())
+	}
+
+	// no resources of a type are left in the cloud
+	report = DetectDrift([]Resource{changed}, state, []string{"aws_security_group"}, testSchema())
+	if expected := []DriftResource{{Type: "aws_security_group", Name: "tfer--sg-1", ID: "sg-1"}, {Type: "aws_vpc", Name: "tfer--vpc-2", ID: "vpc-2"}}; !reflect.DeepEqual(report.Deleted, expected) {
+		t.Errorf("expected deleted %v, got %v", expected, report.Deleted)
+	}
+	report = DetectDrift(nil, state, nil, testSchema())
+	if len(report.Deleted) != 3 {
+		t.Errorf("all resources in state of a service should be deleted, got %v", report.Deleted)
+	}
+}
+
+// the sg service imports aws_security_group, all of them were deleted
+func TestDetectDriftProviderTypes(t *testing.T) {
+	data, err := PrintTfStateV4([]Resource{
+		NewResource("sg-1", "sg-1", "aws_security_group", "aws", map[string]string{"id": "sg-1"}, []string{}, map[string]interface{}{}),
+		NewResource("vpc-1", "vpc-1", "aws_vpc", "aws", map[string]string{"id": "vpc-1"}, []string{}, map[string]interface{}{}),
+	}, "hashicorp/aws", testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := ReadTfState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Resources = append(state.Resources,
+		ResourceStateV4{Mode: "managed", Type: "google_compute_network", Name: "main", Provider: `provider["registry.terraform.io/hashicorp/google"]`,
+			Instances: []InstanceStateV4{{AttributesFlat: map[string]string{"id": "main"}}}},
+		ResourceStateV4{Mode: "managed", Type: "aws_subnet", Name: "a", Provider: `module.network.provider["registry.terraform.io/hashicorp/aws"]`,
+			Instances: []InstanceStateV4{{AttributesFlat: map[string]string{"id": "subnet-1"}}}})
+
+	types := state.ProviderTypes("aws", "hashicorp/aws")
+	if expected := []string{"aws_security_group", "aws_subnet", "aws_vpc"}; !reflect.DeepEqual(types, expected) {
+		t.Fatalf("expected types %v, got %v", expected, types)
+	}
+
+	// regions of an import are reported apart, a resource found in one region isn't deleted
+	vpc := NewResource("vpc-1", "vpc-1", "aws_vpc", "aws", map[string]string{"id": "vpc-1"}, []string{}, map[string]interface{}{})
+	report := NewDriftReport()
+	report.Add(DetectDrift(nil, state, types, testSchema()))
+	report.Add(DetectDrift([]Resource{vpc}, state, types, testSchema()))
+	expected := []DriftResource{
+		{Type: "aws_security_group", Name: "tfer--sg-1", ID: "sg-1"},
+		{Type: "aws_subnet", Name: "a", ID: "subnet-1"},
+	}
+	if !reflect.DeepEqual(report.Deleted, expected) {
+		t.Errorf("expected deleted %v, got %v", expected, report.Deleted)
+	}
+}
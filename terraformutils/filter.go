@@ -0,0 +1,503 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// legacyFilter matches filters in the service=id1:id2 form, other forms of legacy filters start with Type= or Name=
+var legacyFilter = regexp.MustCompile(`^[\w-]+=([^=~].*)?$`)
+
+// resourceFields are resolved from the resource instead of its attributes, they are known before refresh
+var resourceFields = map[string]func(r *Resource) string{
+	"id":   func(r *Resource) string { return r.InstanceState.ID },
+	"type": func(r *Resource) string { return r.InstanceInfo.Type },
+	"name": func(r *Resource) string { return UnsanitizeName(r.ResourceName) },
+}
+
+// FilterExpression is a filter like `type == "aws_instance" && tags.env =~ "^prod"`, evaluated against
+// the resource, its attributes and its item. Supported are:
+//
+//	path == "value", path != "value"   equality
+//	path =~ "regex", path !~ "regex"   regular expressions
+//	path in ["a", "b-*"]               glob patterns
+//	path < 10, <=, >, >=               numeric comparison
+//	path                               attribute existence
+//	!, &&, ||, ( )                     negation and boolean combinations
+//
+// id, type and name are the resource ID, type and name given by the provider, not escaped by TfSanitize, other
+// paths are attributes like tags.env.
+// A comparison is true when any value found at path matches.
+type FilterExpression struct {
+	raw  string
+	root filterNode
+}
+
+// IsFilterExpression tells expressions apart from legacy filters like vpc=id1:id2 or Type=sg;Name=vpc_id;Value=id
+func IsFilterExpression(rawFilter string) bool {
+	if strings.HasPrefix(rawFilter, "Type=") || strings.HasPrefix(rawFilter, "Name=") {
+		return false
+	}
+	return !legacyFilter.MatchString(rawFilter)
+}
+
+// SplitFilters splits comma separated legacy filters like vpc=id1,subnet=id2 as --filter always did, expressions
+// may contain commas and are kept as they are
+func SplitFilters(rawFilters []string) []string {
+	var filters []string
+	for _, rawFilter := range rawFilters {
+		if IsFilterExpression(rawFilter) {
+			filters = append(filters, rawFilter)
+			continue
+		}
+		values, err := csv.NewReader(strings.NewReader(rawFilter)).Read()
+		if err != nil {
+			filters = append(filters, rawFilter)
+			continue
+		}
+		filters = append(filters, values...)
+	}
+	return filters
+}
+
+// ValidateFilter returns the parse error of a filter expression, legacy filters are not checked
+func ValidateFilter(rawFilter string) error {
+	if !IsFilterExpression(rawFilter) {
+		return nil
+	}
+	_, err := ParseFilterExpression(rawFilter)
+	return err
+}
+
+func ParseFilterExpression(expression string) (*FilterExpression, error) {
+	tokens, err := lexFilter(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %s: %v", expression, err)
+	}
+	p := &filterParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = p.errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %s: %v", expression, err)
+	}
+	return &FilterExpression{raw: expression, root: root}, nil
+}
+
+func (e *FilterExpression) Match(resource Resource) bool {
+	return e.root.eval(&resource)
+}
+
+func (e *FilterExpression) String() string {
+	return e.raw
+}
+
+// isInitial is true when the expression only refers to resource fields, so it can run before refresh
+func (e *FilterExpression) isInitial() bool {
+	for _, path := range e.root.paths() {
+		if _, exist := resourceFields[path]; !exist {
+			return false
+		}
+	}
+	return true
+}
+
+type filterNode interface {
+	eval(r *Resource) bool
+	paths() []string
+}
+
+type notNode struct {
+	node filterNode
+}
+
+func (n *notNode) eval(r *Resource) bool { return !n.node.eval(r) }
+func (n *notNode) paths() []string       { return n.node.paths() }
+
+type binaryNode struct {
+	and         bool
+	left, right filterNode
+}
+
+func (n *binaryNode) eval(r *Resource) bool {
+	if n.and {
+		return n.left.eval(r) && n.right.eval(r)
+	}
+	return n.left.eval(r) || n.right.eval(r)
+}
+
+func (n *binaryNode) paths() []string {
+	return append(n.left.paths(), n.right.paths()...)
+}
+
+type existsNode struct {
+	path string
+}
+
+func (n *existsNode) eval(r *Resource) bool {
+	_, found := filterValues(r, n.path)
+	return found
+}
+
+func (n *existsNode) paths() []string { return []string{n.path} }
+
+type compareNode struct {
+	path  string
+	op    string
+	value string
+	regex *regexp.Regexp
+	globs []*regexp.Regexp
+}
+
+func (n *compareNode) eval(r *Resource) bool {
+	values, _ := filterValues(r, n.path)
+	switch n.op {
+	case "!=":
+		return !n.any(values, "==")
+	case "!~":
+		return !n.any(values, "=~")
+	}
+	return n.any(values, n.op)
+}
+
+func (n *compareNode) any(values []string, op string) bool {
+	for _, value := range values {
+		if n.match(value, op) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *compareNode) match(value, op string) bool {
+	switch op {
+	case "==":
+		return value == n.value
+	case "=~":
+		return n.regex.MatchString(value)
+	case "in":
+		for _, glob := range n.globs {
+			if glob.MatchString(value) {
+				return true
+			}
+		}
+		return false
+	}
+	// < <= > >= compare numbers, values which aren't numbers don't match
+	a, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	b, _ := strconv.ParseFloat(n.value, 64)
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	default:
+		return a >= b
+	}
+}
+
+func (n *compareNode) paths() []string { return []string{n.path} }
+
+// filterValues returns values at path, attributes are preferred to the item like in ResourceFilter
+func filterValues(r *Resource, path string) ([]string, bool) {
+	if field, exist := resourceFields[path]; exist {
+		if (path == "id" && r.InstanceState == nil) || (path == "type" && r.InstanceInfo == nil) {
+			return nil, false
+		}
+		return []string{field(r)}, true
+	}
+	var found bool
+	var values []interface{}
+	if r.InstanceState != nil {
+		found, values = walkAndGet(path, r.InstanceState.Attributes)
+	}
+	if !found || len(values) == 0 {
+		var foundInItem bool
+		var itemValues []interface{}
+		foundInItem, itemValues = walkAndGet(path, r.Item)
+		if foundInItem {
+			found, values = true, itemValues
+		}
+	}
+	var strs []string
+	for _, value := range values {
+		strs = append(strs, fmt.Sprint(value))
+	}
+	return strs, found
+}
+
+// globRegexp converts a glob pattern with * and ? to an anchored regular expression
+func globRegexp(glob string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, c := range glob {
+		switch c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+const (
+	tokenEOF = iota
+	tokenOperator
+	tokenString
+	tokenWord
+)
+
+type filterToken struct {
+	kind  int
+	text  string
+	value string
+	pos   int
+}
+
+func (t filterToken) String() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var filterOperators = []string{"&&", "||", "==", "!=", "=~", "!~", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func isFilterWordChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("_-./%#:*?", c) >= 0
+}
+
+func lexFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	i := 0
+	for i < len(expression) {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expression) && expression[end] != '"' {
+				if expression[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expression) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			value, err := strconv.Unquote(expression[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: expression[i : end+1], value: value, pos: i})
+			i = end + 1
+		case isFilterWordChar(c):
+			end := i
+			for end < len(expression) && isFilterWordChar(expression[end]) {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenWord, text: expression[i:end], value: expression[i:end], pos: i})
+			i = end
+		default:
+			operator := ""
+			for _, op := range filterOperators {
+				if strings.HasPrefix(expression[i:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i)
+			}
+			tokens = append(tokens, filterToken{kind: tokenOperator, text: operator, pos: i})
+			i += len(operator)
+		}
+	}
+	return append(tokens, filterToken{kind: tokenEOF, pos: len(expression)}), nil
+}
+
+// filterParser is a recursive descent parser, || binds weaker than && which binds weaker than !
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() filterToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) accept(operator string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == operator {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format+" at position %d", append(args, p.peek().pos)...)
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.accept("!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{node: node}, nil
+	}
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("expected \")\" instead of %s", p.peek())
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *filterParser) parseComparison() (filterNode, error) {
+	t := p.peek()
+	if t.kind != tokenWord {
+		return nil, p.errorf("expected an attribute instead of %s", t)
+	}
+	p.next()
+	path := t.value
+
+	op := p.peek()
+	switch {
+	case op.kind == tokenWord && op.text == "in":
+		p.next()
+		return p.parseIn(path)
+	case op.kind != tokenOperator:
+		return &existsNode{path: path}, nil
+	}
+	switch op.text {
+	case "==", "!=", "=~", "!~", "<", "<=", ">", ">=":
+		p.next()
+	default:
+		return &existsNode{path: path}, nil
+	}
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	node := &compareNode{path: path, op: op.text, value: value.value}
+	switch op.text {
+	case "=~", "!~":
+		if value.kind != tokenString {
+			return nil, fmt.Errorf("expected a regular expression string at position %d", value.pos)
+		}
+		node.regex, err = regexp.Compile(value.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %v", value.pos, err)
+		}
+	case "<", "<=", ">", ">=":
+		if _, err := strconv.ParseFloat(value.value, 64); err != nil {
+			return nil, fmt.Errorf("expected a number at position %d", value.pos)
+		}
+	}
+	return node, nil
+}
+
+func (p *filterParser) parseIn(path string) (filterNode, error) {
+	if !p.accept("[") {
+		return nil, p.errorf("expected \"[\" instead of %s", p.peek())
+	}
+	node := &compareNode{path: path, op: "in"}
+	for !p.accept("]") {
+		if len(node.globs) > 0 && !p.accept(",") {
+			return nil, p.errorf("expected \",\" or \"]\" instead of %s", p.peek())
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		node.globs = append(node.globs, globRegexp(value.value))
+	}
+	return node, nil
+}
+
+// parseValue accepts quoted strings, numbers and booleans
+func (p *filterParser) parseValue() (filterToken, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenString:
+		return p.next(), nil
+	case tokenWord:
+		if _, err := strconv.ParseFloat(t.value, 64); err == nil || t.value == "true" || t.value == "false" {
+			return p.next(), nil
+		}
+	}
+	return t, p.errorf("expected a quoted value instead of %s", t)
+}
@@ -0,0 +1,131 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsFilterExpression(t *testing.T) {
+	tests := map[string]bool{
+		"aws_vpc=myid":                       false,
+		"aws_vpc=:myid":                      false,
+		"resource=id1:'project:dataset_id'":  false,
+		"Name=tags.Abc":                      false,
+		"Type=sg;Name=vpc_id;Value=VPC_ID":   false,
+		`type == "aws_instance"`:             true,
+		`tags.env=~"^prod"`:                  true,
+		`name=~"^prod"`:                      true,
+		`id=="i-1"`:                          true,
+		"tags.team":                          true,
+		`!(name in ["legacy-*"])`:            true,
+		`tags.env == "prod" || tags.sandbox`: true,
+	}
+	for filter, expected := range tests {
+		if actual := IsFilterExpression(filter); actual != expected {
+			t.Errorf("IsFilterExpression(%q) = %v, expected %v", filter, actual, expected)
+		}
+	}
+}
+
+func TestSplitFilters(t *testing.T) {
+	filters := SplitFilters([]string{"vpc=id1,subnet=id2", `name in ["a", "b"]`, "Name=tags.Abc"})
+	expected := []string{"vpc=id1", "subnet=id2", `name in ["a", "b"]`, "Name=tags.Abc"}
+	if !reflect.DeepEqual(filters, expected) {
+		t.Errorf("expected %v, got %v", expected, filters)
+	}
+}
+
+func TestFilterExpressionMatch(t *testing.T) {
+	web := testResource("i-1", "web", "aws_instance",
+		map[string]string{"tags.%": "2", "tags.env": "production", "tags.team": "payments", "cpu_core_count": "4"}, nil)
+	legacy := testResource("i-2", "legacy-web", "aws_instance",
+		map[string]string{"tags.env": "prod-eu", "cpu_core_count": "16"}, nil)
+	// names are escaped by NewResource, filters match the name given by the provider
+	sandbox := testResource("vpc-1", "sandbox vpc", "aws_vpc",
+		map[string]string{}, map[string]interface{}{"tags": map[string]interface{}{"team": "payments", "env": "sandbox"}})
+
+	tests := []struct {
+		expression string
+		expected   []bool // web, legacy, sandbox
+	}{
+		{`type == "aws_instance"`, []bool{true, true, false}},
+		{`type != "aws_instance"`, []bool{false, false, true}},
+		{`type == "aws_instance" && tags.env =~ "^prod" && !(name in ["legacy-*"])`, []bool{true, false, false}},
+		{`tags.team == "payments" && !(tags.env == "sandbox")`, []bool{true, false, false}},
+		{`tags.env !~ "prod"`, []bool{false, false, true}},
+		{`name in ["web", "sand*"]`, []bool{true, false, true}},
+		{`name == "sandbox vpc"`, []bool{false, false, true}},
+		{`id in ["i-?"]`, []bool{true, true, false}},
+		{`cpu_core_count > 8`, []bool{false, true, false}},
+		{`cpu_core_count <= 4`, []bool{true, false, false}},
+		{`tags.team`, []bool{true, false, true}},
+		{`!tags.team`, []bool{false, true, false}},
+		{`tags.env == "production" || tags.env == "sandbox"`, []bool{true, false, true}},
+		{`tags.team == "payments" && (tags.env == "production" || type == "aws_vpc")`, []bool{true, false, true}},
+		{`tags.env == "x" || tags.team == "payments" && type == "aws_vpc"`, []bool{false, false, true}},
+	}
+	for _, test := range tests {
+		expression, err := ParseFilterExpression(test.expression)
+		if err != nil {
+			t.Errorf("%s: %v", test.expression, err)
+			continue
+		}
+		for i, r := range []Resource{web, legacy, sandbox} {
+			if actual := expression.Match(r); actual != test.expected[i] {
+				t.Errorf("%s on %s = %v, expected %v", test.expression, r.ResourceName, actual, test.expected[i])
+			}
+		}
+	}
+}
+
+func TestFilterExpressionErrors(t *testing.T) {
+	for _, expression := range []string{
+		`type ==`,
+		`type == aws_instance`,
+		`tags.env =~ "["`,
+		`cpu > "a"`,
+		`(type == "a"`,
+		`name in ["a" "b"]`,
+		`type == "a" &&`,
+		`type == "unterminated`,
+		`type = "a"`,
+	} {
+		if _, err := ParseFilterExpression(expression); err == nil {
+			t.Errorf("%s: expected an error", expression)
+		}
+	}
+}
+
+func TestServiceCleanupWithFilterExpression(t *testing.T) {
+	service := Service{
+		Resources: []Resource{
+			testResource("i-1", "web", "aws_instance", map[string]string{"tags.team": "payments"}, nil),
+			testResource("i-2", "legacy-web", "aws_instance", map[string]string{"tags.team": "payments"}, nil),
+			testResource("i-3", "db", "aws_instance", map[string]string{"tags.team": "search"}, nil),
+		},
+	}
+	service.ParseFilters([]string{`!(name in ["legacy-*"])`, `tags.team == "payments"`})
+
+	service.InitialCleanup()
+	if len(service.Resources) != 2 {
+		t.Errorf("expected name filter before refresh, got %d resources", len(service.Resources))
+	}
+	service.PostRefreshCleanup()
+	if len(service.Resources) != 1 || service.Resources[0].InstanceState.ID != "i-1" {
+		t.Errorf("expected only i-1, got %v", service.Resources)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/hashicorp/hcl/hcl/ast"
 	hclPrinter "github.com/hashicorp/hcl/hcl/printer"
 	hclParser "github.com/hashicorp/hcl/json/parser"
+	"github.com/hashicorp/terraform/providers"
 )
 
 // Copy code from https://github.com/kubernetes/kops project with few changes for support many provider and heredoc
@@ -161,9 +162,16 @@ func (v *astSanitizer) visitObjectItem(o *ast.ObjectItem) {
 }
 
 func Print(data interface{}, mapsObjects map[string]struct{}, format string, sort bool) ([]byte, error) {
+	return PrintWithSchema(data, mapsObjects, format, sort, nil)
+}
+
+// PrintWithSchema prints like Print, hcl2 format uses the provider schema to tell blocks from attributes
+func PrintWithSchema(data interface{}, mapsObjects map[string]struct{}, format string, sort bool, schema *providers.GetSchemaResponse) ([]byte, error) {
 	switch format {
 	case "hcl":
 		return hclPrint(data, mapsObjects, sort)
+	case "hcl2":
+		return hcl2Print(data, schema, sort)
 	case "json":
 		return jsonPrint(data)
 	}
@@ -282,7 +290,7 @@ func TfSanitize(name string) string {
 }
 
 // Print hcl file from TerraformResource + provider
-func HclPrintResource(resources []Resource, providerData map[string]interface{}, output string, sort bool) ([]byte, error) {
+func HclPrintResource(resources []Resource, providerData map[string]interface{}, output string, sort bool, schema *providers.GetSchemaResponse) ([]byte, error) {
 	resourcesByType := map[string]map[string]interface{}{}
 	mapsObjects := map[string]struct{}{}
 	indexRe := regexp.MustCompile(`\.[0-9]+`)
@@ -293,10 +301,9 @@ func HclPrintResource(resources []Resource, providerData map[string]interface{},
 			resourcesByType[res.InstanceInfo.Type] = r
 		}
 
+		// names are de-duplicated by NameResources, a duplicate here would silently drop a resource
 		if r[res.ResourceName] != nil {
-			log.Println(resources)
-			log.Printf("[ERR]: duplicate resource found: %s.%s", res.InstanceInfo.Type, res.ResourceName)
-			continue
+			return nil, fmt.Errorf("duplicate resource found: %s.%s", res.InstanceInfo.Type, res.ResourceName)
 		}
 
 		r[res.ResourceName] = res.Item
@@ -318,7 +325,7 @@ func HclPrintResource(resources []Resource, providerData map[string]interface{},
 	}
 	var err error
 
-	hclBytes, err := Print(data, mapsObjects, output, sort)
+	hclBytes, err := PrintWithSchema(data, mapsObjects, output, sort, schema)
 	if err != nil {
 		return []byte{}, err
 	}
@@ -0,0 +1,491 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// top level blocks are printed in this order, other blocks follow sorted by name
+var hcl2BlockOrder = []string{"terraform", "provider", "variable", "locals", "data", "resource", "import", "output"}
+
+var heredoc = regexp.MustCompile(`(?s)^<<-?([A-Za-z_][A-Za-z0-9_]*)\n(.*)\n[ \t]*([A-Za-z_][A-Za-z0-9_]*)$`)
+var singleInterpolation = regexp.MustCompile(`^\$\{([^{}]+)\}$`)
+
+// hcl2Printer prints data structured like Terraform JSON configuration as native HCL2. Blocks and attributes
+// are told apart by the provider schema, without schema maps are attributes and lists of maps are blocks.
+//
+// Strings are printed as templates as with hcl output: ${...} sequences are interpolations, a single
+// interpolation is printed as expression, and JSON documents of policies are printed with jsonencode().
+type hcl2Printer struct {
+	schema *providers.GetSchemaResponse
+	sort   bool
+}
+
+func hcl2Print(data interface{}, schema *providers.GetSchemaResponse, sortBlocks bool) ([]byte, error) {
+	// normalize structs and typed maps of providers to plain JSON values
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(dataJSON))
+	decoder.UseNumber()
+	var root map[string]interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("error converting data to HCL: %v", err)
+	}
+
+	p := &hcl2Printer{schema: schema, sort: sortBlocks}
+	f := hclwrite.NewFile()
+	body := f.Body()
+	first := true
+	for _, blockType := range hcl2TopLevelBlocks(root) {
+		for _, block := range p.topLevelBlocks(blockType, root[blockType]) {
+			if !first {
+				body.AppendNewline()
+			}
+			first = false
+			body.AppendBlock(block)
+		}
+	}
+	return hclwrite.Format(f.Bytes()), nil
+}
+
+func hcl2TopLevelBlocks(root map[string]interface{}) []string {
+	var blockTypes []string
+	known := map[string]struct{}{}
+	for _, blockType := range hcl2BlockOrder {
+		known[blockType] = struct{}{}
+		if _, exist := root[blockType]; exist {
+			blockTypes = append(blockTypes, blockType)
+		}
+	}
+	var others []string
+	for blockType := range root {
+		if _, exist := known[blockType]; !exist {
+			others = append(others, blockType)
+		}
+	}
+	sort.Strings(others)
+	return append(blockTypes, others...)
+}
+
+func (p *hcl2Printer) topLevelBlocks(blockType string, value interface{}) []*hclwrite.Block {
+	var blocks []*hclwrite.Block
+	switch blockType {
+	case "resource", "data":
+		for _, resourceType := range sortedKeys(value) {
+			var schema *configschema.Block
+			if p.schema != nil {
+				resourceSchemas := p.schema.ResourceTypes
+				if blockType == "data" {
+					resourceSchemas = p.schema.DataSources
+				}
+				schema = resourceSchemas[resourceType].Block
+			}
+			resources := value.(map[string]interface{})[resourceType]
+			for _, name := range sortedKeys(resources) {
+				block := hclwrite.NewBlock(blockType, []string{resourceType, name})
+				for _, body := range bodies(resources.(map[string]interface{})[name]) {
+					p.writeBody(block.Body(), body, schema, true)
+				}
+				blocks = append(blocks, block)
+			}
+		}
+	case "provider":
+		var schema *configschema.Block
+		if p.schema != nil {
+			schema = p.schema.Provider.Block
+		}
+		for _, name := range sortedKeys(value) {
+			// aliases of a provider are a list of configurations
+			for _, body := range bodies(value.(map[string]interface{})[name]) {
+				block := hclwrite.NewBlock(blockType, []string{name})
+				p.writeBody(block.Body(), body, schema, false)
+				blocks = append(blocks, block)
+			}
+		}
+	case "terraform":
+		for _, body := range bodies(value) {
+			block := hclwrite.NewBlock(blockType, nil)
+			p.writeTerraformBody(block.Body(), body)
+			blocks = append(blocks, block)
+		}
+	case "locals":
+		for _, body := range bodies(value) {
+			block := hclwrite.NewBlock(blockType, nil)
+			p.writeBody(block.Body(), body, nil, false)
+			blocks = append(blocks, block)
+		}
+	case "import":
+		for _, body := range bodies(value) {
+			block := hclwrite.NewBlock(blockType, nil)
+			block.Body().SetAttributeRaw("to", referenceTokens(body["to"]))
+			block.Body().SetAttributeRaw("id", p.valueTokens("id", body["id"]))
+			blocks = append(blocks, block)
+		}
+	default:
+		// blocks with one label like output, variable and module
+		for _, name := range sortedKeys(value) {
+			for _, body := range bodies(value.(map[string]interface{})[name]) {
+				block := hclwrite.NewBlock(blockType, []string{name})
+				p.writeBody(block.Body(), body, nil, false)
+				blocks = append(blocks, block)
+			}
+		}
+	}
+	return blocks
+}
+
+// writeTerraformBody prints the terraform block, required_providers is a block of provider objects and
+// backends are blocks labeled with their type
+func (p *hcl2Printer) writeTerraformBody(body *hclwrite.Body, values map[string]interface{}) {
+	var attributes []string
+	for _, k := range sortedKeys(values) {
+		if k != "required_providers" && k != "backend" {
+			attributes = append(attributes, k)
+		}
+	}
+	for _, k := range attributes {
+		body.SetAttributeRaw(k, p.valueTokens(k, values[k]))
+	}
+	if requiredProviders, exist := values["required_providers"]; exist {
+		block := body.AppendNewBlock("required_providers", nil)
+		for _, sources := range bodies(requiredProviders) {
+			for _, name := range sortedKeys(sources) {
+				block.Body().SetAttributeRaw(name, p.valueTokens(name, sources[name]))
+			}
+		}
+	}
+	if backend, exist := values["backend"]; exist {
+		for _, backends := range bodies(backend) {
+			for _, name := range sortedKeys(backends) {
+				for _, backend := range bodies(backends[name]) {
+					block := body.AppendNewBlock("backend", []string{name})
+					p.writeBody(block.Body(), backend, nil, false)
+				}
+			}
+		}
+	}
+}
+
+// writeBody prints attributes first, then nested blocks
+func (p *hcl2Printer) writeBody(body *hclwrite.Body, values map[string]interface{}, schema *configschema.Block, isResource bool) {
+	type nestedBlock struct {
+		name   string
+		schema *configschema.Block
+		// nesting decides how block values are printed
+		nesting configschema.NestingMode
+	}
+	var blocks []nestedBlock
+	for _, k := range sortedKeys(values) {
+		value := values[k]
+		if value == nil {
+			continue
+		}
+		if !hclsyntax.ValidIdentifier(k) {
+			log.Printf("[WARN] skip %s, it isn't a valid HCL2 argument name\n", k)
+			continue
+		}
+		if isResource && (k == "depends_on" || k == "provider") {
+			body.SetAttributeRaw(k, referenceTokens(value))
+			continue
+		}
+		if schema != nil {
+			if attribute, exist := schema.Attributes[k]; exist {
+				// lists of objects are written as blocks, as Terraform accepts for attributes of legacy providers
+				if elementType, isCollection := collectionElementType(attribute.Type); isCollection && elementType.IsObjectType() && isListOfMaps(value) {
+					nesting := configschema.NestingList
+					if attribute.Type.IsSetType() {
+						nesting = configschema.NestingSet
+					}
+					blocks = append(blocks, nestedBlock{name: k, nesting: nesting})
+					continue
+				}
+				tokens := p.valueTokens(k, value)
+				if attribute.Type.IsSetType() {
+					tokens = p.sortedSetTokens(k, value, tokens)
+				}
+				body.SetAttributeRaw(k, tokens)
+				continue
+			}
+			if blockSchema, exist := schema.BlockTypes[k]; exist {
+				blocks = append(blocks, nestedBlock{name: k, schema: &blockSchema.Block, nesting: blockSchema.Nesting})
+				continue
+			}
+		}
+		if isListOfMaps(value) {
+			blocks = append(blocks, nestedBlock{name: k, nesting: configschema.NestingList})
+			continue
+		}
+		body.SetAttributeRaw(k, p.valueTokens(k, value))
+	}
+
+	for _, b := range blocks {
+		value := values[b.name]
+		if b.nesting == configschema.NestingMap {
+			if labeled, ok := value.(map[string]interface{}); ok {
+				for _, label := range sortedKeys(labeled) {
+					for _, blockBody := range bodies(labeled[label]) {
+						p.writeBody(body.AppendNewBlock(b.name, []string{label}).Body(), blockBody, b.schema, false)
+					}
+				}
+				continue
+			}
+		}
+		blockBodies := bodies(value)
+		var nested []*hclwrite.Block
+		for _, blockBody := range blockBodies {
+			block := hclwrite.NewBlock(b.name, nil)
+			p.writeBody(block.Body(), blockBody, b.schema, false)
+			nested = append(nested, block)
+		}
+		// order of set elements doesn't matter, keep output reproducible
+		if p.sort && b.nesting == configschema.NestingSet {
+			sort.SliceStable(nested, func(i, j int) bool {
+				return string(nested[i].BuildTokens(nil).Bytes()) < string(nested[j].BuildTokens(nil).Bytes())
+			})
+		}
+		for _, block := range nested {
+			body.AppendBlock(block)
+		}
+	}
+}
+
+func (p *hcl2Printer) sortedSetTokens(key string, value interface{}, tokens hclwrite.Tokens) hclwrite.Tokens {
+	elements, ok := value.([]interface{})
+	if !p.sort || !ok {
+		return tokens
+	}
+	var elementTokens []hclwrite.Tokens
+	for _, element := range elements {
+		elementTokens = append(elementTokens, p.valueTokens(key, element))
+	}
+	sort.SliceStable(elementTokens, func(i, j int) bool {
+		return string(elementTokens[i].Bytes()) < string(elementTokens[j].Bytes())
+	})
+	return hclwrite.TokensForTuple(elementTokens)
+}
+
+func (p *hcl2Printer) valueTokens(key string, value interface{}) hclwrite.Tokens {
+	switch v := value.(type) {
+	case nil:
+		return hclwrite.TokensForValue(cty.NullVal(cty.DynamicPseudoType))
+	case bool:
+		return hclwrite.TokensForValue(cty.BoolVal(v))
+	case json.Number:
+		return hclwrite.Tokens{{Type: hclsyntax.TokenNumberLit, Bytes: []byte(v.String())}}
+	case string:
+		return p.stringTokens(key, v)
+	case []interface{}:
+		var elements []hclwrite.Tokens
+		for _, element := range v {
+			elements = append(elements, p.valueTokens(key, element))
+		}
+		return hclwrite.TokensForTuple(elements)
+	case map[string]interface{}:
+		var attributes []hclwrite.ObjectAttrTokens
+		for _, k := range sortedKeys(v) {
+			attributes = append(attributes, hclwrite.ObjectAttrTokens{
+				Name:  objectKeyTokens(k),
+				Value: p.valueTokens(k, v[k]),
+			})
+		}
+		return hclwrite.TokensForObject(attributes)
+	}
+	return hclwrite.TokensForValue(cty.StringVal(fmt.Sprint(value)))
+}
+
+func (p *hcl2Printer) stringTokens(key string, s string) hclwrite.Tokens {
+	if match := heredoc.FindStringSubmatch(s); match != nil && match[1] == match[3] {
+		if document, ok := jsonDocument(match[2]); ok {
+			return hclwrite.TokensForFunctionCall("jsonencode", p.valueTokens(key, document))
+		}
+		return hclwrite.Tokens{
+			{Type: hclsyntax.TokenOHeredoc, Bytes: []byte("<<" + match[1] + "\n")},
+			{Type: hclsyntax.TokenStringLit, Bytes: []byte(match[2] + "\n")},
+			{Type: hclsyntax.TokenCHeredoc, Bytes: []byte(match[1])},
+		}
+	}
+	if strings.Contains(strings.ToLower(key), "policy") {
+		if document, ok := jsonDocument(s); ok {
+			return hclwrite.TokensForFunctionCall("jsonencode", p.valueTokens(key, document))
+		}
+	}
+	if match := singleInterpolation.FindStringSubmatch(s); match != nil {
+		if traversal, diags := hclsyntax.ParseTraversalAbs([]byte(match[1]), "", hcl.InitialPos); !diags.HasErrors() {
+			return hclwrite.TokensForTraversal(traversal)
+		}
+	}
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenOQuote, Bytes: []byte(`"`)},
+		{Type: hclsyntax.TokenQuotedLit, Bytes: templateLiteral(s)},
+		{Type: hclsyntax.TokenCQuote, Bytes: []byte(`"`)},
+	}
+}
+
+// jsonDocument decodes JSON objects and lists, e.g. policies
+func jsonDocument(s string) (interface{}, bool) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") && !strings.HasPrefix(s, "[") {
+		return nil, false
+	}
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil || decoder.More() {
+		return nil, false
+	}
+	return document, true
+}
+
+// templateLiteral escapes s for a quoted template, interpolation sequences are kept as they are
+func templateLiteral(s string) []byte {
+	var b bytes.Buffer
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"), strings.HasPrefix(s[i:], "%%{"):
+			b.WriteString(s[i : i+3])
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.Index(s[i:], "}")
+			if end < 0 {
+				b.WriteString("$${")
+				i += 2
+				continue
+			}
+			b.WriteString(s[i : i+end+1])
+			i += end + 1
+		case strings.HasPrefix(s[i:], "%{"):
+			b.WriteString("%%{")
+			i += 2
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			switch r {
+			case '\\':
+				b.WriteString(`\\`)
+			case '"':
+				b.WriteString(`\"`)
+			case '\n':
+				b.WriteString(`\n`)
+			case '\r':
+				b.WriteString(`\r`)
+			case '\t':
+				b.WriteString(`\t`)
+			default:
+				b.WriteString(s[i : i+size])
+			}
+			i += size
+		}
+	}
+	return b.Bytes()
+}
+
+// referenceTokens prints references of meta-arguments like depends_on as expressions
+func referenceTokens(value interface{}) hclwrite.Tokens {
+	switch v := value.(type) {
+	case string:
+		reference := v
+		if match := singleInterpolation.FindStringSubmatch(v); match != nil {
+			reference = match[1]
+		}
+		if traversal, diags := hclsyntax.ParseTraversalAbs([]byte(reference), "", hcl.InitialPos); !diags.HasErrors() {
+			return hclwrite.TokensForTraversal(traversal)
+		}
+		return hclwrite.TokensForValue(cty.StringVal(v))
+	case []interface{}:
+		var elements []hclwrite.Tokens
+		for _, element := range v {
+			elements = append(elements, referenceTokens(element))
+		}
+		return hclwrite.TokensForTuple(elements)
+	}
+	return hclwrite.TokensForValue(cty.StringVal(fmt.Sprint(value)))
+}
+
+func objectKeyTokens(k string) hclwrite.Tokens {
+	if hclsyntax.ValidIdentifier(k) && !strings.HasPrefix(k, "--") {
+		return hclwrite.TokensForIdentifier(k)
+	}
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenOQuote, Bytes: []byte(`"`)},
+		{Type: hclsyntax.TokenQuotedLit, Bytes: templateLiteral(k)},
+		{Type: hclsyntax.TokenCQuote, Bytes: []byte(`"`)},
+	}
+}
+
+func collectionElementType(t cty.Type) (cty.Type, bool) {
+	if t.IsListType() || t.IsSetType() {
+		return t.ElementType(), true
+	}
+	return cty.NilType, false
+}
+
+func isListOfMaps(value interface{}) bool {
+	list, ok := value.([]interface{})
+	if !ok || len(list) == 0 {
+		return false
+	}
+	for _, element := range list {
+		if _, ok := element.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// bodies returns block bodies of a value, a single object or a list of objects
+func bodies(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		var result []map[string]interface{}
+		for _, element := range v {
+			if body, ok := element.(map[string]interface{}); ok {
+				result = append(result, body)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func sortedKeys(value interface{}) []string {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
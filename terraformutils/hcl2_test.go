@@ -0,0 +1,241 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files of hcl2 output")
+
+func testHcl2Schema() *providers.GetSchemaResponse {
+	ruleType := cty.Object(map[string]cty.Type{
+		"cidr_blocks": cty.List(cty.String),
+		"from_port":   cty.Number,
+		"protocol":    cty.String,
+	})
+	return &providers.GetSchemaResponse{
+		Provider: providers.Schema{Block: &configschema.Block{
+			Attributes: map[string]*configschema.Attribute{
+				"region": {Type: cty.String, Optional: true},
+			},
+			BlockTypes: map[string]*configschema.NestedBlock{
+				"assume_role": {Nesting: configschema.NestingList, Block: configschema.Block{
+					Attributes: map[string]*configschema.Attribute{
+						"role_arn": {Type: cty.String, Optional: true},
+					},
+				}},
+			},
+		}},
+		ResourceTypes: map[string]providers.Schema{
+			"aws_security_group": {Block: &configschema.Block{
+				Attributes: map[string]*configschema.Attribute{
+					"name":    {Type: cty.String, Optional: true},
+					"vpc_id":  {Type: cty.String, Optional: true},
+					"tags":    {Type: cty.Map(cty.String), Optional: true},
+					"ingress": {Type: cty.Set(ruleType), Optional: true},
+				},
+			}},
+			"aws_glue_job": {Block: &configschema.Block{
+				Attributes: map[string]*configschema.Attribute{
+					"name":              {Type: cty.String, Required: true},
+					"default_arguments": {Type: cty.Map(cty.String), Optional: true},
+					"max_capacity":      {Type: cty.Number, Optional: true},
+				},
+				BlockTypes: map[string]*configschema.NestedBlock{
+					"command": {Nesting: configschema.NestingList, Block: configschema.Block{
+						Attributes: map[string]*configschema.Attribute{
+							"script_location": {Type: cty.String, Required: true},
+						},
+					}},
+					"notification_property": {Nesting: configschema.NestingList, Block: configschema.Block{
+						Attributes: map[string]*configschema.Attribute{
+							"notify_delay_after": {Type: cty.Number, Optional: true},
+						},
+					}},
+				},
+			}},
+			"aws_iam_role": {Block: &configschema.Block{
+				Attributes: map[string]*configschema.Attribute{
+					"name":               {Type: cty.String, Required: true},
+					"assume_role_policy": {Type: cty.String, Required: true},
+					"description":        {Type: cty.String, Optional: true},
+				},
+			}},
+			"aws_iam_role_policy": {Block: &configschema.Block{
+				Attributes: map[string]*configschema.Attribute{
+					"role":   {Type: cty.String, Required: true},
+					"policy": {Type: cty.String, Required: true},
+				},
+			}},
+		},
+	}
+}
+
+func TestHcl2Golden(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []Resource
+	}{
+		{
+			name: "blocks_and_maps",
+			resources: []Resource{
+				testResource("sg-1", "web", "aws_security_group", nil, map[string]interface{}{
+					"name":   "web",
+					"vpc_id": "${aws_vpc.tfer--main.id}",
+					// a map attribute, not a block, even though it is a map
+					"tags": map[string]interface{}{"Name": "web", "kubernetes.io/cluster/main": "owned"},
+					// an attribute of objects, written as blocks
+					"ingress": []interface{}{
+						map[string]interface{}{"cidr_blocks": []interface{}{"10.0.0.0/8"}, "from_port": 443, "protocol": "tcp"},
+						map[string]interface{}{"cidr_blocks": []interface{}{"0.0.0.0/0"}, "from_port": 80, "protocol": "tcp"},
+					},
+					"depends_on": []string{"aws_vpc.tfer--main"},
+				}),
+				testResource("etl", "etl", "aws_glue_job", nil, map[string]interface{}{
+					"name":         "etl",
+					"max_capacity": 2.5,
+					"default_arguments": map[string]interface{}{
+						"--job-language": "python",
+						"--TempDir":      "s3://bucket/${path}",
+					},
+					"command":               []interface{}{map[string]interface{}{"script_location": "s3://bucket/etl.py"}},
+					"notification_property": map[string]interface{}{"notify_delay_after": 5},
+				}),
+			},
+		},
+		{
+			name: "policies",
+			resources: []Resource{
+				testResource("app", "app", "aws_iam_role", nil, map[string]interface{}{
+					"name": "app",
+					"assume_role_policy": `<<POLICY
+{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"ec2.amazonaws.com"},"Action":"sts:AssumeRole"}]}
+POLICY`,
+					"description": "line \"one\"\nline two",
+				}),
+				testResource("ci", "ci", "aws_iam_role", nil, map[string]interface{}{
+					"name":               "ci",
+					"assume_role_policy": "${aws_iam_role.tfer--app.assume_role_policy}",
+					"description": `<<EOT
+Used by "ci" jobs
+  of ${var.team}
+EOT`,
+				}),
+				testResource("app:s3", "app_s3", "aws_iam_role_policy", nil, map[string]interface{}{
+					"role":   "${aws_iam_role.tfer--app.name}",
+					"policy": `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/$${aws:username}/*","Condition":{"StringEquals":{"aws:SourceArn":"${aws_iam_role.tfer--app.arn}"}}}]}`,
+				}),
+			},
+		},
+	}
+	for _, test := range tests {
+		data, err := HclPrintResource(test.resources, map[string]interface{}{}, "hcl2", true, testHcl2Schema())
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		checkHcl2Golden(t, test.name, data)
+	}
+}
+
+func TestHcl2GoldenProvider(t *testing.T) {
+	providerData := map[string]interface{}{
+		"provider": map[string]interface{}{
+			"aws": map[string]interface{}{
+				"region":      "eu-west-1",
+				"assume_role": []interface{}{map[string]interface{}{"role_arn": "arn:aws:iam::123456789012:role/terraformer"}},
+			},
+		},
+		"terraform": map[string]interface{}{
+			"required_providers": []map[string]interface{}{{
+				"aws": map[string]interface{}{"source": "hashicorp/aws", "version": "~> 4.0"},
+			}},
+		},
+	}
+	data, err := PrintWithSchema(providerData, map[string]struct{}{}, "hcl2", true, testHcl2Schema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHcl2Golden(t, "provider", data)
+}
+
+func checkHcl2Golden(t *testing.T, name string, data []byte) {
+	if _, diags := hclsyntax.ParseConfig(data, name+".tf", hcl.InitialPos); diags.HasErrors() {
+		t.Errorf("%s: invalid HCL2 %v\n%s", name, diags, data)
+	}
+	golden := filepath.Join("test_data", "hcl2", name+".tf")
+	if *updateGolden {
+		if err := ioutil.WriteFile(golden, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != string(data) {
+		t.Errorf("%s: output differs from %s, expected:\n%s\ngot:\n%s", name, golden, expected, data)
+	}
+}
+
+func TestHcl2Variables(t *testing.T) {
+	variables := map[string]map[string]map[string]interface{}{
+		"data": {"terraform_remote_state": {"vpc": map[string]interface{}{
+			"backend": "local",
+			"config":  map[string]interface{}{"path": "../vpc/terraform.tfstate"},
+		}}},
+	}
+	data, err := Print(variables, map[string]struct{}{"config": {}}, "hcl2", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `data "terraform_remote_state" "vpc" {
+  backend = "local"
+  config = {
+    path = "../vpc/terraform.tfstate"
+  }
+}
+`
+	if string(data) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestTemplateLiteral(t *testing.T) {
+	tests := map[string]string{
+		`plain`:                    `plain`,
+		`quote " and \`:            `quote \" and \\`,
+		"new\nline":                `new\nline`,
+		`${aws_vpc.tfer--main.id}`: `${aws_vpc.tfer--main.id}`,
+		`$${aws:username}`:         `$${aws:username}`,
+		`unterminated ${`:          `unterminated $${`,
+		`%{if}`:                    `%%{if}`,
+	}
+	for s, expected := range tests {
+		if actual := string(templateLiteral(s)); actual != expected {
+			t.Errorf("templateLiteral(%q) = %q, expected %q", s, actual, expected)
+		}
+	}
+}
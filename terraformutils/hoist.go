@@ -0,0 +1,214 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultHoistAttributes are the attributes whose repeated values are hoisted by default
+var DefaultHoistAttributes = []string{"region", "project", "tags"}
+
+// HoistedValue is a literal repeated across resources, scalars are declared as variables, maps and lists as locals
+type HoistedValue struct {
+	Name  string
+	Value interface{}
+	Count int
+	Local bool
+}
+
+// Reference returns the expression replacing the value in resources
+func (v HoistedValue) Reference() string {
+	if v.Local {
+		return "${local." + v.Name + "}"
+	}
+	return "${var." + v.Name + "}"
+}
+
+// Hoister replaces values of allowed attributes used by at least minCount resources with references to
+// variables and locals
+type Hoister struct {
+	minCount   int
+	attributes map[string]struct{}
+}
+
+type hoistCandidate struct {
+	attribute string
+	key       string
+	value     interface{}
+	count     int
+}
+
+// NewHoister returns a Hoister of the attributes, nil when minCount is 0 and values are kept
+func NewHoister(minCount int, attributes []string) (*Hoister, error) {
+	if minCount == 0 {
+		return nil, nil
+	}
+	if minCount < 2 {
+		return nil, fmt.Errorf("hoisted values must be used by at least 2 resources, got %d", minCount)
+	}
+	if len(attributes) == 0 {
+		return nil, fmt.Errorf("no attributes to hoist values of")
+	}
+	h := &Hoister{minCount: minCount, attributes: map[string]struct{}{}}
+	for _, attribute := range attributes {
+		h.attributes[attribute] = struct{}{}
+	}
+	return h, nil
+}
+
+// Hoist rewrites the items of resources and returns the hoisted values sorted by name
+func (h *Hoister) Hoist(resources []Resource) []HoistedValue {
+	candidates := map[string]*hoistCandidate{}
+	for _, r := range resources {
+		// a value counts once per resource
+		seen := map[string]struct{}{}
+		h.walk(r.Item, func(attribute, key string, value interface{}) interface{} {
+			if _, exist := seen[key]; exist {
+				return value
+			}
+			seen[key] = struct{}{}
+			if candidates[key] == nil {
+				candidates[key] = &hoistCandidate{attribute: attribute, key: key, value: value}
+			}
+			candidates[key].count++
+			return value
+		})
+	}
+
+	var repeated []*hoistCandidate
+	for _, candidate := range candidates {
+		if candidate.count >= h.minCount {
+			repeated = append(repeated, candidate)
+		}
+	}
+	// the most used value of an attribute takes its name, others are numbered
+	sort.Slice(repeated, func(i, j int) bool {
+		if repeated[i].attribute != repeated[j].attribute {
+			return repeated[i].attribute < repeated[j].attribute
+		}
+		if repeated[i].count != repeated[j].count {
+			return repeated[i].count > repeated[j].count
+		}
+		return repeated[i].key < repeated[j].key
+	})
+	hoisted := map[string]HoistedValue{}
+	var values []HoistedValue
+	numbers := map[string]int{}
+	for _, candidate := range repeated {
+		name := candidate.attribute
+		numbers[name]++
+		if numbers[name] > 1 {
+			name += "_" + strconv.Itoa(numbers[name])
+		}
+		value := HoistedValue{Name: name, Value: candidate.value, Count: candidate.count, Local: !isScalar(candidate.value)}
+		hoisted[candidate.key] = value
+		values = append(values, value)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	for i := range resources {
+		h.walk(resources[i].Item, func(attribute, key string, value interface{}) interface{} {
+			if v, exist := hoisted[key]; exist {
+				return v.Reference()
+			}
+			return value
+		})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Name < values[j].Name
+	})
+	return values
+}
+
+// walk calls visit with values of allowed attributes, keyed by attribute and value, and sets the returned
+// value in their place. Values of other attributes are walked into.
+func (h *Hoister) walk(item map[string]interface{}, visit func(attribute, key string, value interface{}) interface{}) {
+	for k, v := range item {
+		item[k] = h.walkValue(k, v, visit)
+	}
+}
+
+func (h *Hoister) walkValue(attribute string, value interface{}, visit func(attribute, key string, value interface{}) interface{}) interface{} {
+	if _, allowed := h.attributes[attribute]; allowed && !isEmptyValue(value) && !containsReference(value) {
+		content, err := json.Marshal(value)
+		if err == nil {
+			return visit(attribute, attribute+"="+string(content), value)
+		}
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = h.walkValue(k, item, visit)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = h.walkValue(attribute, item, visit)
+		}
+	}
+	return value
+}
+
+// containsReference is true when the value interpolates, e.g. a linked resource or a redacted value
+func containsReference(value interface{}) bool {
+	content, err := json.Marshal(value)
+	return err != nil || strings.Contains(string(content), "${")
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+// HoistedVariablesData returns variable declarations of hoisted scalars for variables.tf
+func HoistedVariablesData(values []HoistedValue) map[string]map[string]interface{} {
+	data := map[string]map[string]interface{}{}
+	for _, value := range values {
+		if !value.Local {
+			data[value.Name] = map[string]interface{}{
+				"default":     value.Value,
+				"description": fmt.Sprintf("used by %d resources", value.Count),
+			}
+		}
+	}
+	return data
+}
+
+// PrintHoistedLocals prints hoisted maps and lists for locals.tf, nil when there are none. The legacy HCL printer
+// turns a single map into a labeled block, hcl is printed like hcl2 which Terraform 0.12+ reads the same.
+func PrintHoistedLocals(values []HoistedValue, output string, sort bool) ([]byte, error) {
+	locals := map[string]interface{}{}
+	for _, value := range values {
+		if value.Local {
+			locals[value.Name] = value.Value
+		}
+	}
+	if len(locals) == 0 {
+		return nil, nil
+	}
+	if output == "hcl" {
+		output = "hcl2"
+	}
+	return Print(map[string]interface{}{"locals": locals}, nil, output, sort)
+}
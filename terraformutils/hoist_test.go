@@ -0,0 +1,89 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHoist(t *testing.T) {
+	tags := func() map[string]interface{} {
+		return map[string]interface{}{"env": "prod", "team": "network"}
+	}
+	resources := []Resource{
+		testResource("a", "a", "aws_subnet", nil, map[string]interface{}{"region": "eu-west-1", "tags": tags(), "cidr_block": "10.0.0.0/24"}),
+		testResource("b", "b", "aws_subnet", nil, map[string]interface{}{"region": "eu-west-1", "tags": tags(), "cidr_block": "10.0.0.0/24"}),
+		testResource("c", "c", "aws_subnet", nil, map[string]interface{}{"region": "us-east-1", "tags": tags()}),
+		testResource("d", "d", "aws_subnet", nil, map[string]interface{}{"region": "us-east-1", "tags": map[string]interface{}{"env": "dev"}}),
+		testResource("e", "e", "aws_subnet", nil, map[string]interface{}{"region": "eu-west-1", "vpc_id": "${aws_vpc.tfer--main.id}",
+			"ebs": []interface{}{map[string]interface{}{"tags": tags()}}}),
+		testResource("f", "f", "aws_subnet", nil, map[string]interface{}{"region": "${var.region}", "tags": map[string]interface{}{"ref": "${aws_vpc.tfer--main.id}"}}),
+	}
+
+	hoister, err := NewHoister(2, DefaultHoistAttributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := hoister.Hoist(resources)
+
+	expected := []HoistedValue{
+		{Name: "region", Value: "eu-west-1", Count: 3},
+		{Name: "region_2", Value: "us-east-1", Count: 2},
+		{Name: "tags", Value: tags(), Count: 4, Local: true},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+	if resources[0].Item["region"] != "${var.region}" || resources[2].Item["region"] != "${var.region_2}" {
+		t.Errorf("regions should reference variables, got %v and %v", resources[0].Item["region"], resources[2].Item["region"])
+	}
+	if resources[1].Item["tags"] != "${local.tags}" || resources[4].Item["ebs"].([]interface{})[0].(map[string]interface{})["tags"] != "${local.tags}" {
+		t.Errorf("tags should reference locals, got %v", resources[1].Item["tags"])
+	}
+	if resources[0].Item["cidr_block"] != "10.0.0.0/24" {
+		t.Errorf("attributes which aren't allowed are kept")
+	}
+	if !reflect.DeepEqual(resources[3].Item["tags"], map[string]interface{}{"env": "dev"}) {
+		t.Errorf("values used less than the min count are kept")
+	}
+	if resources[5].Item["region"] != "${var.region}" {
+		t.Errorf("references are kept")
+	}
+
+	hcl, err := PrintHoistedLocals(values, "hcl", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(hcl), "tags = {") {
+		t.Errorf("maps should be printed as objects, got %s", hcl)
+	}
+	if variables := HoistedVariablesData(values); len(variables) != 2 || variables["region"]["default"] != "eu-west-1" {
+		t.Errorf("expected variables of regions, got %v", variables)
+	}
+}
+
+func TestNewHoister(t *testing.T) {
+	if hoister, err := NewHoister(0, DefaultHoistAttributes); hoister != nil || err != nil {
+		t.Errorf("values are kept without min count")
+	}
+	if _, err := NewHoister(1, DefaultHoistAttributes); err == nil {
+		t.Errorf("expected an error for a min count of 1")
+	}
+	if _, err := NewHoister(3, nil); err == nil {
+		t.Errorf("expected an error without attributes")
+	}
+}
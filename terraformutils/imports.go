@@ -0,0 +1,85 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Escape HCL template sequences, so IDs are written as plain literals
+var templateEscaper = strings.NewReplacer("${", "$${", "%{", "%%{")
+
+var hclStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// PrintImportBlocks prints Terraform 1.5+ import blocks for resources, one per resource. kept are resources of a
+// previous run still declared in the code, e.g. vanished resources under --merge, their blocks are printed too.
+func PrintImportBlocks(resources []Resource, kept []ExistingResource, format string, sortBlocks bool) ([]byte, error) {
+	type importBlock struct {
+		To string `json:"to"`
+		ID string `json:"id"`
+	}
+	blocks := []importBlock{}
+	seen := map[string]struct{}{}
+	for _, r := range resources {
+		if r.InstanceState == nil || r.InstanceState.ID == "" {
+			continue
+		}
+		to := r.InstanceInfo.Type + "." + r.ResourceName
+		if _, exist := seen[to]; exist {
+			continue
+		}
+		seen[to] = struct{}{}
+		blocks = append(blocks, importBlock{
+			To: to,
+			ID: templateEscaper.Replace(r.InstanceState.ID),
+		})
+	}
+	for _, e := range kept {
+		if _, exist := seen[e.Address()]; exist || e.ID == "" {
+			continue
+		}
+		seen[e.Address()] = struct{}{}
+		blocks = append(blocks, importBlock{
+			To: e.Address(),
+			ID: templateEscaper.Replace(e.ID),
+		})
+	}
+	if sortBlocks {
+		sort.Slice(blocks, func(i, j int) bool {
+			return blocks[i].To < blocks[j].To
+		})
+	}
+
+	switch format {
+	case "hcl", "hcl2":
+		var b bytes.Buffer
+		for i, block := range blocks {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "import {\n  to = %s\n  id = \"%s\"\n}\n", block.To, hclStringEscaper.Replace(block.ID))
+		}
+		return b.Bytes(), nil
+	case "json":
+		return jsonPrint(map[string]interface{}{
+			"import": blocks,
+		})
+	}
+	return []byte{}, errors.New("error: unknown output format")
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPrintImportBlocks(t *testing.T) {
+	resources := []Resource{
+		NewSimpleResource("vpc-2", "vpc-2", "aws_vpc", "aws", []string{}),
+		NewSimpleResource("vpc-1", "vpc-1", "aws_vpc", "aws", []string{}),
+		NewSimpleResource("", "empty", "aws_vpc", "aws", []string{}),
+		NewSimpleResource(`${a}"b`, "odd", "aws_iam_policy", "aws", []string{}),
+	}
+	data, err := PrintImportBlocks(resources, nil, "hcl", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `import {
+  to = aws_iam_policy.tfer--odd
+  id = "$${a}\"b"
+}
+
+import {
+  to = aws_vpc.tfer--vpc-1
+  id = "vpc-1"
+}
+
+import {
+  to = aws_vpc.tfer--vpc-2
+  id = "vpc-2"
+}
+`
+	if string(data) != expected {
+		t.Errorf("failed to print import blocks, got:\n%s", string(data))
+	}
+}
+
+func TestPrintImportBlocksJSON(t *testing.T) {
+	resources := []Resource{
+		NewSimpleResource("vpc-1", "vpc-1", "aws_vpc", "aws", []string{}),
+	}
+	data, err := PrintImportBlocks(resources, nil, "json", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed := map[string][]map[string]string{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed["import"]) != 1 || parsed["import"][0]["to"] != "aws_vpc.tfer--vpc-1" || parsed["import"][0]["id"] != "vpc-1" {
+		t.Errorf("failed to print import blocks, got %s", string(data))
+	}
+}
+
+func TestPrintImportBlocksKept(t *testing.T) {
+	resources := []Resource{
+		NewSimpleResource("vpc-1", "vpc-1", "aws_vpc", "aws", []string{}),
+	}
+	kept := []ExistingResource{
+		{Type: "aws_vpc", Name: "tfer--vpc-1", ID: "vpc-1"},
+		{Type: "aws_vpc", Name: "tfer--old", ID: "vpc-0"},
+	}
+	data, err := PrintImportBlocks(resources, kept, "hcl", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `import {
+  to = aws_vpc.tfer--old
+  id = "vpc-0"
+}
+
+import {
+  to = aws_vpc.tfer--vpc-1
+  id = "vpc-1"
+}
+`
+	if string(data) != expected {
+		t.Errorf("expected the import block of the kept resource, got:\n%s", string(data))
+	}
+}
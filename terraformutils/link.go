@@ -0,0 +1,179 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// linkAttributes identify resources, a value matching several attributes of a resource is linked to the first
+var linkAttributes = []string{"id", "arn", "self_link", "name"}
+
+// linkIgnoredKeys are never replaced by references, e.g. a name equal to the name of another resource or a
+// tag value are no references
+var linkIgnoredKeys = map[string]struct{}{
+	"id":          {},
+	"arn":         {},
+	"self_link":   {},
+	"name":        {},
+	"description": {},
+	"tags":        {},
+	"tags_all":    {},
+	"labels":      {},
+	"depends_on":  {},
+}
+
+var remoteStateReference = regexp.MustCompile(`data\.terraform_remote_state\.([A-Za-z0-9_\-]+)\.`)
+
+type linkTarget struct {
+	service   string
+	resource  *Resource
+	attribute string
+}
+
+// LinkResources replaces values equal to the ID, ARN, self_link or name of another imported resource with a
+// reference to it. Resources in the same directory are referenced directly, others through outputs read by
+// terraform_remote_state data sources named after their service. Values of several resources are ambiguous
+// and kept, as are numbers and booleans.
+func LinkResources(importResources map[string][]Resource, isServicePath bool) map[string][]Resource {
+	var services []string
+	for serviceName := range importResources {
+		services = append(services, serviceName)
+	}
+	sort.Strings(services)
+
+	index := map[string]*linkTarget{}
+	ambiguous := map[string]struct{}{}
+	for _, serviceName := range services {
+		for i := range importResources[serviceName] {
+			r := &importResources[serviceName][i]
+			if r.InstanceState == nil {
+				continue
+			}
+			for _, attribute := range linkAttributes {
+				value := r.InstanceState.Attributes[attribute]
+				if attribute == "id" {
+					value = r.InstanceState.ID
+				}
+				if !isLinkable(value) {
+					continue
+				}
+				target, exist := index[value]
+				switch {
+				case !exist:
+					index[value] = &linkTarget{service: serviceName, resource: r, attribute: attribute}
+				case target.resource != r:
+					ambiguous[value] = struct{}{}
+				}
+			}
+		}
+	}
+	for value := range ambiguous {
+		delete(index, value)
+	}
+
+	for _, serviceName := range services {
+		for i := range importResources[serviceName] {
+			r := &importResources[serviceName][i]
+			link := func(value string) string {
+				target, exist := index[value]
+				if !exist || target.resource == r {
+					return value
+				}
+				return linkReference(target, serviceName, isServicePath)
+			}
+			for k, v := range r.Item {
+				if _, ignored := linkIgnoredKeys[k]; !ignored {
+					r.Item[k] = mapStrings(v, link)
+				}
+			}
+		}
+	}
+	return importResources
+}
+
+func linkReference(target *linkTarget, serviceName string, isServicePath bool) string {
+	r := target.resource
+	if !isServicePath || target.service == serviceName {
+		return "${" + r.InstanceInfo.Type + "." + r.ResourceName + "." + target.attribute + "}"
+	}
+	exist := false
+	for _, attribute := range r.LinkedAttributes {
+		exist = exist || attribute == target.attribute
+	}
+	if !exist {
+		r.LinkedAttributes = append(r.LinkedAttributes, target.attribute)
+	}
+	return "${data.terraform_remote_state." + target.service + ".outputs." + r.InstanceInfo.Type + "_" + r.ResourceName + "_" + target.attribute + "}"
+}
+
+func isLinkable(value string) bool {
+	if len(value) < 3 || value == "true" || value == "false" || strings.Contains(value, "${") {
+		return false
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err != nil
+}
+
+// mapStrings replaces strings of value with fn, except under ignored keys of nested maps
+func mapStrings(value interface{}, fn func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		for k, item := range v {
+			if _, ignored := linkIgnoredKeys[k]; !ignored {
+				v[k] = mapStrings(item, fn)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = mapStrings(item, fn)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			mapStrings(item, fn)
+		}
+	case []string:
+		for i, item := range v {
+			v[i] = fn(item)
+		}
+	}
+	return value
+}
+
+// ReferencedRemoteStates returns names of terraform_remote_state data sources referenced by resources
+func ReferencedRemoteStates(resources []Resource) []string {
+	names := map[string]struct{}{}
+	for _, r := range resources {
+		for _, v := range r.Item {
+			mapStrings(v, func(s string) string {
+				for _, match := range remoteStateReference.FindAllStringSubmatch(s, -1) {
+					names[match[1]] = struct{}{}
+				}
+				return s
+			})
+		}
+	}
+	var referenced []string
+	for name := range names {
+		referenced = append(referenced, name)
+	}
+	sort.Strings(referenced)
+	return referenced
+}
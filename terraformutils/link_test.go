@@ -0,0 +1,122 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testLinkResources() map[string][]Resource {
+	return map[string][]Resource{
+		"vpc": {
+			testResource("vpc-0abc", "main", "aws_vpc", map[string]string{"arn": "arn:aws:ec2:eu-west-1:1:vpc/vpc-0abc"},
+				map[string]interface{}{"cidr_block": "10.0.0.0/16"}),
+		},
+		"subnet": {
+			testResource("subnet-1", "a", "aws_subnet", map[string]string{}, map[string]interface{}{
+				"vpc_id": "vpc-0abc",
+				"tags":   map[string]interface{}{"Vpc": "vpc-0abc"},
+			}),
+		},
+		"iam": {
+			testResource("app", "app", "aws_iam_role", map[string]string{"name": "app", "arn": "arn:aws:iam::1:role/app"},
+				map[string]interface{}{"name": "app"}),
+			testResource("app:s3", "app_s3", "aws_iam_role_policy", map[string]string{"name": "s3"},
+				map[string]interface{}{"role": "app", "name": "s3"}),
+			testResource("ci-profile", "ci", "aws_iam_instance_profile", map[string]string{"name": "ci-runner", "role": "app"},
+				map[string]interface{}{"name": "ci-runner", "role": "app", "roles": []interface{}{"app"}}),
+			testResource("AROACI", "ci_role", "aws_iam_role", map[string]string{"name": "ci-runner"},
+				map[string]interface{}{"name": "ci-runner", "max_session_duration": "3600"}),
+			testResource("AROADEPLOYER", "deployer", "aws_iam_role", map[string]string{"name": "deployer"},
+				map[string]interface{}{"name": "deployer"}),
+			// the policy is named like the role, its name is no reference
+			testResource("deployer:deployer", "deployer_deployer", "aws_iam_role_policy", map[string]string{},
+				map[string]interface{}{"role": "deployer", "name": "deployer"}),
+		},
+		"ec2_instance": {
+			testResource("i-1", "web", "aws_instance", map[string]string{}, map[string]interface{}{
+				"subnet_id":            "subnet-1",
+				"iam_instance_profile": "ci-runner",
+				"tags":                 map[string]interface{}{"Role": "deployer"},
+				"ebs_block_device":     []interface{}{map[string]interface{}{"volume_size": "3600"}},
+			}),
+		},
+	}
+}
+
+func TestLinkResources(t *testing.T) {
+	linked := LinkResources(testLinkResources(), true)
+
+	expected := map[string]map[string]interface{}{
+		"aws_subnet.tfer--a": {
+			"vpc_id": "${data.terraform_remote_state.vpc.outputs.aws_vpc_tfer--main_id}",
+			// tag values are no references
+			"tags": map[string]interface{}{"Vpc": "vpc-0abc"},
+		},
+		// same directory, referenced directly
+		"aws_iam_role_policy.tfer--app_s3": {"role": "${aws_iam_role.tfer--app.id}", "name": "s3"},
+		"aws_iam_instance_profile.tfer--ci": {
+			"name":  "ci-runner",
+			"role":  "${aws_iam_role.tfer--app.id}",
+			"roles": []interface{}{"${aws_iam_role.tfer--app.id}"},
+		},
+		// linked by the name of the role
+		"aws_iam_role_policy.tfer--deployer_deployer": {"role": "${aws_iam_role.tfer--deployer.name}", "name": "deployer"},
+		"aws_instance.tfer--web": {
+			"subnet_id": "${data.terraform_remote_state.subnet.outputs.aws_subnet_tfer--a_id}",
+			// ci-runner is the name of both the instance profile and a role
+			"iam_instance_profile": "ci-runner",
+			"tags":                 map[string]interface{}{"Role": "deployer"},
+			"ebs_block_device":     []interface{}{map[string]interface{}{"volume_size": "3600"}},
+		},
+	}
+	checked := 0
+	for _, resources := range linked {
+		for _, r := range resources {
+			item, exist := expected[r.InstanceInfo.Id]
+			if !exist {
+				continue
+			}
+			checked++
+			if !reflect.DeepEqual(r.Item, item) {
+				t.Errorf("%s: expected %v, got %v", r.InstanceInfo.Id, item, r.Item)
+			}
+		}
+	}
+	if checked != len(expected) {
+		t.Errorf("expected %d resources, found %d", len(expected), checked)
+	}
+	if attributes := linked["vpc"][0].LinkedAttributes; !reflect.DeepEqual(attributes, []string{"id"}) {
+		t.Errorf("vpc id should be exported for other services, got %v", attributes)
+	}
+	if attributes := linked["iam"][0].LinkedAttributes; len(attributes) != 0 {
+		t.Errorf("role is only referenced in its directory, got %v", attributes)
+	}
+	remoteStates := ReferencedRemoteStates(linked["ec2_instance"])
+	if !reflect.DeepEqual(remoteStates, []string{"subnet"}) {
+		t.Errorf("unexpected remote states %v", remoteStates)
+	}
+}
+
+func TestLinkResourcesOneDirectory(t *testing.T) {
+	linked := LinkResources(testLinkResources(), false)
+	if ref := linked["subnet"][0].Item["vpc_id"]; ref != "${aws_vpc.tfer--main.id}" {
+		t.Errorf("expected a direct reference, got %v", ref)
+	}
+	if attributes := linked["vpc"][0].LinkedAttributes; len(attributes) != 0 {
+		t.Errorf("no outputs are needed in one directory, got %v", attributes)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/terraform/terraform"
+)
+
+// ExistingResource is a resource generated by a previous run, found in its state or import blocks
+type ExistingResource struct {
+	Type string
+	Name string
+	ID   string
+}
+
+func (e ExistingResource) Address() string {
+	return e.Type + "." + e.Name
+}
+
+// MergeResult matches resources found now with resources generated by a previous run
+type MergeResult struct {
+	// Resources found now, named as in the previous run when they already existed
+	Resources []Resource
+	// Vanished are resources of the previous run which were not found anymore
+	Vanished     []ExistingResource
+	newResources map[string]struct{}
+}
+
+func (m *MergeResult) IsNew(r Resource) bool {
+	_, exist := m.newResources[mergeKey(r.InstanceInfo.Type, r.InstanceState.ID)]
+	return exist
+}
+
+func mergeKey(resourceType, id string) string {
+	return resourceType + "|" + id
+}
+
+// MergeResources matches resources by type and ID with resources of a previous run. Existing resources
+// keep their previous names, new resources get names not declared yet.
+func MergeResources(resources []Resource, previous []ExistingResource, declared map[string]struct{}) *MergeResult {
+	result := &MergeResult{
+		Resources:    make([]Resource, 0, len(resources)),
+		newResources: map[string]struct{}{},
+	}
+	previousByKey := map[string]ExistingResource{}
+	usedNames := map[string]struct{}{}
+	for address := range declared {
+		usedNames[address] = struct{}{}
+	}
+	for _, e := range previous {
+		previousByKey[mergeKey(e.Type, e.ID)] = e
+		usedNames[e.Address()] = struct{}{}
+	}
+
+	found := map[string]struct{}{}
+	for _, r := range resources {
+		key := mergeKey(r.InstanceInfo.Type, r.InstanceState.ID)
+		if e, exist := previousByKey[key]; exist {
+			r.SetResourceName(e.Name)
+			found[key] = struct{}{}
+		} else {
+			r.SetResourceName(uniqueResourceName(r.InstanceInfo.Type, r.ResourceName, usedNames))
+			result.newResources[key] = struct{}{}
+		}
+		usedNames[r.InstanceInfo.Type+"."+r.ResourceName] = struct{}{}
+		result.Resources = append(result.Resources, r)
+	}
+
+	for _, e := range previous {
+		if _, exist := found[mergeKey(e.Type, e.ID)]; !exist {
+			result.Vanished = append(result.Vanished, e)
+		}
+	}
+	return result
+}
+
+func uniqueResourceName(resourceType, name string, usedNames map[string]struct{}) string {
+	if _, exist := usedNames[resourceType+"."+name]; !exist {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := name + "-" + strconv.Itoa(i)
+		if _, exist := usedNames[resourceType+"."+candidate]; !exist {
+			return candidate
+		}
+	}
+}
+
+// SetResourceName renames resource, the name must be already sanitized
+func (r *Resource) SetResourceName(name string) {
+	r.ResourceName = name
+	r.InstanceInfo = &terraform.InstanceInfo{
+		Id:   fmt.Sprintf("%s.%s", r.InstanceInfo.Type, name),
+		Type: r.InstanceInfo.Type,
+	}
+}
+
+// ExistingResources lists resources of a state with their IDs
+func (s *StateV4) ExistingResources() []ExistingResource {
+	var resources []ExistingResource
+	for _, r := range s.Resources {
+		if r.Mode != "managed" || len(r.Instances) == 0 {
+			continue
+		}
+		id := r.Instances[0].ID()
+		if id == "" {
+			continue
+		}
+		resources = append(resources, ExistingResource{Type: r.Type, Name: r.Name, ID: id})
+	}
+	return resources
+}
+
+// KeepPrevious continues lineage of the previous state and copies the resources of vanished it holds, their
+// code is left untouched by the merge. Resources found again are the ones of s, vanished resources only declared
+// by import blocks aren't in previous and keep their import blocks instead.
+func (s *StateV4) KeepPrevious(previous *StateV4, vanished []ExistingResource, providerSource string) {
+	if previous.Lineage != "" {
+		s.Lineage = previous.Lineage
+		s.Serial = previous.Serial + 1
+	}
+	keep := map[string]struct{}{}
+	for _, e := range vanished {
+		keep[e.Address()] = struct{}{}
+	}
+	for _, r := range previous.Resources {
+		if _, exist := keep[r.Type+"."+r.Name]; !exist {
+			continue
+		}
+		if r.Provider == "" {
+			r.Provider = ProviderAddress(providerSource)
+		}
+		s.Resources = append(s.Resources, r)
+	}
+	s.sortResources()
+}
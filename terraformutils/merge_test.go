@@ -0,0 +1,110 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeResources(t *testing.T) {
+	resources := []Resource{
+		NewSimpleResource("vpc-1", "vpc-1", "aws_vpc", "aws", []string{}),
+		NewSimpleResource("vpc-2", "vpc-1", "aws_vpc", "aws", []string{}),
+		NewSimpleResource("vpc-3", "vpc-3", "aws_vpc", "aws", []string{}),
+	}
+	previous := []ExistingResource{
+		{Type: "aws_vpc", Name: "main", ID: "vpc-1"},
+		{Type: "aws_vpc", Name: "tfer--vpc-1", ID: "vpc-4"},
+	}
+	declared := map[string]struct{}{"aws_vpc.tfer--vpc-3": {}}
+
+	merge := MergeResources(resources, previous, declared)
+
+	var names []string
+	for _, r := range merge.Resources {
+		names = append(names, r.ResourceName)
+	}
+	if expected := []string{"main", "tfer--vpc-1-2", "tfer--vpc-3-2"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected names %v, got %v", expected, names)
+	}
+	if merge.Resources[0].InstanceInfo.Id != "aws_vpc.main" {
+		t.Errorf("unexpected instance info %v", merge.Resources[0].InstanceInfo)
+	}
+	if merge.IsNew(merge.Resources[0]) || !merge.IsNew(merge.Resources[1]) || !merge.IsNew(merge.Resources[2]) {
+		t.Error("only resources not found in previous run should be new")
+	}
+	if expected := []ExistingResource{previous[1]}; !reflect.DeepEqual(merge.Vanished, expected) {
+		t.Errorf("expected vanished %v, got %v", expected, merge.Vanished)
+	}
+}
+
+func TestReadTfStateV3(t *testing.T) {
+	data := []byte(`{
+  "version": 3,
+  "serial": 2,
+  "lineage": "lineage",
+  "modules": [{
+    "path": ["root"],
+    "resources": {
+      "aws_vpc.tfer--vpc-1": {
+        "type": "aws_vpc",
+        "primary": {"id": "vpc-1", "attributes": {"cidr_block": "10.0.0.0/16"}}
+      }
+    }
+  }]
+}`)
+	state, err := ReadTfState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []ExistingResource{{Type: "aws_vpc", Name: "tfer--vpc-1", ID: "vpc-1"}}
+	if existing := state.ExistingResources(); !reflect.DeepEqual(existing, expected) {
+		t.Errorf("expected %v, got %v", expected, existing)
+	}
+	if state.Lineage != "lineage" || state.Serial != 2 {
+		t.Errorf("unexpected state header %+v", state)
+	}
+}
+
+func TestKeepPrevious(t *testing.T) {
+	resources := []Resource{
+		NewResource("vpc-1", "vpc-1", "aws_vpc", "aws", map[string]string{"cidr_block": "10.0.0.0/16"}, []string{}, map[string]interface{}{}),
+	}
+	data, err := PrintTfStateV4(append(resources,
+		NewResource("vpc-2", "vpc-2", "aws_vpc", "aws", map[string]string{}, []string{}, map[string]interface{}{}),
+	), "hashicorp/aws", testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := ReadTfState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merge := MergeResources(resources, previous.ExistingResources(), nil)
+	state, err := NewTfStateV4(merge.Resources, "hashicorp/aws", testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.KeepPrevious(previous, merge.Vanished, "hashicorp/aws")
+
+	if state.Lineage != previous.Lineage || state.Serial != previous.Serial+1 {
+		t.Errorf("lineage should be kept, got %s %d", state.Lineage, state.Serial)
+	}
+	if existing := state.ExistingResources(); !reflect.DeepEqual(existing, previous.ExistingResources()) {
+		t.Errorf("expected %v, got %v", previous.ExistingResources(), existing)
+	}
+}
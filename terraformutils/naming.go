@@ -0,0 +1,371 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// NamingLegacy keeps names escaped by TfSanitize, e.g. tfer--sg-0abc123
+	NamingLegacy = "legacy"
+	// NamingTags names resources after their Name tag, falling back to the provider given name
+	NamingTags = "tags"
+	// NamingTemplate names resources with a user defined template
+	NamingTemplate = "template"
+
+	defaultTagsTemplate = "{tag:Name|name}"
+)
+
+var templatePlaceholder = regexp.MustCompile(`{([^{}]*)}`)
+var escapedRune = regexp.MustCompile(`-([0-9A-F]{4,8})-`)
+var referenceChain = regexp.MustCompile(`[A-Za-z0-9_\-]+(\.[A-Za-z0-9_\-]+)+`)
+var validResourceName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_\-]*$`)
+
+// Naming names resources. Names from templates are normalized to snake_case.
+//
+// Templates support placeholders, alternatives separated by | are used when previous ones are empty:
+//   - {name}: name given by the provider, as used in legacy names without escaping
+//   - {id}: resource ID
+//   - {type}, {type_short}: resource type, without provider prefix for type_short
+//   - {tag:KEY}: value of tag or label KEY
+//   - {attr:PATH}: value of attribute PATH, e.g. {attr:bucket}
+//
+// When a placeholder has no value, the resource is named by {name}.
+type Naming struct {
+	Strategy string
+	Template string
+}
+
+func NewNaming(strategy, template string) (*Naming, error) {
+	switch strategy {
+	case "", NamingLegacy:
+		return &Naming{Strategy: NamingLegacy}, nil
+	case NamingTags:
+		if template == "" {
+			template = defaultTagsTemplate
+		}
+	case NamingTemplate:
+		if template == "" {
+			return nil, fmt.Errorf("naming strategy %s requires a template", NamingTemplate)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported naming strategy: %s", strategy)
+	}
+	if err := validateNamingTemplate(template); err != nil {
+		return nil, err
+	}
+	return &Naming{Strategy: strategy, Template: template}, nil
+}
+
+func validateNamingTemplate(template string) error {
+	rest := templatePlaceholder.ReplaceAllString(template, "")
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("invalid naming template %s: unbalanced braces", template)
+	}
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(template, -1) {
+		for _, placeholder := range strings.Split(match[1], "|") {
+			switch {
+			case placeholder == "name", placeholder == "id", placeholder == "type", placeholder == "type_short":
+			case strings.HasPrefix(placeholder, "tag:") && len(placeholder) > len("tag:"):
+			case strings.HasPrefix(placeholder, "attr:") && len(placeholder) > len("attr:"):
+			default:
+				return fmt.Errorf("invalid naming template %s: unknown placeholder {%s}", template, placeholder)
+			}
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the resource before de-duplication
+func (n *Naming) Name(r Resource) string {
+	if n.Strategy == NamingLegacy {
+		return r.ResourceName
+	}
+	missing := false
+	name := templatePlaceholder.ReplaceAllStringFunc(n.Template, func(match string) string {
+		for _, placeholder := range strings.Split(match[1:len(match)-1], "|") {
+			if value := placeholderValue(r, placeholder); value != "" {
+				return value
+			}
+		}
+		missing = true
+		return ""
+	})
+	if missing {
+		name = placeholderValue(r, "name")
+	}
+	name = SnakeCase(name)
+	if name == "" {
+		return r.ResourceName
+	}
+	// terraform names must start with a letter or underscore
+	if name[0] >= '0' && name[0] <= '9' {
+		name = SnakeCase(typeShort(r.InstanceInfo.Type)) + "_" + name
+	}
+	return name
+}
+
+func placeholderValue(r Resource, placeholder string) string {
+	switch {
+	case placeholder == "name":
+		return UnsanitizeName(r.ResourceName)
+	case placeholder == "id":
+		return r.InstanceState.ID
+	case placeholder == "type":
+		return r.InstanceInfo.Type
+	case placeholder == "type_short":
+		return typeShort(r.InstanceInfo.Type)
+	case strings.HasPrefix(placeholder, "tag:"):
+		key := strings.TrimPrefix(placeholder, "tag:")
+		for _, tags := range []string{"tags", "labels"} {
+			if value := attributeValue(r, tags+"."+key); value != "" {
+				return value
+			}
+		}
+	case strings.HasPrefix(placeholder, "attr:"):
+		return attributeValue(r, strings.TrimPrefix(placeholder, "attr:"))
+	}
+	return ""
+}
+
+func attributeValue(r Resource, path string) string {
+	if value := r.InstanceState.Attributes[path]; value != "" {
+		return value
+	}
+	for _, value := range WalkAndGet(path, r.Item) {
+		if s, ok := value.(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func typeShort(resourceType string) string {
+	parts := strings.SplitN(resourceType, "_", 2)
+	return parts[len(parts)-1]
+}
+
+// UnsanitizeName reverts TfSanitize, only escaped characters which TfSanitize would escape are decoded
+func UnsanitizeName(name string) string {
+	name = strings.TrimPrefix(name, "tfer--")
+	return escapedRune.ReplaceAllStringFunc(name, func(match string) string {
+		encoded := match[1 : len(match)-1]
+		if len(encoded) == 4 && strings.HasPrefix(encoded, "00") {
+			encoded = encoded[2:]
+		}
+		decoded, err := hex.DecodeString(encoded)
+		if err != nil || !utf8.Valid(decoded) || utf8.RuneCount(decoded) != 1 || !unsafeChars.Match(decoded) {
+			return match
+		}
+		return string(decoded)
+	})
+}
+
+// SnakeCase normalizes s to a lower case name, words separated by underscores
+func SnakeCase(s string) string {
+	var b strings.Builder
+	afterLowerOrDigit := false
+	separate := false
+	for _, r := range s {
+		isUpper := r >= 'A' && r <= 'Z'
+		isLowerOrDigit := (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9')
+		if !isUpper && !isLowerOrDigit {
+			separate = true
+			afterLowerOrDigit = false
+			continue
+		}
+		if b.Len() > 0 && (separate || (isUpper && afterLowerOrDigit)) {
+			b.WriteByte('_')
+		}
+		separate = false
+		afterLowerOrDigit = isLowerOrDigit
+		if isUpper {
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// NameResources names resources of all services and de-duplicates names per type. Resources of a service with
+// the same type and ID are reported once, other resources with the same name get numbered suffixes in ID order.
+// References between resources in Item are updated to new names.
+func NameResources(resources map[string][]Resource, naming *Naming) map[string][]Resource {
+	var services []string
+	for serviceName := range resources {
+		services = append(services, serviceName)
+	}
+	sort.Strings(services)
+
+	type namedResource struct {
+		service string
+		index   int
+		name    string
+	}
+	var named []*namedResource
+	seen := map[string]struct{}{}
+	for _, serviceName := range services {
+		for i, r := range resources[serviceName] {
+			if r.InstanceState.ID != "" {
+				key := serviceName + "|" + mergeKey(r.InstanceInfo.Type, r.InstanceState.ID)
+				if _, exist := seen[key]; exist {
+					log.Printf("[WARN] duplicate resource found: %s %s, skipped\n", r.InstanceInfo.Type, r.InstanceState.ID)
+					continue
+				}
+				seen[key] = struct{}{}
+			}
+			named = append(named, &namedResource{service: serviceName, index: i, name: naming.Name(r)})
+		}
+	}
+
+	// resources keeping the name are chosen by ID, not by order returned by APIs
+	byName := map[string][]*namedResource{}
+	for _, n := range named {
+		r := resources[n.service][n.index]
+		address := r.InstanceInfo.Type + "." + n.name
+		byName[address] = append(byName[address], n)
+	}
+	used := map[string]struct{}{}
+	for address := range byName {
+		used[address] = struct{}{}
+	}
+	separator := "_"
+	if naming.Strategy == NamingLegacy {
+		separator = "-"
+	}
+	var addresses []string
+	for address, group := range byName {
+		if len(group) > 1 {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		group := byName[address]
+		sort.SliceStable(group, func(i, j int) bool {
+			return resources[group[i].service][group[i].index].InstanceState.ID < resources[group[j].service][group[j].index].InstanceState.ID
+		})
+		for _, n := range group[1:] {
+			resourceType := resources[n.service][n.index].InstanceInfo.Type
+			for i := 2; ; i++ {
+				candidate := n.name + separator + strconv.Itoa(i)
+				if _, exist := used[resourceType+"."+candidate]; !exist {
+					n.name = candidate
+					used[resourceType+"."+candidate] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+
+	renamed := map[string]string{}
+	namedResources := map[string][]Resource{}
+	for _, n := range named {
+		r := resources[n.service][n.index]
+		oldAddress := r.InstanceInfo.Type + "." + r.ResourceName
+		// the first resource with a name was the one referenced before
+		if _, exist := renamed[oldAddress]; !exist && n.name != r.ResourceName {
+			renamed[oldAddress] = n.name
+		}
+		r.SetResourceName(n.name)
+		namedResources[n.service] = append(namedResources[n.service], r)
+	}
+	UpdateReferences(namedResources, renamed)
+	return namedResources
+}
+
+// RenameResource renames the resource at address type.name, references to it are updated
+func RenameResource(resources map[string][]Resource, address, name string) error {
+	if !validResourceName.MatchString(name) {
+		return fmt.Errorf("invalid resource name: %s", name)
+	}
+	var found *Resource
+	for _, serviceResources := range resources {
+		for i := range serviceResources {
+			r := &serviceResources[i]
+			switch r.InstanceInfo.Type + "." + r.ResourceName {
+			case address:
+				found = r
+			case r.InstanceInfo.Type + "." + name:
+				return fmt.Errorf("resource %s.%s already exists", r.InstanceInfo.Type, name)
+			}
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("resource %s not found", address)
+	}
+	found.SetResourceName(name)
+	UpdateReferences(resources, map[string]string{address: name})
+	return nil
+}
+
+// UpdateReferences replaces references like ${type.name.attribute} to renamed resources, renamed maps
+// old addresses to new names
+func UpdateReferences(resources map[string][]Resource, renamed map[string]string) {
+	if len(renamed) == 0 {
+		return
+	}
+	for _, serviceResources := range resources {
+		for _, r := range serviceResources {
+			for k, v := range r.Item {
+				r.Item[k] = updateReferences(v, renamed)
+			}
+		}
+	}
+}
+
+func updateReferences(value interface{}, renamed map[string]string) interface{} {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, ".") {
+			return v
+		}
+		return referenceChain.ReplaceAllStringFunc(v, func(chain string) string {
+			parts := strings.Split(chain, ".")
+			for i := 0; i < len(parts)-1; i++ {
+				if name, exist := renamed[parts[i]+"."+parts[i+1]]; exist {
+					parts[i+1] = name
+					i++
+				}
+			}
+			return strings.Join(parts, ".")
+		})
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = updateReferences(item, renamed)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = updateReferences(item, renamed)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			updateReferences(item, renamed)
+		}
+	case []string:
+		for i, item := range v {
+			v[i] = updateReferences(item, renamed).(string)
+		}
+	}
+	return value
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for input, expected := range map[string]string{
+		"My Web-Server":  "my_web_server",
+		"prodVPC":        "prod_vpc",
+		"sg-0abc123":     "sg_0abc123",
+		"__a//b__":       "a_b",
+		"already_snake1": "already_snake1",
+	} {
+		if actual := SnakeCase(input); actual != expected {
+			t.Errorf("SnakeCase(%q): expected %q, got %q", input, expected, actual)
+		}
+	}
+}
+
+func TestUnsanitizeName(t *testing.T) {
+	for _, name := range []string{"web/server", "a b", "my-CAFE-app", "zoné", "x-00-y"} {
+		if actual := UnsanitizeName(TfSanitize(name)); actual != name {
+			t.Errorf("expected %q, got %q", name, actual)
+		}
+	}
+}
+
+func TestNewNaming(t *testing.T) {
+	if _, err := NewNaming("template", ""); err == nil {
+		t.Error("template strategy requires a template")
+	}
+	if _, err := NewNaming("template", "{unknown}"); err == nil {
+		t.Error("unknown placeholder should fail")
+	}
+	if _, err := NewNaming("template", "{id"); err == nil {
+		t.Error("unbalanced braces should fail")
+	}
+	if _, err := NewNaming("random", ""); err == nil {
+		t.Error("unknown strategy should fail")
+	}
+}
+
+func TestNameResources(t *testing.T) {
+	vpc := func(id string, tags map[string]string) Resource {
+		attributes := map[string]string{}
+		for k, v := range tags {
+			attributes["tags."+k] = v
+		}
+		return NewResource(id, id, "aws_vpc", "aws", attributes, []string{}, map[string]interface{}{})
+	}
+	subnet := NewResource("subnet-1", "subnet-1", "aws_subnet", "aws", map[string]string{}, []string{}, map[string]interface{}{})
+	subnet.Item = map[string]interface{}{
+		"vpc_id":     "${aws_vpc.tfer--vpc-2.id}",
+		"depends_on": []string{"aws_vpc.tfer--vpc-3"},
+	}
+	resources := map[string][]Resource{
+		"vpc": {
+			vpc("vpc-3", map[string]string{"Name": "Main VPC"}),
+			vpc("vpc-2", map[string]string{"Name": "Main VPC"}),
+			vpc("vpc-1", nil),
+			vpc("vpc-1", nil),
+		},
+		"subnet": {subnet},
+	}
+	naming, err := NewNaming(NamingTags, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := NameResources(resources, naming)
+
+	var names []string
+	for _, r := range named["vpc"] {
+		names = append(names, r.ResourceName)
+	}
+	// the duplicate vpc-1 is skipped, main_vpc is kept by the lower ID
+	if expected := []string{"main_vpc_2", "main_vpc", "vpc_1"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected names %v, got %v", expected, names)
+	}
+	if named["vpc"][0].InstanceInfo.Id != "aws_vpc.main_vpc_2" {
+		t.Errorf("unexpected instance info %v", named["vpc"][0].InstanceInfo)
+	}
+	expectedItem := map[string]interface{}{
+		"vpc_id":     "${aws_vpc.main_vpc.id}",
+		"depends_on": []string{"aws_vpc.main_vpc_2"},
+	}
+	if item := named["subnet"][0].Item; !reflect.DeepEqual(item, expectedItem) {
+		t.Errorf("references should be updated, got %v", item)
+	}
+	if named["subnet"][0].ResourceName != "subnet_1" {
+		t.Errorf("resources without tag should fall back to their name, got %s", named["subnet"][0].ResourceName)
+	}
+}
+
+func TestNameResourcesTemplate(t *testing.T) {
+	resources := map[string][]Resource{
+		"s3": {NewResource("logs", "logs", "aws_s3_bucket", "aws", map[string]string{"bucket": "logs"}, []string{}, map[string]interface{}{})},
+		"sg": {NewResource("sg-1", "sg-1", "aws_security_group", "aws", map[string]string{}, []string{}, map[string]interface{}{})},
+	}
+	naming, err := NewNaming(NamingTemplate, "{type_short}_{id}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := NameResources(resources, naming)
+	if name := named["s3"][0].ResourceName; name != "s3_bucket_logs" {
+		t.Errorf("unexpected name %s", name)
+	}
+	if name := named["sg"][0].ResourceName; name != "security_group_sg_1" {
+		t.Errorf("unexpected name %s", name)
+	}
+
+	legacy, _ := NewNaming("", "")
+	named = NameResources(map[string][]Resource{
+		"sg": {
+			NewResource("sg-2", "default", "aws_security_group", "aws", map[string]string{}, []string{}, map[string]interface{}{}),
+			NewResource("sg-1", "default", "aws_security_group", "aws", map[string]string{}, []string{}, map[string]interface{}{}),
+		},
+	}, legacy)
+	if named["sg"][0].ResourceName != "tfer--default-2" || named["sg"][1].ResourceName != "tfer--default" {
+		t.Errorf("legacy duplicates should get suffixes, got %s %s", named["sg"][0].ResourceName, named["sg"][1].ResourceName)
+	}
+}
+
+func TestRenameResource(t *testing.T) {
+	resource := func(id, name, resourceType string) Resource {
+		r := NewSimpleResource(id, name, resourceType, "aws", []string{})
+		r.SetResourceName(name)
+		return r
+	}
+	vpc := resource("vpc-1", "main", "aws_vpc")
+	other := resource("vpc-2", "other", "aws_vpc")
+	subnet := resource("subnet-1", "subnet", "aws_subnet")
+	subnet.Item = map[string]interface{}{"vpc_id": "${aws_vpc.main.id}"}
+	resources := map[string][]Resource{"vpc": {vpc, other}, "subnet": {subnet}}
+
+	if err := RenameResource(resources, "aws_vpc.main", "network"); err != nil {
+		t.Fatal(err)
+	}
+	if r := resources["vpc"][0]; r.ResourceName != "network" || r.InstanceInfo.Id != "aws_vpc.network" {
+		t.Errorf("unexpected resource %s %s", r.ResourceName, r.InstanceInfo.Id)
+	}
+	if ref := resources["subnet"][0].Item["vpc_id"]; ref != "${aws_vpc.network.id}" {
+		t.Errorf("references should be updated, got %v", ref)
+	}
+	for _, test := range [][2]string{
+		{"aws_vpc.main", "renamed"},  // not found anymore
+		{"aws_vpc.network", "other"}, // already exists
+		{"aws_vpc.network", "1vpc"},  // invalid name
+	} {
+		if err := RenameResource(resources, test[0], test[1]); err == nil {
+			t.Errorf("renaming %s to %s should fail", test[0], test[1])
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerwrapper
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// ParseConfigOverrides parses provider settings given as key=value, nested blocks and map entries are
+// addressed with dots like endpoints.s3 or default_tags.tags.Owner
+func ParseConfigOverrides(pairs []string) (map[string]string, error) {
+	overrides := map[string]string{}
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("provider setting %q is not key=value", pair)
+		}
+		for _, name := range strings.Split(parts[0], ".") {
+			if name == "" {
+				return nil, fmt.Errorf("provider setting %q has an empty name", parts[0])
+			}
+		}
+		overrides[parts[0]] = parts[1]
+	}
+	return overrides, nil
+}
+
+// CheckConfigOverrides rejects overrides of settings the provider flags set, like the region of an import of
+// several regions, the provider and its SDK clients would list and read resources elsewhere
+func CheckConfigOverrides(config cty.Value, overrides map[string]string) error {
+	if config == cty.NilVal || !config.IsKnown() || config.IsNull() || !config.Type().IsObjectType() {
+		return nil
+	}
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !config.Type().HasAttribute(key) {
+			continue
+		}
+		value := config.GetAttr(key)
+		if value.Type() != cty.String || !value.IsKnown() || value.IsNull() || value.AsString() == "" {
+			continue
+		}
+		if value.AsString() != overrides[key] {
+			return fmt.Errorf("provider setting %s=%s conflicts with %s set by the provider flags", key, overrides[key], value.AsString())
+		}
+	}
+	return nil
+}
+
+// MergeConfig sets the overrides in the provider config as strings, they are converted to the types of the
+// provider schema by CoerceConfig
+func MergeConfig(config cty.Value, overrides map[string]string) cty.Value {
+	if len(overrides) == 0 {
+		return config
+	}
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		config = setConfigValue(config, strings.Split(key, "."), cty.StringVal(overrides[key]))
+	}
+	return config
+}
+
+func setConfigValue(config cty.Value, path []string, value cty.Value) cty.Value {
+	attributes := map[string]cty.Value{}
+	if config != cty.NilVal && config.IsKnown() && !config.IsNull() {
+		switch {
+		case config.Type().IsObjectType() || config.Type().IsMapType():
+			attributes = config.AsValueMap()
+		case (config.Type().IsListType() || config.Type().IsSetType() || config.Type().IsTupleType()) && config.LengthInt() == 1:
+			// blocks limited to one item are given as a list of one object
+			return setConfigValue(config.AsValueSlice()[0], path, value)
+		}
+	}
+	if attributes == nil {
+		attributes = map[string]cty.Value{}
+	}
+	if len(path) == 1 {
+		attributes[path[0]] = value
+	} else {
+		attributes[path[0]] = setConfigValue(attributes[path[0]], path[1:], value)
+	}
+	return cty.ObjectVal(attributes)
+}
+
+// CoerceConfig validates the config against the provider schema and converts settings given as strings,
+// lists are comma separated
+func CoerceConfig(block *configschema.Block, config cty.Value) (cty.Value, error) {
+	config, err := coerceBlock(block, config, nil)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	return block.CoerceValue(config)
+}
+
+func coerceBlock(block *configschema.Block, config cty.Value, path []string) (cty.Value, error) {
+	if config == cty.NilVal || !config.IsKnown() || config.IsNull() || !config.Type().IsObjectType() {
+		return config, nil
+	}
+	attributes := config.AsValueMap()
+	for name, value := range attributes {
+		settingPath := append(append([]string{}, path...), name)
+		setting := strings.Join(settingPath, ".")
+		if attribute, exist := block.Attributes[name]; exist {
+			if value.Type() == cty.String && value.IsKnown() && !value.IsNull() &&
+				(attribute.Type.IsListType() || attribute.Type.IsSetType()) {
+				var items []cty.Value
+				for _, item := range strings.Split(value.AsString(), ",") {
+					items = append(items, cty.StringVal(strings.TrimSpace(item)))
+				}
+				value = cty.TupleVal(items)
+			}
+			converted, err := convert.Convert(value, attribute.Type)
+			if err != nil {
+				return cty.NilVal, fmt.Errorf("provider setting %s: %v", setting, err)
+			}
+			attributes[name] = converted
+			continue
+		}
+		nested, exist := block.BlockTypes[name]
+		if !exist {
+			return cty.NilVal, fmt.Errorf("provider setting %s is not supported by the provider", setting)
+		}
+		if !value.Type().IsObjectType() {
+			// already given in the block's form, e.g. by the provider's config
+			continue
+		}
+		item, err := coerceBlock(&nested.Block, value, settingPath)
+		if err != nil {
+			return cty.NilVal, err
+		}
+		switch nested.Nesting {
+		case configschema.NestingSingle, configschema.NestingGroup:
+			attributes[name] = item
+		case configschema.NestingList:
+			attributes[name] = cty.ListVal([]cty.Value{item})
+		case configschema.NestingSet:
+			attributes[name] = cty.SetVal([]cty.Value{item})
+		default:
+			return cty.NilVal, fmt.Errorf("provider setting %s: blocks nested as map are not supported", setting)
+		}
+	}
+	return cty.ObjectVal(attributes), nil
+}
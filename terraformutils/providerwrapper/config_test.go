@@ -0,0 +1,136 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerwrapper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var testProviderSchema = &configschema.Block{
+	Attributes: map[string]*configschema.Attribute{
+		"region":                 {Type: cty.String, Optional: true},
+		"max_retries":            {Type: cty.Number, Optional: true},
+		"skip_region_validation": {Type: cty.Bool, Optional: true},
+		"allowed_account_ids":    {Type: cty.Set(cty.String), Optional: true},
+	},
+	BlockTypes: map[string]*configschema.NestedBlock{
+		"endpoints": {
+			Nesting: configschema.NestingSet,
+			Block: configschema.Block{Attributes: map[string]*configschema.Attribute{
+				"s3":  {Type: cty.String, Optional: true},
+				"ec2": {Type: cty.String, Optional: true},
+			}},
+		},
+		"default_tags": {
+			Nesting:  configschema.NestingList,
+			MaxItems: 1,
+			Block: configschema.Block{Attributes: map[string]*configschema.Attribute{
+				"tags": {Type: cty.Map(cty.String), Optional: true},
+			}},
+		},
+	},
+}
+
+func TestCoerceConfig(t *testing.T) {
+	overrides, err := ParseConfigOverrides([]string{
+		"region=us-east-1",
+		"max_retries=3",
+		"allowed_account_ids=111, 222",
+		"endpoints.s3=http://localhost:4566",
+		"default_tags.tags.Owner=team=platform",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := MergeConfig(cty.ObjectVal(map[string]cty.Value{
+		"region":                 cty.StringVal("eu-west-1"),
+		"skip_region_validation": cty.True,
+	}), overrides)
+	config, err = CoerceConfig(testProviderSchema, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.GetAttr("region").AsString() != "us-east-1" {
+		t.Errorf("overrides replace the provider's config, got region %s", config.GetAttr("region").GoString())
+	}
+	if !config.GetAttr("skip_region_validation").True() {
+		t.Errorf("settings not overridden are kept")
+	}
+	if !config.GetAttr("max_retries").RawEquals(cty.NumberIntVal(3)) {
+		t.Errorf("expected max_retries 3, got %s", config.GetAttr("max_retries").GoString())
+	}
+	if !config.GetAttr("allowed_account_ids").RawEquals(cty.SetVal([]cty.Value{cty.StringVal("111"), cty.StringVal("222")})) {
+		t.Errorf("lists are comma separated, got %s", config.GetAttr("allowed_account_ids").GoString())
+	}
+	endpoints := config.GetAttr("endpoints").AsValueSlice()
+	if len(endpoints) != 1 || endpoints[0].GetAttr("s3").AsString() != "http://localhost:4566" || !endpoints[0].GetAttr("ec2").IsNull() {
+		t.Errorf("expected an endpoints block with s3, got %s", config.GetAttr("endpoints").GoString())
+	}
+	tags := config.GetAttr("default_tags").Index(cty.NumberIntVal(0)).GetAttr("tags")
+	if !tags.RawEquals(cty.MapVal(map[string]cty.Value{"Owner": cty.StringVal("team=platform")})) {
+		t.Errorf("expected map entry Owner, got %s", tags.GoString())
+	}
+}
+
+func TestCoerceConfigErrors(t *testing.T) {
+	testCases := []struct {
+		setting string
+		err     string
+	}{
+		{"regoin=us-east-1", "regoin is not supported"},
+		{"endpoints.s4=http://localhost:4566", "endpoints.s4 is not supported"},
+		{"max_retries=many", "max_retries"},
+	}
+	for _, tc := range testCases {
+		overrides, err := ParseConfigOverrides([]string{tc.setting})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = CoerceConfig(testProviderSchema, MergeConfig(cty.EmptyObjectVal, overrides))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error %q, got %v", tc.setting, tc.err, err)
+		}
+	}
+	for _, setting := range []string{"region", "=us-east-1", "endpoints..s3=url"} {
+		if _, err := ParseConfigOverrides([]string{setting}); err == nil {
+			t.Errorf("%s: expected an error", setting)
+		}
+	}
+}
+
+func TestCheckConfigOverrides(t *testing.T) {
+	config := cty.ObjectVal(map[string]cty.Value{
+		"region":                 cty.StringVal("eu-west-1"),
+		"profile":                cty.StringVal(""),
+		"skip_region_validation": cty.True,
+	})
+	for _, overrides := range []map[string]string{
+		{"region": "eu-west-1", "profile": "prod"},
+		{"endpoints.s3": "http://localhost:4566", "skip_region_validation": "false"},
+	} {
+		if err := CheckConfigOverrides(config, overrides); err != nil {
+			t.Errorf("unexpected error for %v: %v", overrides, err)
+		}
+	}
+	err := CheckConfigOverrides(config, map[string]string{"region": "us-east-1"})
+	if err == nil || !strings.Contains(err.Error(), "region=us-east-1 conflicts with eu-west-1") {
+		t.Errorf("expected a conflict with the region, got %v", err)
+	}
+}
@@ -0,0 +1,546 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerwrapper
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// DefaultLockFile is the dependency lock file written by terraform init
+const DefaultLockFile = ".terraform.lock.hcl"
+
+// Installation selects provider binaries
+type Installation struct {
+	// Version is a version constraint like ~> 4.0, any version is allowed when empty
+	Version string
+	// PluginDirs are filesystem mirrors searched instead of the default directories
+	PluginDirs []string
+	// LockFile pins versions and checksums of providers, ignored when it doesn't exist
+	LockFile string
+	// Source is the provider address like hashicorp/aws, packages of other namespaces in filesystem mirrors
+	// are skipped. Any namespace is accepted when empty.
+	Source string
+}
+
+// ProviderPackage is the provider binary used for a provider
+type ProviderPackage struct {
+	// Source is the provider address like registry.terraform.io/hashicorp/aws, empty for v0.12 plugin dirs
+	Source     string
+	Version    *version.Version
+	Executable string
+	// Hashes are the checksums written to the lock file, computed by lockHashes unless verified against one
+	Hashes []string
+
+	constraints string
+	// dir and zip are the unpacked and packed package hashed for the lock file
+	dir      string
+	zip      string
+	hashOnce sync.Once
+	hashErr  error
+}
+
+type lockFile struct {
+	Providers []lockedProvider `hcl:"provider,block"`
+	Remain    hcl.Body         `hcl:",remain"`
+}
+
+type lockedProvider struct {
+	Source      string   `hcl:"source,label"`
+	Version     string   `hcl:"version"`
+	Constraints string   `hcl:"constraints,optional"`
+	Hashes      []string `hcl:"hashes,optional"`
+}
+
+// registryHost is the host of provider addresses without one
+const registryHost = "registry.terraform.io"
+
+// installations are kept by provider, jobs of run import several providers concurrently
+var (
+	installations    = map[string]Installation{}
+	installed        = map[string]*ProviderPackage{}
+	installationLock sync.Mutex
+)
+
+// SetInstallation configures how the provider is found, it must be called before the provider is started.
+// Packages found are kept while the installation doesn't change, providers like aws import several times.
+func SetInstallation(providerName string, i Installation) error {
+	if i.Version != "" {
+		if _, err := version.NewConstraint(i.Version); err != nil {
+			return fmt.Errorf("invalid provider version constraint %s: %v", i.Version, err)
+		}
+	}
+	installationLock.Lock()
+	defer installationLock.Unlock()
+	if !reflect.DeepEqual(providerInstallation(providerName), i) {
+		installations[providerName] = i
+		delete(installed, providerName)
+	}
+	return nil
+}
+
+func providerInstallation(providerName string) Installation {
+	if i, exist := installations[providerName]; exist {
+		return i
+	}
+	return Installation{LockFile: DefaultLockFile}
+}
+
+// FindProvider returns the package of the highest version of the provider matching the version constraint
+// and the lock file
+func FindProvider(providerName string) (*ProviderPackage, error) {
+	if recorder.Replaying() {
+		return replayedPackage(providerName)
+	}
+	installationLock.Lock()
+	defer installationLock.Unlock()
+	if pkg, exist := installed[providerName]; exist {
+		return pkg, nil
+	}
+	pkg, err := findProvider(providerName, providerInstallation(providerName))
+	if err != nil {
+		return nil, err
+	}
+	installed[providerName] = pkg
+	return pkg, nil
+}
+
+type providerCandidate struct {
+	source  string
+	version *version.Version
+	// dir is an unpacked package, zip a packed one and executable a v0.12 plugin file
+	dir        string
+	zip        string
+	executable string
+}
+
+func findProvider(providerName string, i Installation) (*ProviderPackage, error) {
+	var constraints version.Constraints
+	if i.Version != "" {
+		var err error
+		if constraints, err = version.NewConstraint(i.Version); err != nil {
+			return nil, err
+		}
+	}
+	locked, err := readLockFile(i.LockFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := i.PluginDirs
+	if len(dirs) == 0 {
+		dirs = defaultPluginDirs()
+	}
+	source := i.Source
+	if strings.Count(source, "/") == 1 {
+		source = registryHost + "/" + source
+	}
+	var selected *providerCandidate
+	for _, dir := range dirs {
+		for _, candidate := range providerCandidates(dir, providerName) {
+			candidate := candidate
+			// forks have the same type in other namespaces
+			if source != "" && candidate.source != "" && !strings.EqualFold(candidate.source, source) {
+				continue
+			}
+			if constraints != nil && (candidate.version == nil || !constraints.Check(candidate.version)) {
+				continue
+			}
+			if lock, exist := locked[candidate.source]; exist && (candidate.version == nil || candidate.version.String() != lock.Version) {
+				continue
+			}
+			// the first directory wins for equal versions
+			if selected == nil || (candidate.version != nil && (selected.version == nil || candidate.version.GreaterThan(selected.version))) {
+				selected = &candidate
+			}
+		}
+	}
+	if selected == nil {
+		if i.Version != "" {
+			return nil, fmt.Errorf("no %s provider matching %s found in %s", providerName, i.Version, strings.Join(dirs, ", "))
+		}
+		return nil, fmt.Errorf("no %s provider found in %s. Ensure that you are following https://www.terraform.io/docs/configuration/providers.html#third-party-plugins", providerName, strings.Join(dirs, ", "))
+	}
+	return installProvider(selected, providerName, i, locked[selected.source])
+}
+
+// defaultPluginDirs are the directories terraform init installs providers to, followed by the implied local
+// mirror of the user
+func defaultPluginDirs() []string {
+	dataDir := os.Getenv("TF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = DefaultDataDir
+	}
+	home := filepath.Join(os.Getenv("HOME"), ".terraform.d")
+	return []string{
+		filepath.Join(dataDir, "providers"),
+		filepath.Join(dataDir, "plugins"),
+		filepath.Join(home, "providers"),
+		filepath.Join(home, "plugins"),
+	}
+}
+
+// providerCandidates lists packages in the layouts of a filesystem mirror,
+// HOSTNAME/NAMESPACE/TYPE/VERSION/TARGET/ and HOSTNAME/NAMESPACE/TYPE/terraform-provider-TYPE_VERSION_TARGET.zip,
+// and in TARGET/ of v0.12 plugin directories
+func providerCandidates(dir, providerName string) []providerCandidate {
+	var candidates []providerCandidate
+	prefix := "terraform-provider-" + providerName
+	hosts, _ := ioutil.ReadDir(dir)
+	for _, host := range hosts {
+		if !host.IsDir() {
+			continue
+		}
+		if host.Name() == pluginMachineName {
+			for _, file := range readDir(filepath.Join(dir, host.Name())) {
+				name := file.Name()
+				if file.IsDir() || !strings.HasPrefix(name, prefix) || (len(name) > len(prefix) && name[len(prefix)] != '_') {
+					continue
+				}
+				candidate := providerCandidate{executable: filepath.Join(dir, host.Name(), name)}
+				if parts := strings.Split(name, "_"); len(parts) > 1 {
+					candidate.version, _ = version.NewVersion(strings.TrimPrefix(parts[1], "v"))
+				}
+				candidates = append(candidates, candidate)
+			}
+			continue
+		}
+		for _, namespace := range readDir(filepath.Join(dir, host.Name())) {
+			source := host.Name() + "/" + namespace.Name() + "/" + providerName
+			typeDir := filepath.Join(dir, host.Name(), namespace.Name(), providerName)
+			for _, entry := range readDir(typeDir) {
+				if entry.IsDir() {
+					v, err := version.NewVersion(entry.Name())
+					packageDir := filepath.Join(typeDir, entry.Name(), pluginMachineName)
+					if _, statErr := os.Stat(packageDir); err == nil && statErr == nil {
+						candidates = append(candidates, providerCandidate{source: source, version: v, dir: packageDir})
+					}
+					continue
+				}
+				name := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), prefix+"_"), "_"+pluginMachineName+".zip")
+				if name == entry.Name() || !strings.HasSuffix(entry.Name(), ".zip") {
+					continue
+				}
+				if v, err := version.NewVersion(name); err == nil {
+					candidates = append(candidates, providerCandidate{source: source, version: v, zip: filepath.Join(typeDir, entry.Name())})
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+func readDir(dir string) []os.FileInfo {
+	files, _ := ioutil.ReadDir(dir)
+	return files
+}
+
+// installProvider verifies the candidate against the lock file, packed packages are unpacked to the data dir
+// like terraform init does. Packages are hashed only to verify them against the lock file or to write it.
+func installProvider(candidate *providerCandidate, providerName string, i Installation, lock *lockedProvider) (*ProviderPackage, error) {
+	pkg := &ProviderPackage{Source: candidate.source, Version: candidate.version, Executable: candidate.executable, constraints: i.Version}
+	if candidate.executable != "" {
+		return pkg, nil
+	}
+	pkg.dir, pkg.zip = candidate.dir, candidate.zip
+	if lock != nil {
+		hashes, err := packageHashes(pkg.dir, pkg.zip)
+		if err != nil {
+			return nil, err
+		}
+		if !containsAny(lock.Hashes, hashes) {
+			return nil, fmt.Errorf("checksum of %s %s doesn't match %s, locked are %s", candidate.source, candidate.version, i.LockFile, strings.Join(lock.Hashes, ", "))
+		}
+		pkg.Hashes = uniqueSorted(append(hashes, lock.Hashes...))
+		if pkg.constraints == "" {
+			pkg.constraints = lock.Constraints
+		}
+	}
+	dir := candidate.dir
+	if candidate.zip != "" {
+		dataDir := os.Getenv("TF_DATA_DIR")
+		if dataDir == "" {
+			dataDir = DefaultDataDir
+		}
+		dir = filepath.Join(append([]string{dataDir, "providers"}, strings.Split(candidate.source, "/")...)...)
+		dir = filepath.Join(dir, candidate.version.String(), pluginMachineName)
+		if err := unzip(candidate.zip, dir); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range readDir(dir) {
+		if !file.IsDir() && strings.HasPrefix(file.Name(), "terraform-provider-"+providerName) {
+			pkg.Executable = filepath.Join(dir, file.Name())
+		}
+	}
+	if pkg.Executable == "" {
+		return nil, fmt.Errorf("no terraform-provider-%s executable in %s", providerName, dir)
+	}
+	return pkg, nil
+}
+
+// packageHashes returns the h1: hash of the files of a package, and the zh: hash of packed packages
+func packageHashes(dir, zip string) ([]string, error) {
+	if zip == "" {
+		h1, err := hashDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		return []string{h1}, nil
+	}
+	zipHash, err := hashZip(zip)
+	if err != nil {
+		return nil, err
+	}
+	h1, err := hashZipContent(zip)
+	if err != nil {
+		return nil, err
+	}
+	return []string{h1, zipHash}, nil
+}
+
+// lockHashes returns the checksums written to the lock file, packages not verified against one are hashed once
+func (pkg *ProviderPackage) lockHashes() ([]string, error) {
+	pkg.hashOnce.Do(func() {
+		if len(pkg.Hashes) > 0 || (pkg.dir == "" && pkg.zip == "") {
+			return
+		}
+		var hashes []string
+		hashes, pkg.hashErr = packageHashes(pkg.dir, pkg.zip)
+		pkg.Hashes = uniqueSorted(hashes)
+	})
+	return pkg.Hashes, pkg.hashErr
+}
+
+func readLockFile(path string) (map[string]*lockedProvider, error) {
+	locked := map[string]*lockedProvider{}
+	if path == "" {
+		return locked, nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return locked, nil
+	}
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	content := lockFile{}
+	if diags := gohcl.DecodeBody(file.Body, nil, &content); diags.HasErrors() {
+		return nil, diags
+	}
+	for i := range content.Providers {
+		locked[content.Providers[i].Source] = &content.Providers[i]
+	}
+	return locked, nil
+}
+
+// LockFileData returns a dependency lock file for the provider, nil for providers of v0.12 plugin dirs
+func LockFileData(providerName string) ([]byte, error) {
+	pkg, err := FindProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.Source == "" {
+		return nil, nil
+	}
+	hashes, err := pkg.lockHashes()
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	b.WriteString("# This file is maintained automatically by \"terraform init\".\n")
+	b.WriteString("# Manual edits may be lost in future updates.\n\n")
+	fmt.Fprintf(&b, "provider %q {\n", pkg.Source)
+	fmt.Fprintf(&b, "  version     = %q\n", pkg.Version.String())
+	if pkg.constraints != "" {
+		fmt.Fprintf(&b, "  constraints = %q\n", pkg.constraints)
+	}
+	b.WriteString("  hashes = [\n")
+	for _, hash := range hashes {
+		fmt.Fprintf(&b, "    %q,\n", hash)
+	}
+	b.WriteString("  ]\n}\n")
+	return b.Bytes(), nil
+}
+
+// hashDir returns the h1: hash of terraform, the dirhash of go modules over the files of a package. The
+// package dir is resolved first, packages of TF_PLUGIN_CACHE_DIR are linked to the cache.
+func hashDir(dir string) (string, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	files := map[string]func() (io.ReadCloser, error){}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = func() (io.ReadCloser, error) {
+			return os.Open(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash1(files)
+}
+
+func hashZipContent(path string) (string, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	files := map[string]func() (io.ReadCloser, error){}
+	for _, f := range r.File {
+		if !strings.HasSuffix(f.Name, "/") {
+			files[f.Name] = f.Open
+		}
+	}
+	return hash1(files)
+}
+
+func hash1(files map[string]func() (io.ReadCloser, error)) (string, error) {
+	var names []string
+	for name := range files {
+		if strings.Contains(name, "\n") {
+			return "", errors.New("file names with newlines can't be hashed")
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	summary := sha256.New()
+	for _, name := range names {
+		r, err := files[name]()
+		if err != nil {
+			return "", err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %s\n", h.Sum(nil), name)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// hashZip returns the zh: hash of terraform, the sha256 of a packed package as listed in SHA256SUMS
+func hashZip(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "zh:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func unzip(path, dir string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		target := filepath.Join(dir, f.Name)
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("invalid file %s in %s", f.Name, path)
+		}
+		if strings.HasSuffix(f.Name, "/") {
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := unzipFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unzipFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, f.Mode()|0o700)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func containsAny(values, wanted []string) bool {
+	for _, v := range values {
+		for _, w := range wanted {
+			if v == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func uniqueSorted(values []string) []string {
+	unique := map[string]struct{}{}
+	var result []string
+	for _, v := range values {
+		if _, exist := unique[v]; !exist {
+			unique[v] = struct{}{}
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,232 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerwrapper
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testMirror returns a filesystem mirror with aws 4.1.0, 4.9.0 and 5.0.0 unpacked and 4.10.0 packed
+func testMirror(t *testing.T) string {
+	mirror := t.TempDir()
+	typeDir := filepath.Join(mirror, "registry.terraform.io", "hashicorp", "aws")
+	for _, v := range []string{"4.1.0", "4.9.0", "5.0.0"} {
+		writeTestFile(t, filepath.Join(typeDir, v, pluginMachineName, "terraform-provider-aws_v"+v+"_x5"), "aws "+v)
+	}
+	writeTestFile(t, filepath.Join(mirror, "registry.terraform.io", "hashicorp", "awscc", "9.0.0", pluginMachineName, "terraform-provider-awscc_v9.0.0"), "awscc")
+
+	f, err := os.Create(filepath.Join(typeDir, "terraform-provider-aws_4.10.0_"+pluginMachineName+".zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	entry, err := w.Create("terraform-provider-aws_v4.10.0_x5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := entry.Write([]byte("aws 4.10.0")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return mirror
+}
+
+func TestFindProvider(t *testing.T) {
+	mirror := testMirror(t)
+	t.Setenv("TF_DATA_DIR", t.TempDir())
+
+	pkg, err := findProvider("aws", Installation{PluginDirs: []string{mirror}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version.String() != "5.0.0" || pkg.Source != "registry.terraform.io/hashicorp/aws" {
+		t.Errorf("expected the highest version, got %s %s", pkg.Source, pkg.Version)
+	}
+
+	pkg, err = findProvider("aws", Installation{Version: "~> 4.0", PluginDirs: []string{mirror}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version.String() != "4.10.0" {
+		t.Errorf("expected 4.10.0, got %s", pkg.Version)
+	}
+	// packed packages are unpacked to the data dir
+	if content, err := os.ReadFile(pkg.Executable); err != nil || string(content) != "aws 4.10.0" {
+		t.Errorf("expected the unpacked provider, got %s: %v", pkg.Executable, err)
+	}
+	if len(pkg.Hashes) != 0 {
+		t.Errorf("expected packages to be hashed for lock files only, got %v", pkg.Hashes)
+	}
+	if hashes, err := pkg.lockHashes(); err != nil || len(hashes) != 2 || !strings.HasPrefix(hashes[0], "h1:") || !strings.HasPrefix(hashes[1], "zh:") {
+		t.Errorf("expected h1 and zh hashes, got %v: %v", hashes, err)
+	}
+
+	if _, err := findProvider("aws", Installation{Version: "~> 3.0", PluginDirs: []string{mirror}}); err == nil {
+		t.Errorf("expected an error for an unmatched constraint")
+	}
+}
+
+func TestFindProviderSkipsForks(t *testing.T) {
+	mirror := testMirror(t)
+	writeTestFile(t, filepath.Join(mirror, "registry.terraform.io", "someorg", "aws", "9.0.0", pluginMachineName, "terraform-provider-aws_v9.0.0_x5"), "fork")
+
+	pkg, err := findProvider("aws", Installation{PluginDirs: []string{mirror}, Source: "hashicorp/aws"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version.String() != "5.0.0" || pkg.Source != "registry.terraform.io/hashicorp/aws" {
+		t.Errorf("expected the fork to be skipped, got %s %s", pkg.Source, pkg.Version)
+	}
+	if _, err := findProvider("aws", Installation{Version: ">= 9.0", PluginDirs: []string{mirror}, Source: "hashicorp/aws"}); err == nil {
+		t.Errorf("expected an error when only the fork matches")
+	}
+}
+
+func TestFindProviderLocked(t *testing.T) {
+	mirror := testMirror(t)
+	pkg, err := findProvider("aws", Installation{Version: "4.1.0", PluginDirs: []string{mirror}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes, err := pkg.lockHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockFile := filepath.Join(t.TempDir(), DefaultLockFile)
+	writeTestFile(t, lockFile, `
+provider "registry.terraform.io/hashicorp/aws" {
+  version     = "4.1.0"
+  constraints = "~> 4.0"
+  hashes = [
+    "`+hashes[0]+`",
+    "zh:0000",
+  ]
+}
+`)
+	pkg, err = findProvider("aws", Installation{PluginDirs: []string{mirror}, LockFile: lockFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version.String() != "4.1.0" {
+		t.Errorf("expected the locked version, got %s", pkg.Version)
+	}
+	if len(pkg.Hashes) != 2 || pkg.constraints != "~> 4.0" {
+		t.Errorf("expected hashes and constraints of the lock file, got %v %s", pkg.Hashes, pkg.constraints)
+	}
+
+	writeTestFile(t, filepath.Join(mirror, "registry.terraform.io", "hashicorp", "aws", "4.1.0", pluginMachineName, "terraform-provider-aws_v4.1.0_x5"), "changed")
+	if _, err := findProvider("aws", Installation{PluginDirs: []string{mirror}, LockFile: lockFile}); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a checksum error, got %v", err)
+	}
+}
+
+// terraform init links packages of TF_PLUGIN_CACHE_DIR to the cache
+func TestFindProviderCached(t *testing.T) {
+	cache := testMirror(t)
+	mirror := t.TempDir()
+	typeDir := filepath.Join("registry.terraform.io", "hashicorp", "aws", "4.1.0")
+	if err := os.MkdirAll(filepath.Join(mirror, typeDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(cache, typeDir, pluginMachineName), filepath.Join(mirror, typeDir, pluginMachineName)); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := findProvider("aws", Installation{PluginDirs: []string{mirror}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version.String() != "4.1.0" {
+		t.Errorf("expected the linked package, got %s", pkg.Version)
+	}
+	hashes, err := pkg.lockHashes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := hashDir(filepath.Join(cache, typeDir, pluginMachineName))
+	if err != nil || len(hashes) != 1 || hashes[0] != cached {
+		t.Errorf("expected the hash of the cached package %s, got %v: %v", cached, hashes, err)
+	}
+}
+
+func TestFindProviderV12(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, pluginMachineName, "terraform-provider-aws_v2.70.0_x4"), "aws")
+	writeTestFile(t, filepath.Join(dir, pluginMachineName, "terraform-provider-awscc_v0.1.0_x5"), "awscc")
+	pkg, err := findProvider("aws", Installation{PluginDirs: []string{dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Version.String() != "2.70.0" || pkg.Source != "" || filepath.Base(pkg.Executable) != "terraform-provider-aws_v2.70.0_x4" {
+		t.Errorf("unexpected package %v", pkg)
+	}
+}
+
+func TestLockFileData(t *testing.T) {
+	mirror := testMirror(t)
+	if err := SetInstallation("aws", Installation{Version: ">= 5.0", PluginDirs: []string{mirror}}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetInstallation("aws", Installation{LockFile: DefaultLockFile}) //nolint
+	data, err := LockFileData("aws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`provider "registry.terraform.io/hashicorp/aws" {`, `version     = "5.0.0"`, `constraints = ">= 5.0"`, `"h1:`} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected %s in %s", expected, data)
+		}
+	}
+	if v := GetProviderVersion("aws"); v != "5.0.0" {
+		t.Errorf("expected version 5.0.0, got %s", v)
+	}
+	if err := SetInstallation("aws", Installation{Version: "~> x"}); err == nil {
+		t.Errorf("expected an error for an invalid constraint")
+	}
+}
+
+func TestSetInstallationPerProvider(t *testing.T) {
+	mirror := testMirror(t)
+	t.Setenv("TF_DATA_DIR", t.TempDir())
+	if err := SetInstallation("aws", Installation{Version: "~> 4.0", PluginDirs: []string{mirror}}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetInstallation("aws", Installation{LockFile: DefaultLockFile}) //nolint
+	if err := SetInstallation("awscc", Installation{PluginDirs: []string{mirror}}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetInstallation("awscc", Installation{LockFile: DefaultLockFile}) //nolint
+	if v := GetProviderVersion("aws"); v != "4.10.0" {
+		t.Errorf("expected the aws constraint to be kept, got %s", v)
+	}
+	if v := GetProviderVersion("awscc"); v != "9.0.0" {
+		t.Errorf("expected the awscc installation, got %s", v)
+	}
+}
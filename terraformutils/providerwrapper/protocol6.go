@@ -0,0 +1,555 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerwrapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/terraform/configs/configschema"
+	tfplugin "github.com/hashicorp/terraform/plugin"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"github.com/zclconf/go-cty/cty/msgpack"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// versionedPlugins are the plugin protocols offered to providers, providers serving both choose 6
+var versionedPlugins = map[int]plugin.PluginSet{
+	5: {tfplugin.ProviderPluginName: &tfplugin.GRPCProviderPlugin{}},
+	6: {tfplugin.ProviderPluginName: &grpcProviderPluginV6{}},
+}
+
+// providers like aws have schemas larger than the default 4MB of grpc
+const maxRecvSize = 64 << 20
+
+// grpcProviderPluginV6 implements plugin.GRPCPlugin for providers serving plugin protocol 6 only, like
+// providers built on the plugin framework
+type grpcProviderPluginV6 struct {
+	plugin.Plugin
+}
+
+func (p *grpcProviderPluginV6) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return &grpcProviderV6{conn: c, ctx: ctx}, nil
+}
+
+func (p *grpcProviderPluginV6) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	return errors.New("serving providers is not supported")
+}
+
+// grpcProviderV6 is the client side of plugin protocol 6. The vendored terraform only ships protocol 5, so
+// messages of tfplugin6.proto used by terraformer are encoded with protowire; fields not known here are
+// skipped when decoding.
+type grpcProviderV6 struct {
+	conn *grpc.ClientConn
+	ctx  context.Context
+
+	mu     sync.Mutex
+	schema *providers.GetSchemaResponse
+}
+
+func (p *grpcProviderV6) invoke(method string, request []byte, options ...grpc.CallOption) ([]pbField, error) {
+	req := rawMessage(request)
+	var resp rawMessage
+	options = append(options, grpc.ForceCodec(rawCodec{}))
+	if err := p.conn.Invoke(p.ctx, "/tfplugin6.Provider/"+method, &req, &resp, options...); err != nil {
+		return nil, err
+	}
+	return pbParse(resp)
+}
+
+func (p *grpcProviderV6) GetSchema() providers.GetSchemaResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.schema != nil {
+		return *p.schema
+	}
+	resp := providers.GetSchemaResponse{
+		ResourceTypes: map[string]providers.Schema{},
+		DataSources:   map[string]providers.Schema{},
+	}
+	fields, err := p.invoke("GetProviderSchema", nil, grpc.MaxCallRecvMsgSize(maxRecvSize))
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			resp.Provider, err = decodeSchema(f.bytes)
+		case 2, 3:
+			var name string
+			var schema providers.Schema
+			name, schema, err = decodeSchemaEntry(f.bytes)
+			if f.num == 2 {
+				resp.ResourceTypes[name] = schema
+			} else {
+				resp.DataSources[name] = schema
+			}
+		case 4:
+			resp.Diagnostics, err = appendDiagnostic(resp.Diagnostics, f.bytes)
+		}
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+	}
+	if resp.Provider.Block == nil {
+		resp.Provider.Block = &configschema.Block{}
+	}
+	if !resp.Diagnostics.HasErrors() {
+		p.schema = &resp
+	}
+	return resp
+}
+
+// resourceType returns the type of resource state, the schema is cached by GetSchema
+func (p *grpcProviderV6) resourceType(typeName string) (cty.Type, error) {
+	schema := p.GetSchema()
+	if schema.Diagnostics.HasErrors() {
+		return cty.NilType, schema.Diagnostics.Err()
+	}
+	resourceSchema, exist := schema.ResourceTypes[typeName]
+	if !exist || resourceSchema.Block == nil {
+		return cty.NilType, fmt.Errorf("unknown resource type %s", typeName)
+	}
+	return resourceSchema.Block.ImpliedType(), nil
+}
+
+func (p *grpcProviderV6) Configure(r providers.ConfigureRequest) providers.ConfigureResponse {
+	resp := providers.ConfigureResponse{}
+	schema := p.GetSchema()
+	if schema.Diagnostics.HasErrors() {
+		resp.Diagnostics = schema.Diagnostics
+		return resp
+	}
+	config, err := encodeDynamicValue(r.Config, schema.Provider.Block.ImpliedType())
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	var req []byte
+	req = pbAppendString(req, 1, r.TerraformVersion)
+	req = pbAppendBytes(req, 2, config)
+	fields, err := p.invoke("ConfigureProvider", req)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	for _, f := range fields {
+		if f.num == 1 {
+			if resp.Diagnostics, err = appendDiagnostic(resp.Diagnostics, f.bytes); err != nil {
+				resp.Diagnostics = resp.Diagnostics.Append(err)
+			}
+		}
+	}
+	return resp
+}
+
+func (p *grpcProviderV6) ReadResource(r providers.ReadResourceRequest) providers.ReadResourceResponse {
+	resp := providers.ReadResourceResponse{}
+	ty, err := p.resourceType(r.TypeName)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	state, err := encodeDynamicValue(r.PriorState, ty)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	var req []byte
+	req = pbAppendString(req, 1, r.TypeName)
+	req = pbAppendBytes(req, 2, state)
+	req = pbAppendBytes(req, 3, r.Private)
+	fields, err := p.invoke("ReadResource", req)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	resp.NewState = cty.NullVal(ty)
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			resp.NewState, err = decodeDynamicValue(f.bytes, ty)
+		case 2:
+			resp.Diagnostics, err = appendDiagnostic(resp.Diagnostics, f.bytes)
+		case 3:
+			resp.Private = f.bytes
+		}
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+	}
+	return resp
+}
+
+func (p *grpcProviderV6) ImportResourceState(r providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	resp := providers.ImportResourceStateResponse{}
+	var req []byte
+	req = pbAppendString(req, 1, r.TypeName)
+	req = pbAppendString(req, 2, r.ID)
+	fields, err := p.invoke("ImportResourceState", req)
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+		return resp
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			var imported providers.ImportedResource
+			imported, err = p.decodeImportedResource(f.bytes)
+			resp.ImportedResources = append(resp.ImportedResources, imported)
+		case 2:
+			resp.Diagnostics, err = appendDiagnostic(resp.Diagnostics, f.bytes)
+		}
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			return resp
+		}
+	}
+	return resp
+}
+
+func (p *grpcProviderV6) decodeImportedResource(b []byte) (providers.ImportedResource, error) {
+	imported := providers.ImportedResource{}
+	fields, err := pbParse(b)
+	if err != nil {
+		return imported, err
+	}
+	var state []byte
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			imported.TypeName = string(f.bytes)
+		case 2:
+			state = f.bytes
+		case 3:
+			imported.Private = f.bytes
+		}
+	}
+	ty, err := p.resourceType(imported.TypeName)
+	if err != nil {
+		return imported, err
+	}
+	imported.State, err = decodeDynamicValue(state, ty)
+	return imported, err
+}
+
+func decodeSchemaEntry(b []byte) (string, providers.Schema, error) {
+	fields, err := pbParse(b)
+	if err != nil {
+		return "", providers.Schema{}, err
+	}
+	var name string
+	schema := providers.Schema{Block: &configschema.Block{}}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			name = string(f.bytes)
+		case 2:
+			if schema, err = decodeSchema(f.bytes); err != nil {
+				return "", schema, err
+			}
+		}
+	}
+	return name, schema, nil
+}
+
+func decodeSchema(b []byte) (providers.Schema, error) {
+	schema := providers.Schema{Block: &configschema.Block{}}
+	fields, err := pbParse(b)
+	if err != nil {
+		return schema, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			schema.Version = int64(f.varint)
+		case 2:
+			if schema.Block, err = decodeBlock(f.bytes); err != nil {
+				return schema, err
+			}
+		}
+	}
+	return schema, nil
+}
+
+func decodeBlock(b []byte) (*configschema.Block, error) {
+	block := &configschema.Block{
+		Attributes: map[string]*configschema.Attribute{},
+		BlockTypes: map[string]*configschema.NestedBlock{},
+	}
+	fields, err := pbParse(b)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 2:
+			name, attribute, err := decodeAttribute(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			block.Attributes[name] = attribute
+		case 3:
+			name, nested, err := decodeNestedBlock(f.bytes)
+			if err != nil {
+				return nil, err
+			}
+			block.BlockTypes[name] = nested
+		}
+	}
+	return block, nil
+}
+
+// decodeAttribute returns attributes with nested types as attributes of object types, configschema of the
+// vendored terraform has no nested attributes
+func decodeAttribute(b []byte) (string, *configschema.Attribute, error) {
+	attribute := &configschema.Attribute{}
+	fields, err := pbParse(b)
+	if err != nil {
+		return "", nil, err
+	}
+	var name string
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			name = string(f.bytes)
+		case 2:
+			if err := attribute.Type.UnmarshalJSON(f.bytes); err != nil {
+				return "", nil, fmt.Errorf("invalid type of attribute %s: %v", name, err)
+			}
+		case 10:
+			if attribute.Type, err = decodeNestedType(f.bytes); err != nil {
+				return "", nil, err
+			}
+		case 3:
+			attribute.Description = string(f.bytes)
+		case 4:
+			attribute.Required = f.varint != 0
+		case 5:
+			attribute.Optional = f.varint != 0
+		case 6:
+			attribute.Computed = f.varint != 0
+		case 7:
+			attribute.Sensitive = f.varint != 0
+		}
+	}
+	return name, attribute, nil
+}
+
+func decodeNestedType(b []byte) (cty.Type, error) {
+	fields, err := pbParse(b)
+	if err != nil {
+		return cty.NilType, err
+	}
+	attributes := map[string]cty.Type{}
+	nesting := uint64(0)
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			name, attribute, err := decodeAttribute(f.bytes)
+			if err != nil {
+				return cty.NilType, err
+			}
+			attributes[name] = attribute.Type
+		case 3:
+			nesting = f.varint
+		}
+	}
+	object := cty.Object(attributes)
+	switch nesting {
+	case 1:
+		return object, nil
+	case 2:
+		return cty.List(object), nil
+	case 3:
+		return cty.Set(object), nil
+	case 4:
+		return cty.Map(object), nil
+	}
+	return cty.NilType, fmt.Errorf("unsupported nesting of nested attribute: %d", nesting)
+}
+
+func decodeNestedBlock(b []byte) (string, *configschema.NestedBlock, error) {
+	nested := &configschema.NestedBlock{}
+	fields, err := pbParse(b)
+	if err != nil {
+		return "", nil, err
+	}
+	var name string
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			name = string(f.bytes)
+		case 2:
+			block, err := decodeBlock(f.bytes)
+			if err != nil {
+				return "", nil, err
+			}
+			nested.Block = *block
+		case 3:
+			switch f.varint {
+			case 1:
+				nested.Nesting = configschema.NestingSingle
+			case 2:
+				nested.Nesting = configschema.NestingList
+			case 3:
+				nested.Nesting = configschema.NestingSet
+			case 4:
+				nested.Nesting = configschema.NestingMap
+			case 5:
+				nested.Nesting = configschema.NestingGroup
+			default:
+				return "", nil, fmt.Errorf("unsupported nesting of block %s: %d", name, f.varint)
+			}
+		case 4:
+			nested.MinItems = int(f.varint)
+		case 5:
+			nested.MaxItems = int(f.varint)
+		}
+	}
+	return name, nested, nil
+}
+
+func appendDiagnostic(diags tfdiags.Diagnostics, b []byte) (tfdiags.Diagnostics, error) {
+	fields, err := pbParse(b)
+	if err != nil {
+		return diags, err
+	}
+	severity := tfdiags.Error
+	var summary, detail string
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			if f.varint == 2 {
+				severity = tfdiags.Warning
+			}
+		case 2:
+			summary = string(f.bytes)
+		case 3:
+			detail = string(f.bytes)
+		}
+	}
+	return diags.Append(tfdiags.Sourceless(severity, summary, detail)), nil
+}
+
+// encodeDynamicValue returns a DynamicValue message with the msgpack encoded value
+func encodeDynamicValue(value cty.Value, ty cty.Type) ([]byte, error) {
+	b, err := msgpack.Marshal(value, ty)
+	if err != nil {
+		return nil, err
+	}
+	return pbAppendBytes(nil, 1, b), nil
+}
+
+func decodeDynamicValue(b []byte, ty cty.Type) (cty.Value, error) {
+	fields, err := pbParse(b)
+	if err != nil {
+		return cty.NilVal, err
+	}
+	for _, f := range fields {
+		switch f.num {
+		case 1:
+			return msgpack.Unmarshal(f.bytes, ty)
+		case 2:
+			return ctyjson.Unmarshal(f.bytes, ty)
+		}
+	}
+	return cty.NullVal(ty), nil
+}
+
+// pbField is a field of a protobuf message, bytes are set for strings, bytes and messages
+type pbField struct {
+	num    protowire.Number
+	varint uint64
+	bytes  []byte
+}
+
+func pbParse(b []byte) ([]pbField, error) {
+	var fields []pbField
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		f := pbField{num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func pbAppendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func pbAppendBytes(b []byte, num protowire.Number, value []byte) []byte {
+	if len(value) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, value)
+}
+
+func pbAppendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// rawMessage is an encoded protobuf message, sent and received as is by rawCodec
+type rawMessage []byte
+
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *v.(*rawMessage), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*rawMessage) = append(rawMessage{}, data...)
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
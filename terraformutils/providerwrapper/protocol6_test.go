@@ -0,0 +1,219 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerwrapper
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/tfdiags"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/msgpack"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var testThingType = cty.Object(map[string]cty.Type{
+	"id":       cty.String,
+	"password": cty.String,
+	"rules":    cty.List(cty.Object(map[string]cty.Type{"port": cty.Number})),
+	"config":   cty.List(cty.Object(map[string]cty.Type{"enabled": cty.Bool})),
+})
+
+func pbMessage(num protowire.Number, fields ...[]byte) []byte {
+	var b []byte
+	for _, f := range fields {
+		b = append(b, f...)
+	}
+	return pbAppendBytes(nil, num, b)
+}
+
+func testSchemaV6() []byte {
+	attribute := func(name string, fields ...[]byte) []byte {
+		return pbMessage(2, append([][]byte{pbAppendString(nil, 1, name)}, fields...)...)
+	}
+	rules := pbMessage(10,
+		pbMessage(1, pbAppendString(nil, 1, "port"), pbAppendString(nil, 2, `"number"`), pbAppendVarint(nil, 5, 1)),
+		pbAppendVarint(nil, 3, 2))
+	config := pbMessage(3,
+		pbAppendString(nil, 1, "config"),
+		pbMessage(2, attribute("enabled", pbAppendString(nil, 2, `"bool"`), pbAppendVarint(nil, 5, 1))),
+		pbAppendVarint(nil, 3, 2),
+		pbAppendVarint(nil, 5, 1))
+	thing := pbMessage(2,
+		pbAppendVarint(nil, 1, 2),
+		pbMessage(2,
+			attribute("id", pbAppendString(nil, 2, `"string"`), pbAppendVarint(nil, 6, 1)),
+			attribute("password", pbAppendString(nil, 2, `"string"`), pbAppendVarint(nil, 5, 1), pbAppendVarint(nil, 7, 1)),
+			attribute("rules", rules, pbAppendVarint(nil, 5, 1)),
+			config))
+	provider := pbMessage(1, pbMessage(2, attribute("region", pbAppendString(nil, 2, `"string"`), pbAppendVarint(nil, 4, 1))))
+	return append(provider, pbMessage(2, pbAppendString(nil, 1, "test_thing"), thing)...)
+}
+
+func testDiagnostic(severity uint64, summary string) []byte {
+	return pbAppendString(pbAppendVarint(nil, 1, severity), 2, summary)
+}
+
+// testProviderV6 serves tfplugin6.Provider methods used by terraformer
+func testProviderV6(t *testing.T) (*grpcProviderV6, func()) {
+	handlers := map[string]func(req []pbField) []byte{
+		"GetProviderSchema": func(req []pbField) []byte {
+			return testSchemaV6()
+		},
+		"ConfigureProvider": func(req []pbField) []byte {
+			if len(req) != 2 || string(req[0].bytes) != "0.12.31" {
+				return pbAppendBytes(nil, 1, testDiagnostic(1, "unexpected request"))
+			}
+			return pbAppendBytes(nil, 1, testDiagnostic(2, "deprecated region"))
+		},
+		"ReadResource": func(req []pbField) []byte {
+			if string(req[0].bytes) != "test_thing" {
+				return pbAppendBytes(nil, 2, testDiagnostic(1, "unknown type"))
+			}
+			var state, private []byte
+			for _, f := range req[1:] {
+				if f.num == 2 {
+					state = f.bytes
+				} else if f.num == 3 {
+					private = f.bytes
+				}
+			}
+			return append(pbAppendBytes(nil, 1, state), pbAppendBytes(nil, 3, append(private, '!'))...)
+		},
+		"ImportResourceState": func(req []pbField) []byte {
+			state, _ := msgpack.Marshal(cty.ObjectVal(map[string]cty.Value{
+				"id":       cty.StringVal(string(req[1].bytes)),
+				"password": cty.NullVal(cty.String),
+				"rules":    cty.ListVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"port": cty.NumberIntVal(443)})}),
+				"config":   cty.ListValEmpty(cty.Object(map[string]cty.Type{"enabled": cty.Bool})),
+			}), testThingType)
+			return pbMessage(1,
+				pbAppendString(nil, 1, string(req[0].bytes)),
+				pbMessage(2, pbAppendBytes(nil, 1, state)),
+				pbAppendBytes(nil, 3, []byte("private")))
+		},
+	}
+	desc := grpc.ServiceDesc{ServiceName: "tfplugin6.Provider", HandlerType: (*interface{})(nil)}
+	for name, handler := range handlers {
+		handler := handler
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: name,
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				var req rawMessage
+				if err := dec(&req); err != nil {
+					return nil, err
+				}
+				fields, err := pbParse(req)
+				if err != nil {
+					return nil, err
+				}
+				resp := rawMessage(handler(fields))
+				return &resp, nil
+			},
+		})
+	}
+	server := grpc.NewServer(grpc.ForceServerCodec(rawCodec{}))
+	server.RegisterService(&desc, struct{}{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener) //nolint
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &grpcProviderV6{conn: conn, ctx: context.Background()}, func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestProtocol6Schema(t *testing.T) {
+	p, stop := testProviderV6(t)
+	defer stop()
+
+	schema := p.GetSchema()
+	if schema.Diagnostics.HasErrors() {
+		t.Fatal(schema.Diagnostics.Err())
+	}
+	if region := schema.Provider.Block.Attributes["region"]; region == nil || !region.Required {
+		t.Errorf("expected a required region in provider schema, got %v", schema.Provider.Block.Attributes)
+	}
+	thing, exist := schema.ResourceTypes["test_thing"]
+	if !exist {
+		t.Fatalf("expected test_thing resource, got %v", schema.ResourceTypes)
+	}
+	if thing.Version != 2 {
+		t.Errorf("expected schema version 2, got %d", thing.Version)
+	}
+	if !thing.Block.Attributes["password"].Sensitive || !thing.Block.Attributes["id"].Computed {
+		t.Errorf("unexpected attributes %v", thing.Block.Attributes)
+	}
+	config := thing.Block.BlockTypes["config"]
+	if config == nil || config.Nesting != configschema.NestingList || config.MaxItems != 1 {
+		t.Errorf("unexpected config block %v", config)
+	}
+	if ty := thing.Block.ImpliedType(); !ty.Equals(testThingType) {
+		t.Errorf("expected type %s, got %s", testThingType.FriendlyName(), ty.FriendlyName())
+	}
+}
+
+func TestProtocol6Resources(t *testing.T) {
+	p, stop := testProviderV6(t)
+	defer stop()
+
+	configure := p.Configure(providers.ConfigureRequest{
+		TerraformVersion: "0.12.31",
+		Config:           cty.ObjectVal(map[string]cty.Value{"region": cty.StringVal("eu-west-1")}),
+	})
+	if configure.Diagnostics.HasErrors() || len(configure.Diagnostics) != 1 || configure.Diagnostics[0].Severity() != tfdiags.Warning {
+		t.Errorf("expected a warning, got %v", configure.Diagnostics)
+	}
+
+	imported := p.ImportResourceState(providers.ImportResourceStateRequest{TypeName: "test_thing", ID: "thing-1"})
+	if imported.Diagnostics.HasErrors() {
+		t.Fatal(imported.Diagnostics.Err())
+	}
+	if len(imported.ImportedResources) != 1 {
+		t.Fatalf("expected one imported resource, got %v", imported.ImportedResources)
+	}
+	resource := imported.ImportedResources[0]
+	if resource.TypeName != "test_thing" || resource.State.GetAttr("id").AsString() != "thing-1" {
+		t.Errorf("unexpected imported resource %v", resource)
+	}
+
+	read := p.ReadResource(providers.ReadResourceRequest{
+		TypeName:   resource.TypeName,
+		PriorState: resource.State,
+		Private:    resource.Private,
+	})
+	if read.Diagnostics.HasErrors() {
+		t.Fatal(read.Diagnostics.Err())
+	}
+	if !read.NewState.RawEquals(resource.State) || string(read.Private) != "private!" {
+		t.Errorf("expected state %#v, got %#v", resource.State, read.NewState)
+	}
+
+	unknown := p.ReadResource(providers.ReadResourceRequest{TypeName: "test_other"})
+	if !unknown.Diagnostics.HasErrors() {
+		t.Errorf("expected an error for an unknown resource type")
+	}
+}
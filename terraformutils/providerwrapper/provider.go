@@ -17,14 +17,15 @@ package providerwrapper //nolint
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"runtime"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils/terraformerstring"
 
 	"github.com/zclconf/go-cty/cty"
@@ -49,15 +50,42 @@ const DefaultPluginVendorDirV12 = "terraform.d/plugins/" + pluginMachineName
 // pluginMachineName is the directory name used in new plugin paths.
 const pluginMachineName = runtime.GOOS + "_" + runtime.GOARCH
 
+// PluginProvider is the part of a provider plugin used by terraformer, served by providers speaking plugin
+// protocol 5 or 6
+type PluginProvider interface {
+	GetSchema() providers.GetSchemaResponse
+	Configure(providers.ConfigureRequest) providers.ConfigureResponse
+	ReadResource(providers.ReadResourceRequest) providers.ReadResourceResponse
+	ImportResourceState(providers.ImportResourceStateRequest) providers.ImportResourceStateResponse
+}
+
 type ProviderWrapper struct {
-	Provider     *tfplugin.GRPCProvider
+	Provider     PluginProvider
 	client       *plugin.Client
 	rpcClient    plugin.ClientProtocol
 	providerName string
 	config       cty.Value
 	schema       *providers.GetSchemaResponse
+	schemaLock   sync.Mutex
 	retryCount   int
 	retrySleepMs int
+	retries      int64
+}
+
+// ReadError is returned by Refresh when a resource can't be read after all retries nor imported
+type ReadError struct {
+	Type    string
+	ID      string
+	Retries int
+	Err     error
+}
+
+func (e *ReadError) Error() string {
+	return fmt.Sprintf("failed to read %s %s after %d retries: %v", e.Type, e.ID, e.Retries, e.Err)
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
 }
 
 func NewProviderWrapper(providerName string, providerConfig cty.Value, verbose bool, options ...map[string]int) (*ProviderWrapper, error) {
@@ -82,10 +110,20 @@ func NewProviderWrapper(providerName string, providerConfig cty.Value, verbose b
 }
 
 func (p *ProviderWrapper) Kill() {
-	p.client.Kill()
+	if recording, ok := p.Provider.(*recordingProvider); ok {
+		if err := recording.Save(); err != nil {
+			log.Println(err)
+		}
+	}
+	if p.client != nil {
+		p.client.Kill()
+	}
 }
 
 func (p *ProviderWrapper) GetSchema() *providers.GetSchemaResponse {
+	// services are initialized concurrently
+	p.schemaLock.Lock()
+	defer p.schemaLock.Unlock()
 	if p.schema == nil {
 		r := p.Provider.GetSchema()
 		p.schema = &r
@@ -158,6 +196,11 @@ func (p *ProviderWrapper) readObjBlocks(block map[string]*configschema.NestedBlo
 	return readOnlyAttributes
 }
 
+// Retries returns the number of read attempts retried by Refresh so far
+func (p *ProviderWrapper) Retries() int {
+	return int(atomic.LoadInt64(&p.retries))
+}
+
 func (p *ProviderWrapper) Refresh(info *terraform.InstanceInfo, state *terraform.InstanceState) (*terraform.InstanceState, error) {
 	schema := p.GetSchema()
 	impliedType := schema.ResourceTypes[info.Type].Block.ImpliedType()
@@ -166,8 +209,13 @@ func (p *ProviderWrapper) Refresh(info *terraform.InstanceInfo, state *terraform
 		return nil, err
 	}
 	successReadResource := false
+	retries := 0
 	resp := providers.ReadResourceResponse{}
 	for i := 0; i < p.retryCount; i++ {
+		if i > 0 {
+			retries++
+			atomic.AddInt64(&p.retries, 1)
+		}
 		resp = p.Provider.ReadResource(providers.ReadResourceRequest{
 			TypeName:   info.Type,
 			PriorState: priorState,
@@ -192,10 +240,10 @@ func (p *ProviderWrapper) Refresh(info *terraform.InstanceInfo, state *terraform
 			ID:       state.ID,
 		})
 		if importResponse.Diagnostics.HasErrors() {
-			return nil, resp.Diagnostics.Err()
+			return nil, &ReadError{Type: info.Type, ID: state.ID, Retries: retries, Err: resp.Diagnostics.Err()}
 		}
 		if len(importResponse.ImportedResources) == 0 {
-			return nil, errors.New("not able to import resource for a given ID")
+			return nil, &ReadError{Type: info.Type, ID: state.ID, Retries: retries, Err: errors.New("not able to import resource for a given ID")}
 		}
 		return terraform.NewInstanceStateShimmedFromValue(importResponse.ImportedResources[0].State, int(schema.ResourceTypes[info.Type].Version)), nil
 	}
@@ -209,7 +257,21 @@ func (p *ProviderWrapper) Refresh(info *terraform.InstanceInfo, state *terraform
 }
 
 func (p *ProviderWrapper) initProvider(verbose bool) error {
-	providerFilePath, err := getProviderFileName(p.providerName)
+	if recorder.Replaying() {
+		provider, err := newReplayProvider(p.providerName)
+		if err != nil {
+			return err
+		}
+		p.Provider = provider
+		// the recorded schema still validates the provider config
+		if block := provider.GetSchema().Provider.Block; block != nil {
+			if _, err := CoerceConfig(block, p.config); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	pkg, err := FindProvider(p.providerName)
 	if err != nil {
 		return err
 	}
@@ -224,9 +286,9 @@ func (p *ProviderWrapper) initProvider(verbose bool) error {
 	logger := hclog.New(&options)
 	p.client = plugin.NewClient(
 		&plugin.ClientConfig{
-			Cmd:              exec.Command(providerFilePath),
+			Cmd:              exec.Command(pkg.Executable),
 			HandshakeConfig:  tfplugin.Handshake,
-			VersionedPlugins: tfplugin.VersionedPlugins,
+			VersionedPlugins: versionedPlugins,
 			Managed:          true,
 			Logger:           logger,
 			AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
@@ -241,9 +303,15 @@ func (p *ProviderWrapper) initProvider(verbose bool) error {
 		return err
 	}
 
-	p.Provider = raw.(*tfplugin.GRPCProvider)
+	p.Provider = raw.(PluginProvider)
+	if recorder.Mode() == recorder.ModeRecord {
+		p.Provider = newRecordingProvider(p.Provider, p.providerName, pkg)
+	}
+	if verbose {
+		log.Printf("%s provider %s serves plugin protocol %d", p.providerName, pkg.Executable, p.client.NegotiatedVersion())
+	}
 
-	config, err := p.GetSchema().Provider.Block.CoerceValue(p.config)
+	config, err := CoerceConfig(p.GetSchema().Provider.Block, p.config)
 	if err != nil {
 		return err
 	}
@@ -255,104 +323,16 @@ func (p *ProviderWrapper) initProvider(verbose bool) error {
 	return nil
 }
 
-func getProviderFileName(providerName string) (string, error) {
-	defaultDataDir := os.Getenv("TF_DATA_DIR")
-	if defaultDataDir == "" {
-		defaultDataDir = DefaultDataDir
-	}
-	providerFilePath, err := getProviderFileNameV13andV14(defaultDataDir, providerName)
-	if err != nil || providerFilePath == "" {
-		providerFilePath, err = getProviderFileNameV13andV14(os.Getenv("HOME")+string(os.PathSeparator)+
-			".terraform.d", providerName)
-	}
-	if err != nil || providerFilePath == "" {
-		return getProviderFileNameV12(providerName)
-	}
-	return providerFilePath, nil
-}
-
-func getProviderFileNameV13andV14(prefix, providerName string) (string, error) {
-	// Read terraform v14 file path
-	registryDir := prefix + string(os.PathSeparator) + "providers" + string(os.PathSeparator) +
-		"registry.terraform.io"
-	providerDirs, err := ioutil.ReadDir(registryDir)
-	if err != nil {
-		// Read terraform v13 file path
-		registryDir = prefix + string(os.PathSeparator) + "plugins" + string(os.PathSeparator) +
-			"registry.terraform.io"
-		providerDirs, err = ioutil.ReadDir(registryDir)
-		if err != nil {
-			return "", err
-		}
-	}
-	providerFilePath := ""
-	for _, providerDir := range providerDirs {
-		pluginPath := registryDir + string(os.PathSeparator) + providerDir.Name() +
-			string(os.PathSeparator) + providerName
-		dirs, err := ioutil.ReadDir(pluginPath)
-		if err != nil {
-			continue
-		}
-		for _, dir := range dirs {
-			if !dir.IsDir() {
-				continue
-			}
-			for _, dir := range dirs {
-				fullPluginPath := pluginPath + string(os.PathSeparator) + dir.Name() +
-					string(os.PathSeparator) + runtime.GOOS + "_" + runtime.GOARCH
-				files, err := ioutil.ReadDir(fullPluginPath)
-				if err == nil {
-					for _, file := range files {
-						if strings.HasPrefix(file.Name(), "terraform-provider-"+providerName) {
-							providerFilePath = fullPluginPath + string(os.PathSeparator) + file.Name()
-						}
-					}
-				}
-			}
-		}
-	}
-	return providerFilePath, nil
-}
-
-func getProviderFileNameV12(providerName string) (string, error) {
-	defaultDataDir := os.Getenv("TF_DATA_DIR")
-	if defaultDataDir == "" {
-		defaultDataDir = DefaultDataDir
-	}
-	pluginPath := defaultDataDir + string(os.PathSeparator) + "plugins" + string(os.PathSeparator) + runtime.GOOS + "_" + runtime.GOARCH
-	files, err := ioutil.ReadDir(pluginPath)
-	if err != nil {
-		pluginPath = os.Getenv("HOME") + string(os.PathSeparator) + "." + DefaultPluginVendorDirV12
-		files, err = ioutil.ReadDir(pluginPath)
-		if err != nil {
-			return "", err
-		}
-	}
-	providerFilePath := ""
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		if strings.HasPrefix(file.Name(), "terraform-provider-"+providerName) {
-			providerFilePath = pluginPath + string(os.PathSeparator) + file.Name()
-		}
-	}
-	return providerFilePath, nil
-}
-
+// GetProviderVersion returns the version of the provider binary used, empty when it's unknown
 func GetProviderVersion(providerName string) string {
-	providerFilePath, err := getProviderFileName(providerName)
+	pkg, err := FindProvider(providerName)
 	if err != nil {
-		log.Println("Can't find provider file path. Ensure that you are following https://www.terraform.io/docs/configuration/providers.html#third-party-plugins.")
+		log.Println(err)
 		return ""
 	}
-	t := strings.Split(providerFilePath, string(os.PathSeparator))
-	providerFileName := t[len(t)-1]
-	providerFileNameParts := strings.Split(providerFileName, "_")
-	if len(providerFileNameParts) < 2 {
+	if pkg.Version == nil {
 		log.Println("Can't find provider version. Ensure that you are following https://www.terraform.io/docs/configuration/providers.html#plugin-names-and-versions.")
 		return ""
 	}
-	providerVersion := providerFileNameParts[1]
-	return "~> " + strings.TrimPrefix(providerVersion, "v")
+	return pkg.Version.String()
 }
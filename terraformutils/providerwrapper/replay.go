@@ -0,0 +1,313 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerwrapper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// providerCassette holds provider calls of a run, schemas are kept for resource types read or imported only
+type providerCassette struct {
+	Version         string                      `json:"version,omitempty"`
+	Provider        providers.Schema            `json:"provider"`
+	ResourceSchemas map[string]providers.Schema `json:"resource_schemas"`
+	Reads           map[string]*recordedRead    `json:"reads"`
+	Imports         map[string]*recordedImport  `json:"imports"`
+
+	mu sync.Mutex
+}
+
+type recordedRead struct {
+	State   json.RawMessage `json:"state,omitempty"`
+	Private []byte          `json:"private,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+type recordedImport struct {
+	Resources []recordedResource `json:"resources,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+type recordedResource struct {
+	TypeName string          `json:"type_name"`
+	State    json.RawMessage `json:"state"`
+	Private  []byte          `json:"private,omitempty"`
+}
+
+func providerCassettePath(providerName string) string {
+	return filepath.Join(recorder.Dir(), "provider-"+providerName+".json")
+}
+
+func loadProviderCassette(providerName string) (*providerCassette, error) {
+	content, err := ioutil.ReadFile(providerCassettePath(providerName))
+	if err != nil {
+		return nil, err
+	}
+	c := &providerCassette{}
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, fmt.Errorf("invalid provider cassette %s: %v", providerCassettePath(providerName), err)
+	}
+	return c, nil
+}
+
+// replayedPackage is the provider package of a replayed run, it has no executable
+func replayedPackage(providerName string) (*ProviderPackage, error) {
+	c, err := loadProviderCassette(providerName)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &ProviderPackage{}
+	if c.Version != "" {
+		if pkg.Version, err = version.NewVersion(c.Version); err != nil {
+			return nil, err
+		}
+	}
+	return pkg, nil
+}
+
+// readKey identifies a read by the id of the prior state, by the whole prior state for resources without id
+func readKey(typeName string, state cty.Value) string {
+	if state.Type().IsObjectType() && state.Type().HasAttribute("id") && !state.IsNull() {
+		id := state.GetAttr("id")
+		if id.IsKnown() && !id.IsNull() && id.Type() == cty.String {
+			return typeName + "/" + id.AsString()
+		}
+	}
+	b, _ := ctyjson.Marshal(state, state.Type())
+	return typeName + "/" + string(b)
+}
+
+// recordingCassettes are the provider cassettes recorded by path, imports of a command record into the same
+// cassette, e.g. aws imports each region apart
+var recordingCassettes = map[string]*providerCassette{}
+var recordingCassettesLock sync.Mutex
+
+func recordingCassette(path string) *providerCassette {
+	recordingCassettesLock.Lock()
+	defer recordingCassettesLock.Unlock()
+	c, exist := recordingCassettes[path]
+	if !exist {
+		c = &providerCassette{
+			ResourceSchemas: map[string]providers.Schema{},
+			Reads:           map[string]*recordedRead{},
+			Imports:         map[string]*recordedImport{},
+		}
+		recordingCassettes[path] = c
+	}
+	return c
+}
+
+// recordingProvider records calls of the provider for replayProvider
+type recordingProvider struct {
+	PluginProvider
+	path     string
+	mu       sync.Mutex
+	cassette *providerCassette
+	schema   *providers.GetSchemaResponse
+}
+
+func newRecordingProvider(provider PluginProvider, providerName string, pkg *ProviderPackage) *recordingProvider {
+	path := providerCassettePath(providerName)
+	p := &recordingProvider{
+		PluginProvider: provider,
+		path:           path,
+		cassette:       recordingCassette(path),
+	}
+	if pkg.Version != nil {
+		p.cassette.mu.Lock()
+		p.cassette.Version = pkg.Version.String()
+		p.cassette.mu.Unlock()
+	}
+	return p
+}
+
+func (p *recordingProvider) GetSchema() providers.GetSchemaResponse {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.schema == nil {
+		schema := p.PluginProvider.GetSchema()
+		p.schema = &schema
+		p.cassette.mu.Lock()
+		p.cassette.Provider = schema.Provider
+		p.cassette.mu.Unlock()
+	}
+	return *p.schema
+}
+
+func (p *recordingProvider) addSchema(typeName string) (cty.Type, bool) {
+	schema, exist := p.GetSchema().ResourceTypes[typeName]
+	if !exist || schema.Block == nil {
+		return cty.NilType, false
+	}
+	p.cassette.mu.Lock()
+	p.cassette.ResourceSchemas[typeName] = schema
+	p.cassette.mu.Unlock()
+	return schema.Block.ImpliedType(), true
+}
+
+func (p *recordingProvider) ReadResource(r providers.ReadResourceRequest) providers.ReadResourceResponse {
+	resp := p.PluginProvider.ReadResource(r)
+	ty, exist := p.addSchema(r.TypeName)
+	if !exist {
+		return resp
+	}
+	read := &recordedRead{Private: resp.Private}
+	if resp.Diagnostics.HasErrors() {
+		read.Error = resp.Diagnostics.Err().Error()
+	} else if state, err := ctyjson.Marshal(resp.NewState, ty); err == nil {
+		read.State = state
+	}
+	p.cassette.mu.Lock()
+	p.cassette.Reads[readKey(r.TypeName, r.PriorState)] = read
+	p.cassette.mu.Unlock()
+	return resp
+}
+
+func (p *recordingProvider) ImportResourceState(r providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	resp := p.PluginProvider.ImportResourceState(r)
+	imported := &recordedImport{}
+	if resp.Diagnostics.HasErrors() {
+		imported.Error = resp.Diagnostics.Err().Error()
+	}
+	for _, resource := range resp.ImportedResources {
+		ty, exist := p.addSchema(resource.TypeName)
+		if !exist {
+			continue
+		}
+		state, err := ctyjson.Marshal(resource.State, ty)
+		if err != nil {
+			continue
+		}
+		imported.Resources = append(imported.Resources, recordedResource{TypeName: resource.TypeName, State: state, Private: resource.Private})
+	}
+	p.cassette.mu.Lock()
+	p.cassette.Imports[r.TypeName+"/"+r.ID] = imported
+	p.cassette.mu.Unlock()
+	return resp
+}
+
+// Save writes the provider cassette with calls of all imports recorded to it so far
+func (p *recordingProvider) Save() error {
+	p.cassette.mu.Lock()
+	defer p.cassette.mu.Unlock()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(p.cassette); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(p.path, buf.Bytes(), os.ModePerm)
+}
+
+// replayProvider answers calls from a provider cassette, it stands in for the provider binary
+type replayProvider struct {
+	cassette *providerCassette
+}
+
+func newReplayProvider(providerName string) (*replayProvider, error) {
+	c, err := loadProviderCassette(providerName)
+	if err != nil {
+		return nil, err
+	}
+	return &replayProvider{cassette: c}, nil
+}
+
+func (p *replayProvider) GetSchema() providers.GetSchemaResponse {
+	return providers.GetSchemaResponse{
+		Provider:      p.cassette.Provider,
+		ResourceTypes: p.cassette.ResourceSchemas,
+		DataSources:   map[string]providers.Schema{},
+	}
+}
+
+func (p *replayProvider) Configure(providers.ConfigureRequest) providers.ConfigureResponse {
+	return providers.ConfigureResponse{}
+}
+
+func (p *replayProvider) resourceType(typeName string) (cty.Type, error) {
+	schema, exist := p.cassette.ResourceSchemas[typeName]
+	if !exist || schema.Block == nil {
+		return cty.NilType, fmt.Errorf("no recorded schema of %s", typeName)
+	}
+	return schema.Block.ImpliedType(), nil
+}
+
+func (p *replayProvider) ReadResource(r providers.ReadResourceRequest) providers.ReadResourceResponse {
+	resp := providers.ReadResourceResponse{}
+	key := readKey(r.TypeName, r.PriorState)
+	read, exist := p.cassette.Reads[key]
+	if !exist {
+		resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("no recorded read of %s", key))
+		return resp
+	}
+	if read.Error != "" {
+		resp.Diagnostics = resp.Diagnostics.Append(errors.New(read.Error))
+		return resp
+	}
+	ty, err := p.resourceType(r.TypeName)
+	if err == nil {
+		resp.NewState, err = ctyjson.Unmarshal(read.State, ty)
+	}
+	if err != nil {
+		resp.Diagnostics = resp.Diagnostics.Append(err)
+	}
+	resp.Private = read.Private
+	return resp
+}
+
+func (p *replayProvider) ImportResourceState(r providers.ImportResourceStateRequest) providers.ImportResourceStateResponse {
+	resp := providers.ImportResourceStateResponse{}
+	imported, exist := p.cassette.Imports[r.TypeName+"/"+r.ID]
+	if !exist {
+		resp.Diagnostics = resp.Diagnostics.Append(fmt.Errorf("no recorded import of %s/%s", r.TypeName, r.ID))
+		return resp
+	}
+	if imported.Error != "" {
+		resp.Diagnostics = resp.Diagnostics.Append(errors.New(imported.Error))
+	}
+	for _, resource := range imported.Resources {
+		ty, err := p.resourceType(resource.TypeName)
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			continue
+		}
+		state, err := ctyjson.Unmarshal(resource.State, ty)
+		if err != nil {
+			resp.Diagnostics = resp.Diagnostics.Append(err)
+			continue
+		}
+		resp.ImportedResources = append(resp.ImportedResources, providers.ImportedResource{
+			TypeName: resource.TypeName,
+			State:    state,
+			Private:  resource.Private,
+		})
+	}
+	return resp
+}
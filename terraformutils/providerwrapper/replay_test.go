@@ -0,0 +1,141 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package providerwrapper
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/recorder"
+
+	"github.com/hashicorp/terraform/terraform"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestRecordReplayProvider(t *testing.T) {
+	dir := t.TempDir()
+	if err := recorder.Start(recorder.ModeRecord, dir); err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Stop() //nolint
+
+	// record refreshes served by a fake provider
+	fake, stop := testProviderV6(t)
+	defer stop()
+	recording := &ProviderWrapper{
+		Provider:   newRecordingProvider(fake, "test", &ProviderPackage{}),
+		retryCount: 1,
+	}
+	info := &terraform.InstanceInfo{Type: "test_thing", Id: "thing-1"}
+	state := &terraform.InstanceState{ID: "thing-1", Attributes: map[string]string{"id": "thing-1", "password": "secret"}}
+	recorded, err := recording.Refresh(info, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recording.Kill()
+	if err := recorder.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	// replay them without provider
+	if err := recorder.Start(recorder.ModeReplay, dir); err != nil {
+		t.Fatal(err)
+	}
+	replaying, err := NewProviderWrapper("test", cty.NilVal, false, map[string]int{"retryCount": 1, "retrySleepMs": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replaying.Kill()
+	replayed, err := replaying.Refresh(info, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(replayed.Attributes, recorded.Attributes) || replayed.Attributes["password"] != "secret" {
+		t.Errorf("expected state %v, got %v", recorded.Attributes, replayed.Attributes)
+	}
+	if _, exist := replaying.GetSchema().ResourceTypes["test_thing"]; !exist {
+		t.Errorf("expected the recorded schema of test_thing")
+	}
+
+	_, err = replaying.Refresh(&terraform.InstanceInfo{Type: "test_thing", Id: "thing-2"}, &terraform.InstanceState{ID: "thing-2", Attributes: map[string]string{"id": "thing-2"}})
+	var readError *ReadError
+	if !errors.As(err, &readError) {
+		t.Errorf("expected a read error for a read not recorded, got %v", err)
+	}
+}
+
+// regions of an import run concurrently, each with its own provider wrapper, record into the same cassettes
+func TestRecordReplayRegions(t *testing.T) {
+	dir := t.TempDir()
+	regions := []string{"thing-east", "thing-west"}
+	importRegions := func(m string, newWrapper func() (*ProviderWrapper, error)) map[string]*terraform.InstanceState {
+		if err := recorder.Start(m, dir); err != nil {
+			t.Fatal(err)
+		}
+		states := map[string]*terraform.InstanceState{}
+		var statesLock sync.Mutex
+		var wg sync.WaitGroup
+		for _, id := range regions {
+			wg.Add(1)
+			go func(id string) {
+				defer wg.Done()
+				// like Import, each region starts the recorder again and stops it when done
+				if err := recorder.Start(m, dir); err != nil {
+					t.Error(err)
+					return
+				}
+				defer recorder.Stop() //nolint
+				wrapper, err := newWrapper()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer wrapper.Kill()
+				state, err := wrapper.Refresh(&terraform.InstanceInfo{Type: "test_thing", Id: id}, &terraform.InstanceState{ID: id, Attributes: map[string]string{"id": id}})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				statesLock.Lock()
+				states[id] = state
+				statesLock.Unlock()
+			}(id)
+		}
+		wg.Wait()
+		if recorder.Mode() != m {
+			t.Errorf("expected the recorder to run until the command stops it, got mode %q", recorder.Mode())
+		}
+		if err := recorder.Stop(); err != nil {
+			t.Fatal(err)
+		}
+		return states
+	}
+
+	fake, stop := testProviderV6(t)
+	defer stop()
+	recorded := importRegions(recorder.ModeRecord, func() (*ProviderWrapper, error) {
+		return &ProviderWrapper{Provider: newRecordingProvider(fake, "test", &ProviderPackage{}), retryCount: 1}, nil
+	})
+	replayed := importRegions(recorder.ModeReplay, func() (*ProviderWrapper, error) {
+		return NewProviderWrapper("test", cty.NilVal, false, map[string]int{"retryCount": 1, "retrySleepMs": 1})
+	})
+	for _, id := range regions {
+		if recorded[id] == nil || replayed[id] == nil || !reflect.DeepEqual(replayed[id].Attributes, recorded[id].Attributes) {
+			t.Errorf("%s: expected state %v, got %v", id, recorded[id], replayed[id])
+		}
+	}
+}
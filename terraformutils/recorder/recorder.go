@@ -0,0 +1,322 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recorder records API traffic of an import to cassette files and replays it offline
+package recorder
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// ModeOff sends requests as is
+	ModeOff = ""
+	// ModeRecord sends requests and records responses to cassettes
+	ModeRecord = "record"
+	// ModeReplay answers requests from cassettes without sending them
+	ModeReplay = "replay"
+)
+
+// HTTPCassette is the file name of recorded HTTP traffic in a cassette directory
+const HTTPCassette = "http.json"
+
+// Interaction is a recorded request with its response. Request headers aren't recorded, they hold
+// credentials and signatures.
+type Interaction struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Body       Body        `json:"body,omitempty"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Response   Body        `json:"response,omitempty"`
+}
+
+// Body is marshaled as a string, as base64 when it isn't UTF-8
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal("base64:" + base64.StdEncoding.EncodeToString(b))
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if len(s) > 7 && s[:7] == "base64:" {
+		decoded, err := base64.StdEncoding.DecodeString(s[7:])
+		*b = decoded
+		return err
+	}
+	*b = Body(s)
+	return nil
+}
+
+// Cassette holds the interactions of a run
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+
+	mu sync.Mutex
+	// replayed counts answered requests by key, a request recorded several times is answered in order,
+	// repeated requests get the last answer
+	replayed map[string]int
+	byKey    map[string][]int
+}
+
+var (
+	mode     = ModeOff
+	dir      string
+	cassette *Cassette
+	// users counts Start calls not stopped yet, imports of a command share the cassette of its first Start
+	users int
+	lock  sync.Mutex
+)
+
+// Start records to or replays from the cassette directory dir. Starting again with the same mode and
+// directory shares the running cassette, it is saved once the last user stops.
+func Start(m, d string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if mode != ModeOff && m != ModeOff {
+		if m != mode || filepath.Clean(d) != filepath.Clean(dir) {
+			return fmt.Errorf("recorder already running in %s mode with %s", mode, dir)
+		}
+		users++
+		return nil
+	}
+	switch m {
+	case ModeOff:
+		mode, dir, cassette, users = ModeOff, "", nil, 0
+		return nil
+	case ModeRecord:
+		if err := os.MkdirAll(d, os.ModePerm); err != nil {
+			return err
+		}
+		cassette = &Cassette{}
+	case ModeReplay:
+		c, err := LoadCassette(filepath.Join(d, HTTPCassette))
+		if err != nil {
+			return err
+		}
+		cassette = c
+	default:
+		return fmt.Errorf("unsupported recorder mode: %s", m)
+	}
+	mode, dir, users = m, d, 1
+	return nil
+}
+
+// Stop ends a Start, the last one saves recorded interactions and sends requests as is again
+func Stop() error {
+	lock.Lock()
+	defer lock.Unlock()
+	if users--; users > 0 {
+		return nil
+	}
+	var err error
+	if mode == ModeRecord {
+		err = cassette.Save(filepath.Join(dir, HTTPCassette))
+	}
+	mode, dir, cassette, users = ModeOff, "", nil, 0
+	return err
+}
+
+// Mode returns the current mode
+func Mode() string {
+	lock.Lock()
+	defer lock.Unlock()
+	return mode
+}
+
+// Dir returns the cassette directory, empty when neither recording nor replaying
+func Dir() string {
+	lock.Lock()
+	defer lock.Unlock()
+	return dir
+}
+
+// Replaying is true when requests are answered from cassettes
+func Replaying() bool {
+	return Mode() == ModeReplay
+}
+
+// Transport wraps base to record or replay requests, base is returned as is when neither recording nor
+// replaying. Providers use it for the HTTP clients of their SDKs.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	lock.Lock()
+	defer lock.Unlock()
+	switch mode {
+	case ModeRecord:
+		return &recordingTransport{base: base, cassette: cassette}
+	case ModeReplay:
+		return &replayingTransport{cassette: cassette}
+	}
+	return base
+}
+
+// HTTPClient returns a client with Transport over http.DefaultTransport
+func HTTPClient() *http.Client {
+	return &http.Client{Transport: Transport(http.DefaultTransport)}
+}
+
+type recordingTransport struct {
+	base     http.RoundTripper
+	cassette *Cassette
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+	t.cassette.add(Interaction{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		Body:       body,
+		StatusCode: resp.StatusCode,
+		Header:     header,
+		Response:   respBody,
+	})
+	return resp, nil
+}
+
+type replayingTransport struct {
+	cassette *Cassette
+}
+
+func (t *replayingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	interaction, exist := t.cassette.Find(req.Method, req.URL.String(), body)
+	if !exist {
+		return nil, fmt.Errorf("no recorded response for %s %s %s", req.Method, req.URL, body)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+		StatusCode:    interaction.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        interaction.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(interaction.Response)),
+		ContentLength: int64(len(interaction.Response)),
+		Request:       req,
+	}, nil
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{}
+	if err := json.Unmarshal(content, c); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %v", path, err)
+	}
+	return c, nil
+}
+
+func (c *Cassette) add(interaction Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, interaction)
+}
+
+// Find returns the response recorded for a request
+func (c *Cassette) Find(method, url string, body []byte) (Interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byKey == nil {
+		c.byKey = map[string][]int{}
+		c.replayed = map[string]int{}
+		for i, interaction := range c.Interactions {
+			key := interactionKey(interaction.Method, interaction.URL, interaction.Body)
+			c.byKey[key] = append(c.byKey[key], i)
+		}
+	}
+	key := interactionKey(method, url, body)
+	recorded := c.byKey[key]
+	if len(recorded) == 0 {
+		return Interaction{}, false
+	}
+	i := c.replayed[key]
+	if i >= len(recorded) {
+		i = len(recorded) - 1
+	}
+	c.replayed[key]++
+	return c.Interactions[recorded[i]], true
+}
+
+func interactionKey(method, url string, body []byte) string {
+	return method + " " + url + "\n" + string(body)
+}
+
+// Save writes the cassette, interactions are sorted by request so concurrent runs give the same file
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sort.SliceStable(c.Interactions, func(i, j int) bool {
+		a, b := c.Interactions[i], c.Interactions[j]
+		return interactionKey(a.Method, a.URL, a.Body) < interactionKey(b.Method, b.URL, b.Body)
+	})
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), os.ModePerm)
+}
@@ -0,0 +1,125 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func get(t *testing.T, client *http.Client, method, url, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(content)
+}
+
+func TestRecordReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Header().Set("Set-Cookie", "session=secret")
+		fmt.Fprintf(w, "%s %s call %d", r.Method, body, calls)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := Start(ModeRecord, dir); err != nil {
+		t.Fatal(err)
+	}
+	client := HTTPClient()
+	get(t, client, "POST", server.URL+"/", "Action=DescribeVpcs")
+	get(t, client, "POST", server.URL+"/", "Action=DescribeVpcs")
+	get(t, client, "POST", server.URL+"/", "Action=DescribeSubnets")
+	get(t, client, "GET", server.URL+"/missing", "")
+	if err := Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Start(ModeReplay, dir); err != nil {
+		t.Fatal(err)
+	}
+	defer Stop() //nolint
+	client = HTTPClient()
+	testCases := []struct {
+		method, path, body string
+		status             int
+		response           string
+	}{
+		{"POST", "/", "Action=DescribeSubnets", 200, "POST Action=DescribeSubnets call 3"},
+		// repeated requests are answered in recorded order, then with the last response
+		{"POST", "/", "Action=DescribeVpcs", 200, "POST Action=DescribeVpcs call 1"},
+		{"POST", "/", "Action=DescribeVpcs", 200, "POST Action=DescribeVpcs call 2"},
+		{"POST", "/", "Action=DescribeVpcs", 200, "POST Action=DescribeVpcs call 2"},
+		{"GET", "/missing", "", 404, "GET  call 4"},
+	}
+	for _, tc := range testCases {
+		status, response := get(t, client, tc.method, server.URL+tc.path, tc.body)
+		if status != tc.status || response != tc.response {
+			t.Errorf("%s %s %s: expected %d %q, got %d %q", tc.method, tc.path, tc.body, tc.status, tc.response, status, response)
+		}
+	}
+	if calls != 4 {
+		t.Errorf("replay should not send requests, server got %d", calls)
+	}
+	req, _ := http.NewRequest("GET", server.URL+"/other", nil)
+	if _, err := client.Do(req); err == nil {
+		t.Errorf("expected an error for a request not recorded")
+	}
+
+	cassette, err := LoadCassette(dir + "/" + HTTPCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, interaction := range cassette.Interactions {
+		if interaction.Header.Get("Set-Cookie") != "" {
+			t.Errorf("cookies should not be recorded")
+		}
+	}
+}
+
+func TestBodyJSON(t *testing.T) {
+	for _, body := range []Body{Body("text"), Body{0xff, 0x00, 0x01}} {
+		b, err := body.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded Body
+		if err := decoded.UnmarshalJSON(b); err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded) != string(body) {
+			t.Errorf("expected %v, got %v", body, decoded)
+		}
+	}
+}
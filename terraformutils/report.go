@@ -0,0 +1,239 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
+)
+
+const (
+	// FailOnService fails the import when resources of a service can't be listed
+	FailOnService = "service"
+	// FailOnResource fails the import when a service fails or a resource is dropped
+	FailOnResource = "resource"
+	// FailOnNone never fails on partial failures, they are only reported
+	FailOnNone = "none"
+)
+
+// ErrResourceNotFound is the reason of resources which were listed but don't exist when refreshed
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ServiceError is returned when resources of a service can't be listed
+type ServiceError struct {
+	Service string
+	Err     error
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("service %s failed: %v", e.Service, e.Err)
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// ServiceErrors is returned for services which failed, resources of other services are still imported
+type ServiceErrors []*ServiceError
+
+func (e ServiceErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// RefreshError is returned when a resource can't be refreshed, the resource is dropped from the import
+type RefreshError struct {
+	Service string
+	Type    string
+	Name    string
+	ID      string
+	Retries int
+	Err     error
+
+	resource *Resource
+}
+
+func (e *RefreshError) Error() string {
+	return fmt.Sprintf("unable to refresh resource %s.%s: %v", e.Type, e.Name, e.Err)
+}
+
+func (e *RefreshError) Unwrap() error {
+	return e.Err
+}
+
+func newRefreshError(r *Resource, err error) *RefreshError {
+	refreshError := &RefreshError{
+		Type: r.InstanceInfo.Type,
+		Name: r.ResourceName,
+		ID:   r.InstanceState.ID,
+		Err:  err,
+
+		resource: r,
+	}
+	var readError *providerwrapper.ReadError
+	if errors.As(err, &readError) {
+		refreshError.Retries = readError.Retries
+	}
+	return refreshError
+}
+
+// RefreshErrors is returned for resources which were dropped, other resources are still refreshed
+type RefreshErrors []*RefreshError
+
+func (e RefreshErrors) Error() string {
+	var messages []string
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// IncompleteImportError is returned when failures of an import exceed the --fail-on policy
+type IncompleteImportError struct {
+	FailedServices   int
+	DroppedResources int
+}
+
+func (e *IncompleteImportError) Error() string {
+	return fmt.Sprintf("import is incomplete: %d services failed, %d resources dropped", e.FailedServices, e.DroppedResources)
+}
+
+// Report summarizes failures of an import run
+type Report struct {
+	FailedServices   []FailedService   `json:"failed_services"`
+	DroppedResources []DroppedResource `json:"dropped_resources"`
+	// Retries is the number of read attempts retried by the provider, also for resources which were refreshed
+	Retries int `json:"retries"`
+}
+
+type FailedService struct {
+	Provider string `json:"provider"`
+	Service  string `json:"service"`
+	// Path is where the service would have been generated, it tells regions or projects apart
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+type DroppedResource struct {
+	Provider string `json:"provider"`
+	Service  string `json:"service"`
+	Path     string `json:"path"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	ID       string `json:"id"`
+	Retries  int    `json:"retries"`
+	Reason   string `json:"reason"`
+}
+
+func NewReport() *Report {
+	return &Report{
+		FailedServices:   []FailedService{},
+		DroppedResources: []DroppedResource{},
+	}
+}
+
+// ValidateFailOn checks a --fail-on policy
+func ValidateFailOn(failOn string) error {
+	switch failOn {
+	case FailOnService, FailOnResource, FailOnNone:
+		return nil
+	}
+	return fmt.Errorf("unsupported fail-on policy: %s, supported are %s, %s and %s", failOn, FailOnService, FailOnResource, FailOnNone)
+}
+
+// AddServiceErrors records failed services, path returns the output path of a service
+func (r *Report) AddServiceErrors(provider string, errs ServiceErrors, path func(service string) string) {
+	for _, err := range errs {
+		r.FailedServices = append(r.FailedServices, FailedService{
+			Provider: provider,
+			Service:  err.Service,
+			Path:     path(err.Service),
+			Reason:   err.Err.Error(),
+		})
+	}
+	r.sort()
+}
+
+// AddRefreshErrors records dropped resources, path returns the output path of a service
+func (r *Report) AddRefreshErrors(provider string, errs RefreshErrors, path func(service string) string) {
+	for _, err := range errs {
+		r.DroppedResources = append(r.DroppedResources, DroppedResource{
+			Provider: provider,
+			Service:  err.Service,
+			Path:     path(err.Service),
+			Type:     err.Type,
+			Name:     err.Name,
+			ID:       err.ID,
+			Retries:  err.Retries,
+			Reason:   err.Err.Error(),
+		})
+	}
+	r.sort()
+}
+
+// Add appends other report, e.g. of another region
+func (r *Report) Add(other *Report) {
+	r.FailedServices = append(r.FailedServices, other.FailedServices...)
+	r.DroppedResources = append(r.DroppedResources, other.DroppedResources...)
+	r.Retries += other.Retries
+	r.sort()
+}
+
+// Check returns an IncompleteImportError when failures are not accepted by the failOn policy
+func (r *Report) Check(failOn string) error {
+	if err := ValidateFailOn(failOn); err != nil {
+		return err
+	}
+	failed := false
+	switch failOn {
+	case FailOnService:
+		failed = len(r.FailedServices) > 0
+	case FailOnResource:
+		failed = len(r.FailedServices) > 0 || len(r.DroppedResources) > 0
+	}
+	if !failed {
+		return nil
+	}
+	return &IncompleteImportError{
+		FailedServices:   len(r.FailedServices),
+		DroppedResources: len(r.DroppedResources),
+	}
+}
+
+func (r *Report) sort() {
+	sort.SliceStable(r.FailedServices, func(i, j int) bool {
+		if r.FailedServices[i].Path != r.FailedServices[j].Path {
+			return r.FailedServices[i].Path < r.FailedServices[j].Path
+		}
+		return r.FailedServices[i].Service < r.FailedServices[j].Service
+	})
+	sort.SliceStable(r.DroppedResources, func(i, j int) bool {
+		a, b := r.DroppedResources[i], r.DroppedResources[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+}
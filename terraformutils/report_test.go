@@ -0,0 +1,99 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
+)
+
+func testServicePath(service string) string {
+	return "generated/aws/" + service
+}
+
+func TestReportCheck(t *testing.T) {
+	serviceFailed := NewReport()
+	serviceFailed.AddServiceErrors("aws", ServiceErrors{
+		{Service: "s3", Err: errors.New("access denied")},
+	}, testServicePath)
+	resourceDropped := NewReport()
+	resourceDropped.AddRefreshErrors("aws", RefreshErrors{
+		{Service: "vpc", Type: "aws_vpc", Name: "tfer--main", ID: "vpc-1", Err: ErrResourceNotFound},
+	}, testServicePath)
+
+	tests := []struct {
+		report *Report
+		failOn string
+		fail   bool
+	}{
+		{NewReport(), FailOnResource, false},
+		{serviceFailed, FailOnService, true},
+		{serviceFailed, FailOnResource, true},
+		{serviceFailed, FailOnNone, false},
+		{resourceDropped, FailOnService, false},
+		{resourceDropped, FailOnResource, true},
+		{resourceDropped, FailOnNone, false},
+	}
+	for i, test := range tests {
+		err := test.report.Check(test.failOn)
+		var incomplete *IncompleteImportError
+		if test.fail != errors.As(err, &incomplete) {
+			t.Errorf("case %d: fail-on %s returned %v", i, test.failOn, err)
+		}
+	}
+	if err := NewReport().Check("all"); err == nil {
+		t.Errorf("expected unsupported policy to fail")
+	}
+}
+
+func TestReportAdd(t *testing.T) {
+	report := NewReport()
+	report.AddServiceErrors("aws", ServiceErrors{{Service: "s3", Err: errors.New("access denied")}}, testServicePath)
+	report.Retries = 2
+	other := NewReport()
+	other.AddRefreshErrors("aws", RefreshErrors{
+		{Service: "vpc", Type: "aws_vpc", Name: "tfer--main", ID: "vpc-1", Retries: 4, Err: errors.New("throttled")},
+	}, testServicePath)
+	other.Retries = 5
+	report.Add(other)
+
+	if len(report.FailedServices) != 1 || report.FailedServices[0].Path != "generated/aws/s3" || report.FailedServices[0].Reason != "access denied" {
+		t.Errorf("unexpected failed services %v", report.FailedServices)
+	}
+	expected := DroppedResource{
+		Provider: "aws", Service: "vpc", Path: "generated/aws/vpc", Type: "aws_vpc", Name: "tfer--main", ID: "vpc-1", Retries: 4, Reason: "throttled",
+	}
+	if len(report.DroppedResources) != 1 || report.DroppedResources[0] != expected {
+		t.Errorf("unexpected dropped resources %v", report.DroppedResources)
+	}
+	if report.Retries != 7 {
+		t.Errorf("expected 7 retries, got %d", report.Retries)
+	}
+}
+
+func TestRefreshErrorRetries(t *testing.T) {
+	r := NewSimpleResource("vpc-1", "main", "aws_vpc", "aws", []string{})
+	readError := &providerwrapper.ReadError{Type: "aws_vpc", ID: "vpc-1", Retries: 3, Err: errors.New("throttled")}
+	err := newRefreshError(&r, fmt.Errorf("refresh: %w", readError))
+	if err.Retries != 3 || err.ID != "vpc-1" || err.Type != "aws_vpc" {
+		t.Errorf("unexpected refresh error %+v", err)
+	}
+	if !errors.Is(err, readError) {
+		t.Errorf("refresh error doesn't wrap the provider error")
+	}
+}
@@ -16,7 +16,6 @@ package terraformutils
 
 import (
 	"fmt"
-	"log"
 	"regexp"
 	"strings"
 	"time"
@@ -38,6 +37,8 @@ type Resource struct {
 	AdditionalFields  map[string]interface{} `json:",omitempty"`
 	SlowQueryRequired bool
 	DataFiles         map[string][]byte
+	// LinkedAttributes are referenced by resources of other services, they are exported as outputs
+	LinkedAttributes []string `json:",omitempty"`
 }
 
 type ApplicableFilter interface {
@@ -49,9 +50,14 @@ type ResourceFilter struct {
 	ServiceName      string
 	FieldPath        string
 	AcceptableValues []string
+	// Expression is set for filter expressions, other fields are empty then
+	Expression *FilterExpression
 }
 
 func (rf *ResourceFilter) Filter(resource Resource) bool {
+	if rf.Expression != nil {
+		return rf.Expression.Match(resource)
+	}
 	if !rf.IsApplicable(strings.TrimPrefix(resource.InstanceInfo.Type, resource.Provider+"_")) {
 		return true
 	}
@@ -86,6 +92,9 @@ func (rf *ResourceFilter) IsApplicable(serviceName string) bool {
 }
 
 func (rf *ResourceFilter) isInitial() bool {
+	if rf.Expression != nil {
+		return rf.Expression.isInitial()
+	}
 	return rf.FieldPath == "id"
 }
 
@@ -122,15 +131,25 @@ func NewSimpleResource(id, resourceName, resourceType, provider string, allowEmp
 	)
 }
 
-func (r *Resource) Refresh(provider *providerwrapper.ProviderWrapper) {
-	var err error
+// Refresh reads the current state of the resource, a RefreshError is returned when it can't be read
+// or doesn't exist anymore
+func (r *Resource) Refresh(provider *providerwrapper.ProviderWrapper) error {
 	if r.SlowQueryRequired {
 		time.Sleep(200 * time.Millisecond)
 	}
-	r.InstanceState, err = provider.Refresh(r.InstanceInfo, r.InstanceState)
+	state, err := provider.Refresh(r.InstanceInfo, r.InstanceState)
 	if err != nil {
-		log.Println(err)
+		refreshError := newRefreshError(r, err)
+		r.InstanceState = nil
+		return refreshError
 	}
+	if state == nil || state.ID == "" {
+		refreshError := newRefreshError(r, ErrResourceNotFound)
+		r.InstanceState = state
+		return refreshError
+	}
+	r.InstanceState = state
+	return nil
 }
 
 func (r Resource) GetIDKey() string {
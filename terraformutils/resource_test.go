@@ -0,0 +1,25 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+// testResource returns a resource created with NewResource like services do, so its name is escaped by TfSanitize
+func testResource(id, name, resourceType string, attributes map[string]string, item map[string]interface{}) Resource {
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
+	r := NewResource(id, name, resourceType, "aws", attributes, []string{}, map[string]interface{}{})
+	r.Item = item
+	return r
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+const DefaultParallelism = 15
+
+// Scheduler bounds concurrent calls to a provider API and their rate. Service initialization and
+// resource refresh share the scheduler of their provider, also across regions imported concurrently.
+type Scheduler struct {
+	slots   chan struct{}
+	limiter *rate.Limiter
+}
+
+var schedulers = map[string]*Scheduler{}
+var schedulersLock sync.Mutex
+
+// NewScheduler runs at most parallelism tasks at once, rateLimit is the number of tasks started per second,
+// 0 for no limit
+func NewScheduler(parallelism int, rateLimit float64) *Scheduler {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	limit := rate.Inf
+	if rateLimit > 0 {
+		limit = rate.Limit(rateLimit)
+	}
+	// bursts up to parallelism let workers start at once
+	return &Scheduler{
+		slots:   make(chan struct{}, parallelism),
+		limiter: rate.NewLimiter(limit, parallelism),
+	}
+}
+
+// ProviderScheduler returns the scheduler shared by all imports of a provider with the same limits, created on
+// first use. Imports with other limits, e.g. jobs of run, get their own scheduler.
+func ProviderScheduler(providerName string, parallelism int, rateLimit float64) *Scheduler {
+	schedulersLock.Lock()
+	defer schedulersLock.Unlock()
+	key := fmt.Sprintf("%s/%d/%g", providerName, parallelism, rateLimit)
+	if s, exist := schedulers[key]; exist {
+		return s
+	}
+	s := NewScheduler(parallelism, rateLimit)
+	schedulers[key] = s
+	return s
+}
+
+func (s *Scheduler) Parallelism() int {
+	return cap(s.slots)
+}
+
+// Run waits for a free slot and a token of the rate limiter and runs task
+func (s *Scheduler) Run(task func()) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+	s.Wait()
+	task()
+}
+
+// Wait waits for a token of the rate limiter only, for tasks outside of the parallelism bound
+func (s *Scheduler) Wait() {
+	_ = s.limiter.Wait(context.Background())
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerParallelism(t *testing.T) {
+	scheduler := NewScheduler(3, 0)
+	var running, maxRunning int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scheduler.Run(func() {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+			})
+		}()
+	}
+	wg.Wait()
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 tasks at once, got %d", maxRunning)
+	}
+}
+
+func TestSchedulerRateLimit(t *testing.T) {
+	// burst of 1 task, then 50 tasks per second
+	scheduler := NewScheduler(1, 50)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		scheduler.Run(func() {})
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("rate limit not applied, 6 tasks took %s", elapsed)
+	}
+}
+
+func TestProviderScheduler(t *testing.T) {
+	s := ProviderScheduler("test", 2, 0)
+	if ProviderScheduler("test", 2, 0) != s || s.Parallelism() != 2 {
+		t.Error("imports of a provider should share its scheduler")
+	}
+	if other := ProviderScheduler("test", 5, 0); other == s || other.Parallelism() != 5 {
+		t.Error("imports with other limits should get their own scheduler")
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+)
+
+const (
+	// SensitiveKeep writes sensitive values to HCL and state
+	SensitiveKeep = "keep"
+	// SensitiveRedact replaces sensitive values in HCL with variables, state keeps them
+	SensitiveRedact = "redact"
+	// SensitiveStrip replaces sensitive values in HCL with variables and removes them from state
+	SensitiveStrip = "strip"
+)
+
+// SensitiveVariable is declared for a value replaced by Redactor
+type SensitiveVariable struct {
+	Name      string
+	Address   string
+	Attribute string
+}
+
+// Redactor replaces values of attributes marked sensitive in the provider schema, or matching patterns,
+// with references to variables
+type Redactor struct {
+	schema   *providers.GetSchemaResponse
+	patterns []*regexp.Regexp
+	strip    bool
+	// sensitive attribute paths by resource type, e.g. master_user_settings.master_user_password
+	sensitive map[string]map[string]struct{}
+}
+
+// ValidateSensitive checks a --sensitive mode
+func ValidateSensitive(mode string) error {
+	switch mode {
+	case SensitiveKeep, SensitiveRedact, SensitiveStrip:
+		return nil
+	}
+	return fmt.Errorf("unsupported sensitive mode: %s, supported are %s, %s and %s", mode, SensitiveKeep, SensitiveRedact, SensitiveStrip)
+}
+
+// NewRedactor returns a Redactor for the mode, patterns are regular expressions matching attribute paths
+// like password or type.path like aws_db_instance.password. Nil is returned when values are kept.
+func NewRedactor(mode string, patterns []string, schema *providers.GetSchemaResponse) (*Redactor, error) {
+	if err := ValidateSensitive(mode); err != nil {
+		return nil, err
+	}
+	if mode == SensitiveKeep {
+		return nil, nil
+	}
+	rd := &Redactor{
+		schema:    schema,
+		strip:     mode == SensitiveStrip,
+		sensitive: map[string]map[string]struct{}{},
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sensitive pattern %s: %v", pattern, err)
+		}
+		rd.patterns = append(rd.patterns, re)
+	}
+	return rd, nil
+}
+
+// Redact replaces sensitive values of the resource with variables and returns them, with strip values
+// are removed from state too
+func (rd *Redactor) Redact(r *Resource) []SensitiveVariable {
+	var variables []SensitiveVariable
+	prefix := r.InstanceInfo.Type + "_" + r.ResourceName
+	for k, v := range r.Item {
+		r.Item[k] = rd.redactValue(r, []string{k}, prefix+"_"+k, v, &variables)
+	}
+	if rd.strip && r.InstanceState != nil {
+		for key := range r.InstanceState.Attributes {
+			if rd.isSensitive(r.InstanceInfo.Type, flatmapPath(key)) {
+				delete(r.InstanceState.Attributes, key)
+			}
+		}
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+	return variables
+}
+
+func (rd *Redactor) redactValue(r *Resource, path []string, name string, value interface{}, variables *[]SensitiveVariable) interface{} {
+	if rd.isSensitive(r.InstanceInfo.Type, path) && !isEmptyValue(value) {
+		*variables = append(*variables, SensitiveVariable{
+			Name:      name,
+			Address:   r.InstanceInfo.Type + "." + r.ResourceName,
+			Attribute: strings.Join(path, "."),
+		})
+		return "${var." + name + "}"
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = rd.redactValue(r, append(path[:len(path):len(path)], k), name+"_"+k, item, variables)
+		}
+	case []interface{}:
+		for i, item := range v {
+			itemName := name
+			if len(v) > 1 {
+				itemName += "_" + strconv.Itoa(i)
+			}
+			v[i] = rd.redactValue(r, path, itemName, item, variables)
+		}
+	}
+	return value
+}
+
+// isSensitive is true when path or one of its parents is sensitive
+func (rd *Redactor) isSensitive(resourceType string, path []string) bool {
+	sensitive := rd.sensitiveAttributes(resourceType)
+	for i := 1; i <= len(path); i++ {
+		attribute := strings.Join(path[:i], ".")
+		if _, exist := sensitive[attribute]; exist {
+			return true
+		}
+		for _, pattern := range rd.patterns {
+			if pattern.MatchString(attribute) || pattern.MatchString(resourceType+"."+attribute) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (rd *Redactor) sensitiveAttributes(resourceType string) map[string]struct{} {
+	if sensitive, exist := rd.sensitive[resourceType]; exist {
+		return sensitive
+	}
+	sensitive := map[string]struct{}{}
+	if rd.schema != nil {
+		if schema, exist := rd.schema.ResourceTypes[resourceType]; exist && schema.Block != nil {
+			addSensitiveAttributes(sensitive, "", schema.Block)
+		}
+	}
+	rd.sensitive[resourceType] = sensitive
+	return sensitive
+}
+
+func addSensitiveAttributes(sensitive map[string]struct{}, prefix string, block *configschema.Block) {
+	for k, attribute := range block.Attributes {
+		if attribute.Sensitive {
+			sensitive[prefix+k] = struct{}{}
+		}
+	}
+	for k, nested := range block.BlockTypes {
+		addSensitiveAttributes(sensitive, prefix+k+".", &nested.Block)
+	}
+}
+
+// flatmapPath returns the path of a flatmap key without list indexes and set hashes
+func flatmapPath(key string) []string {
+	var path []string
+	for _, segment := range strings.Split(key, ".") {
+		if _, err := strconv.Atoi(segment); err == nil {
+			continue
+		}
+		path = append(path, segment)
+	}
+	return path
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == "" || strings.Contains(v, "${")
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// SensitiveVariablesData returns variable declarations for variables.tf
+func SensitiveVariablesData(variables []SensitiveVariable) map[string]map[string]interface{} {
+	data := map[string]map[string]interface{}{}
+	for _, variable := range variables {
+		data[variable.Name] = map[string]interface{}{
+			"description": fmt.Sprintf("%s of %s", variable.Attribute, variable.Address),
+			"sensitive":   true,
+		}
+	}
+	return data
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func testSensitiveSchema() *providers.GetSchemaResponse {
+	return &providers.GetSchemaResponse{
+		ResourceTypes: map[string]providers.Schema{
+			"aws_db_instance": {Block: &configschema.Block{
+				Attributes: map[string]*configschema.Attribute{
+					"identifier": {Type: cty.String, Optional: true},
+					"password":   {Type: cty.String, Optional: true, Sensitive: true},
+				},
+			}},
+			"aws_elasticsearch_domain": {Block: &configschema.Block{
+				BlockTypes: map[string]*configschema.NestedBlock{
+					"master_user_options": {Nesting: configschema.NestingList, Block: configschema.Block{
+						Attributes: map[string]*configschema.Attribute{
+							"master_user_name":     {Type: cty.String, Optional: true},
+							"master_user_password": {Type: cty.String, Optional: true, Sensitive: true},
+						},
+					}},
+				},
+			}},
+		},
+	}
+}
+
+func TestRedact(t *testing.T) {
+	db := NewResource("db", "db", "aws_db_instance", "aws", map[string]string{
+		"id": "db", "identifier": "db", "password": "secret",
+	}, []string{}, map[string]interface{}{})
+	db.SetResourceName("db")
+	db.Item = map[string]interface{}{"identifier": "db", "password": "secret"}
+
+	es := NewResource("logs", "logs", "aws_elasticsearch_domain", "aws", map[string]string{
+		"master_user_options.#":                      "1",
+		"master_user_options.0.master_user_name":     "admin",
+		"master_user_options.0.master_user_password": "secret",
+		"advanced_options.%":                         "1",
+		"advanced_options.api_token":                 "token",
+	}, []string{}, map[string]interface{}{})
+	es.SetResourceName("logs")
+	es.Item = map[string]interface{}{
+		"master_user_options": []interface{}{map[string]interface{}{"master_user_name": "admin", "master_user_password": "secret"}},
+		"advanced_options":    map[string]interface{}{"api_token": "token"},
+	}
+
+	redactor, err := NewRedactor(SensitiveStrip, []string{"token$"}, testSensitiveSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables := append(redactor.Redact(&db), redactor.Redact(&es)...)
+
+	expected := []SensitiveVariable{
+		{Name: "aws_db_instance_db_password", Address: "aws_db_instance.db", Attribute: "password"},
+		{Name: "aws_elasticsearch_domain_logs_advanced_options_api_token", Address: "aws_elasticsearch_domain.logs", Attribute: "advanced_options.api_token"},
+		{Name: "aws_elasticsearch_domain_logs_master_user_options_master_user_password", Address: "aws_elasticsearch_domain.logs", Attribute: "master_user_options.master_user_password"},
+	}
+	if !reflect.DeepEqual(variables, expected) {
+		t.Errorf("expected variables %v, got %v", expected, variables)
+	}
+	if db.Item["password"] != "${var.aws_db_instance_db_password}" || db.Item["identifier"] != "db" {
+		t.Errorf("unexpected item %v", db.Item)
+	}
+	expectedItem := map[string]interface{}{
+		"master_user_options": []interface{}{map[string]interface{}{
+			"master_user_name":     "admin",
+			"master_user_password": "${var.aws_elasticsearch_domain_logs_master_user_options_master_user_password}",
+		}},
+		"advanced_options": map[string]interface{}{"api_token": "${var.aws_elasticsearch_domain_logs_advanced_options_api_token}"},
+	}
+	if !reflect.DeepEqual(es.Item, expectedItem) {
+		t.Errorf("expected item %v, got %v", expectedItem, es.Item)
+	}
+
+	expectedState := map[string]string{
+		"master_user_options.#":                  "1",
+		"master_user_options.0.master_user_name": "admin",
+		"advanced_options.%":                     "1",
+	}
+	if !reflect.DeepEqual(es.InstanceState.Attributes, expectedState) {
+		t.Errorf("expected state %v, got %v", expectedState, es.InstanceState.Attributes)
+	}
+}
+
+func TestRedactKeepsState(t *testing.T) {
+	db := NewResource("db", "db", "aws_db_instance", "aws", map[string]string{"password": "secret"}, []string{}, map[string]interface{}{})
+	db.Item = map[string]interface{}{"password": "secret"}
+	redactor, err := NewRedactor(SensitiveRedact, nil, testSensitiveSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variables := redactor.Redact(&db); len(variables) != 1 {
+		t.Errorf("expected one variable, got %v", variables)
+	}
+	if db.InstanceState.Attributes["password"] != "secret" {
+		t.Errorf("redact should keep state, got %v", db.InstanceState.Attributes)
+	}
+	if redactor, _ := NewRedactor(SensitiveKeep, nil, testSensitiveSchema()); redactor != nil {
+		t.Errorf("no redactor is needed to keep values")
+	}
+	if _, err := NewRedactor("hide", nil, nil); err == nil {
+		t.Errorf("expected an error for an unsupported mode")
+	}
+}
@@ -21,6 +21,9 @@ import (
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
 )
 
+// ProviderConfigArg is the service arg holding the --provider-config settings as map[string]string
+const ProviderConfigArg = "provider_config"
+
 type ServiceGenerator interface {
 	InitResources() error
 	GetResources() []Resource
@@ -71,6 +74,14 @@ func (s *Service) ParseFilters(rawFilters []string) {
 
 func (s *Service) ParseFilter(rawFilter string) []ResourceFilter {
 	var filters []ResourceFilter
+	if IsFilterExpression(rawFilter) {
+		expression, err := ParseFilterExpression(rawFilter)
+		if err != nil {
+			log.Print(err)
+			return filters
+		}
+		return append(filters, ResourceFilter{Expression: expression})
+	}
 	if !strings.HasPrefix(rawFilter, "Name=") && len(strings.Split(rawFilter, "=")) == 2 {
 		parts := strings.Split(rawFilter, "=")
 		serviceName, resourcesID := parts[0], parts[1]
@@ -136,6 +147,14 @@ func (s *Service) SetArgs(args map[string]interface{}) {
 	s.Args = args
 }
 
+// GetProviderConfig returns the --provider-config settings, e.g. endpoints.s3, for the SDK clients
+func (s *Service) GetProviderConfig() map[string]string {
+	if config, ok := s.Args[ProviderConfigArg].(map[string]string); ok {
+		return config
+	}
+	return map[string]string{}
+}
+
 func (s *Service) GetResources() []Resource {
 	return s.Resources
 }
@@ -137,3 +137,32 @@ func TestServiceAttributeNameOnlyCleanupWithFilter(t *testing.T) {
 		t.Errorf("failed to cleanup")
 	}
 }
+
+func TestSelectResources(t *testing.T) {
+	vpc := NewResource("vpc-1", "vpc-1", "aws_vpc", "aws", map[string]string{"tags.env": "dev"}, []string{}, map[string]interface{}{})
+	sg := NewResource("sg-1", "sg-1", "aws_security_group", "aws", map[string]string{"vpc_id": "vpc-1"}, []string{}, map[string]interface{}{})
+	otherSg := NewResource("sg-2", "sg-2", "aws_security_group", "aws", map[string]string{"vpc_id": "vpc-2"}, []string{}, map[string]interface{}{})
+	resources := []Resource{vpc, sg, otherSg}
+
+	service := Service{}
+	service.ParseFilters([]string{"Type=security_group;Name=vpc_id;Value=vpc-1"})
+	ids := func(resources []Resource) []string {
+		var ids []string
+		for _, r := range resources {
+			ids = append(ids, r.InstanceState.ID)
+		}
+		return ids
+	}
+	// filters not applicable to a type keep its resources when selecting and when excluding
+	if selected := ids(SelectResources(resources, service.Filter, false)); !reflect.DeepEqual(selected, []string{"vpc-1", "sg-1"}) {
+		t.Errorf("unexpected selected resources %v", selected)
+	}
+	if selected := ids(SelectResources(resources, service.Filter, true)); !reflect.DeepEqual(selected, []string{"vpc-1", "sg-2"}) {
+		t.Errorf("unexpected resources after exclude %v", selected)
+	}
+
+	service.ParseFilters([]string{`tags.env == "dev"`})
+	if selected := ids(SelectResources(resources, service.Filter, true)); !reflect.DeepEqual(selected, []string{"sg-1", "sg-2"}) {
+		t.Errorf("unexpected resources after exclude %v", selected)
+	}
+}
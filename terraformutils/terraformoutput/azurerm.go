@@ -0,0 +1,140 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformoutput
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+const azureBlobURLFormat = "https://%s.blob.core.windows.net"
+
+// AzureRMState stores state in an Azure storage account container
+type AzureRMState struct {
+	StorageAccountName string
+	ContainerName      string
+	Key                string
+	Config             map[string]string
+}
+
+func NewAzureRMState(bucket string, settings map[string]string) (*AzureRMState, error) {
+	containerName := settings["container_name"]
+	if containerName == "" {
+		containerName = bucket
+	}
+	if settings["storage_account_name"] == "" || containerName == "" {
+		return nil, errors.New("azurerm state backend requires storage_account_name and container_name")
+	}
+	return &AzureRMState{
+		StorageAccountName: settings["storage_account_name"],
+		ContainerName:      containerName,
+		Key:                settings["key"],
+		Config:             settings,
+	}, nil
+}
+
+func (a AzureRMState) BackendName() string {
+	return "azurerm"
+}
+
+func (a AzureRMState) BackendConfig(path string) map[string]interface{} {
+	return backendConfig(a.Config, path, map[string]interface{}{
+		"storage_account_name": a.StorageAccountName,
+		"container_name":       a.ContainerName,
+		"key":                  a.BlobName(path),
+	})
+}
+
+func (a AzureRMState) RemoteStateConfig(path, fromPath string) map[string]interface{} {
+	return a.BackendConfig(path)
+}
+
+func (a AzureRMState) BlobName(path string) string {
+	if a.Key != "" {
+		return expandPath(a.Key, path)
+	}
+	return trimPath(path) + "/terraform.tfstate"
+}
+
+// same credentials as the azurerm backend: access key or SAS token
+func (a AzureRMState) containerURL() (azblob.ContainerURL, error) {
+	accountURL, err := url.Parse(fmt.Sprintf(azureBlobURLFormat, a.StorageAccountName))
+	if err != nil {
+		return azblob.ContainerURL{}, err
+	}
+	var credential azblob.Credential
+	accessKey := a.setting("access_key", "ARM_ACCESS_KEY")
+	sasToken := a.setting("sas_token", "ARM_SAS_TOKEN")
+	switch {
+	case accessKey != "":
+		credential, err = azblob.NewSharedKeyCredential(a.StorageAccountName, accessKey)
+		if err != nil {
+			return azblob.ContainerURL{}, err
+		}
+	case sasToken != "":
+		credential = azblob.NewAnonymousCredential()
+		accountURL.RawQuery = strings.TrimPrefix(sasToken, "?")
+	default:
+		return azblob.ContainerURL{}, errors.New("azurerm state backend requires access_key or sas_token (ARM_ACCESS_KEY or ARM_SAS_TOKEN)")
+	}
+	serviceURL := azblob.NewServiceURL(*accountURL, azblob.NewPipeline(credential, azblob.PipelineOptions{}))
+	return serviceURL.NewContainerURL(a.ContainerName), nil
+}
+
+func (a AzureRMState) setting(key, envName string) string {
+	if a.Config[key] != "" {
+		return a.Config[key]
+	}
+	return os.Getenv(envName)
+}
+
+func (a AzureRMState) Upload(path string, file []byte) error {
+	containerURL, err := a.containerURL()
+	if err != nil {
+		return err
+	}
+	blobURL := containerURL.NewBlockBlobURL(a.BlobName(path))
+	_, err = azblob.UploadBufferToBlockBlob(context.Background(), file, blobURL, azblob.UploadToBlockBlobOptions{
+		BlobHTTPHeaders: azblob.BlobHTTPHeaders{ContentType: "application/json"},
+	})
+	return err
+}
+
+func (a AzureRMState) Download(path string) ([]byte, error) {
+	containerURL, err := a.containerURL()
+	if err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	blobURL := containerURL.NewBlobURL(a.BlobName(path))
+	resp, err := blobURL.Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	var storageError azblob.StorageError
+	if errors.As(err, &storageError) && storageError.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	body := resp.Body(azblob.RetryReaderOptions{})
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
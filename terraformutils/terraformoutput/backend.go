@@ -0,0 +1,105 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformoutput
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StateBackend stores generated state and describes where terraform can find it
+type StateBackend interface {
+	// BackendName is the terraform backend type, e.g. gcs or s3
+	BackendName() string
+	// BackendConfig is the backend block configuration for the state of resources generated in path,
+	// nil when terraform defaults are enough
+	BackendConfig(path string) map[string]interface{}
+	// RemoteStateConfig is the terraform_remote_state configuration used in fromPath to read the state of path
+	RemoteStateConfig(path, fromPath string) map[string]interface{}
+	// Upload stores the state file of resources generated in path
+	Upload(path string, file []byte) error
+}
+
+// PathPlaceholder is replaced in backend configuration values with the path of generated resources
+const PathPlaceholder = "{path}"
+
+// Settings which are used to access the backend, but must not end up in generated files
+var secretBackendSettings = map[string]struct{}{
+	"access_key": {},
+	"secret_key": {},
+	"sas_token":  {},
+	"password":   {},
+	"token":      {},
+}
+
+// NewStateBackend creates backend by --state name, bucket and backend specific --state-config settings
+func NewStateBackend(name, bucket string, config map[string]string) (StateBackend, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+	switch name {
+	case "", "local":
+		return &LocalState{Path: config["path"]}, nil
+	case "bucket", "gcs":
+		return &BucketState{Name: bucket, Prefix: config["prefix"], Config: config}, nil
+	case "s3":
+		return NewS3State(bucket, config)
+	case "azurerm":
+		return NewAzureRMState(bucket, config)
+	case "http":
+		return NewHTTPState(config)
+	}
+	return nil, fmt.Errorf("unsupported state backend: %s", name)
+}
+
+// BackendGetTfData returns terraform block with backend configuration for resources generated in path
+func BackendGetTfData(backend StateBackend, path string) interface{} {
+	config := backend.BackendConfig(path)
+	if config == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"terraform": map[string]interface{}{
+			"backend": []map[string]interface{}{
+				{
+					backend.BackendName(): config,
+				},
+			},
+		},
+	}
+}
+
+func trimPath(path string) string {
+	return strings.TrimSuffix(path, "/")
+}
+
+func expandPath(value, path string) string {
+	return strings.ReplaceAll(value, PathPlaceholder, trimPath(path))
+}
+
+// backendConfig merges user settings, without secrets, with backend defaults
+func backendConfig(config map[string]string, path string, defaults map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for k, v := range config {
+		if _, secret := secretBackendSettings[k]; secret {
+			continue
+		}
+		result[k] = expandPath(v, path)
+	}
+	for k, v := range defaults {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,209 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformoutput
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// stateServer is a minimal stand-in for S3 compatible storage and http backend servers
+type stateServer struct {
+	method        string
+	path          string
+	body          []byte
+	authorization string
+}
+
+func (s *stateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.method = r.Method
+	s.path = r.URL.Path
+	s.authorization = r.Header.Get("Authorization")
+	s.body, _ = ioutil.ReadAll(r.Body)
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestNewStateBackendUnsupported(t *testing.T) {
+	if _, err := NewStateBackend("consul", "", nil); err == nil {
+		t.Error("expected error for unsupported backend")
+	}
+}
+
+func TestGCSBackendConfig(t *testing.T) {
+	backend, err := NewStateBackend("bucket", "gs://terraform-state", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"terraform": map[string]interface{}{
+			"backend": []map[string]interface{}{{
+				"gcs": map[string]interface{}{
+					"bucket": "terraform-state",
+					"prefix": "generated/google/networks",
+				},
+			}},
+		},
+	}
+	if data := BackendGetTfData(backend, "generated/google/networks/"); !reflect.DeepEqual(data, expected) {
+		t.Errorf("unexpected backend data %v", data)
+	}
+}
+
+func TestS3BackendUpload(t *testing.T) {
+	server := &stateServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "minio")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "minio123")
+
+	backend, err := NewStateBackend("s3", "s3://terraform-state", map[string]string{
+		"region":         "us-east-1",
+		"dynamodb_table": "locks",
+		"endpoint":       ts.URL,
+		"secret_key":     "minio123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Upload("generated/aws/vpc/", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if server.method != http.MethodPut || server.path != "/terraform-state/generated/aws/vpc/terraform.tfstate" || string(server.body) != "{}" {
+		t.Errorf("unexpected upload %s %s %s", server.method, server.path, string(server.body))
+	}
+
+	expected := map[string]interface{}{
+		"bucket":           "terraform-state",
+		"key":              "generated/aws/vpc/terraform.tfstate",
+		"region":           "us-east-1",
+		"dynamodb_table":   "locks",
+		"endpoint":         ts.URL,
+		"force_path_style": true,
+	}
+	if config := backend.RemoteStateConfig("generated/aws/vpc/", "generated/aws/subnet/"); !reflect.DeepEqual(config, expected) {
+		t.Errorf("unexpected remote state config %v", config)
+	}
+}
+
+func TestS3BackendCredentials(t *testing.T) {
+	server := &stateServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "environment")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "environment")
+
+	backend, err := NewStateBackend("s3", "s3://terraform-state", map[string]string{
+		"region":     "us-east-1",
+		"endpoint":   ts.URL,
+		"access_key": "minio",
+		"secret_key": "minio123",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Upload("generated/aws/vpc/", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(server.authorization, "Credential=minio/") {
+		t.Errorf("expected the request signed with the access key of the settings, got %s", server.authorization)
+	}
+	if _, exist := backend.BackendConfig("generated/aws/vpc/")["secret_key"]; exist {
+		t.Errorf("expected the secret key not to be written to the backend config")
+	}
+}
+
+func TestAzureRMBackendConfig(t *testing.T) {
+	if _, err := NewStateBackend("azurerm", "", map[string]string{}); err == nil {
+		t.Error("expected error without storage account")
+	}
+	backend, err := NewStateBackend("azurerm", "tfstate", map[string]string{
+		"storage_account_name": "terraformer",
+		"resource_group_name":  "state",
+		"access_key":           "secret",
+		"key":                  "{path}.tfstate",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"storage_account_name": "terraformer",
+		"container_name":       "tfstate",
+		"resource_group_name":  "state",
+		"key":                  "generated/azurerm/disk.tfstate",
+	}
+	if config := backend.BackendConfig("generated/azurerm/disk/"); !reflect.DeepEqual(config, expected) {
+		t.Errorf("unexpected backend config %v", config)
+	}
+}
+
+func TestHTTPBackendUpload(t *testing.T) {
+	server := &stateServer{}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	backend, err := NewStateBackend("http", "", map[string]string{
+		"address":  ts.URL + "/state/",
+		"username": "terraformer",
+		"password": "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Upload("generated/github/repositories/", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if server.method != http.MethodPost || server.path != "/state/generated/github/repositories" {
+		t.Errorf("unexpected upload %s %s", server.method, server.path)
+	}
+	expected := map[string]interface{}{
+		"address":  ts.URL + "/state/generated/github/repositories",
+		"username": "terraformer",
+	}
+	if config := backend.BackendConfig("generated/github/repositories/"); !reflect.DeepEqual(config, expected) {
+		t.Errorf("unexpected backend config %v", config)
+	}
+}
+
+func TestLocalBackend(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "generated", "aws", "vpc") + "/"
+
+	backend, _ := NewStateBackend("local", "", nil)
+	if backend.BackendConfig(path) != nil {
+		t.Error("default local backend doesn't need backend block")
+	}
+	if err := backend.Upload(path, []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadFile(filepath.Join(path, "terraform.tfstate")); err != nil {
+		t.Error(err)
+	}
+	if config := backend.RemoteStateConfig(path, filepath.Join(dir, "generated", "aws", "subnet")); config["path"] != "../vpc/terraform.tfstate" {
+		t.Errorf("unexpected remote state config %v", config)
+	}
+
+	backend, _ = NewStateBackend("local", "", map[string]string{"path": filepath.Join(dir, "states", "{path}.tfstate")})
+	if err := backend.Upload("aws/vpc/", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ioutil.ReadFile(filepath.Join(dir, "states", "aws", "vpc.tfstate")); err != nil {
+		t.Error(err)
+	}
+}
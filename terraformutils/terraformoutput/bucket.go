@@ -15,45 +15,67 @@ package terraformoutput
 
 import (
 	"context"
-	"log"
+	"errors"
+	"io/ioutil"
 	"strings"
 
 	"cloud.google.com/go/storage"
 )
 
+// BucketState stores state in a Google Cloud Storage bucket
 type BucketState struct {
-	Name string
+	Name   string
+	Prefix string
+	Config map[string]string
+}
+
+func (b BucketState) BackendName() string {
+	return "gcs"
+}
+
+func (b BucketState) BackendConfig(path string) map[string]interface{} {
+	return backendConfig(b.Config, path, map[string]interface{}{
+		"bucket": b.bucketName(),
+		"prefix": b.BucketPrefix(path),
+	})
+}
+
+func (b BucketState) RemoteStateConfig(path, fromPath string) map[string]interface{} {
+	return b.BackendConfig(path)
 }
 
 func (b BucketState) BucketGetTfData(path string) interface{} {
-	name := strings.ReplaceAll(b.Name, "gs://", "")
-	bucketStateData := map[string]interface{}{
-		"terraform": map[string]interface{}{
-			"backend": []map[string]interface{}{
-				{
-					"gcs": map[string]interface{}{
-						"bucket": name,
-						"prefix": b.BucketPrefix(path),
-					},
-				},
-			},
-		},
-	}
-	return bucketStateData
+	return BackendGetTfData(b, path)
 }
 
 func (b BucketState) BucketPrefix(path string) string {
-	return strings.TrimSuffix(path, "/")
+	if b.Prefix != "" {
+		return expandPath(b.Prefix, path)
+	}
+	return trimPath(path)
+}
+
+func (b BucketState) bucketName() string {
+	return strings.TrimPrefix(b.Name, "gs://")
+}
+
+// gcs backend keeps state of the default workspace in <prefix>/default.tfstate
+func (b BucketState) objectName(path string) string {
+	return b.BucketPrefix(path) + "/default.tfstate"
+}
+
+func (b BucketState) Upload(path string, file []byte) error {
+	return b.BucketUpload(path, file)
 }
 
 func (b BucketState) BucketUpload(path string, file []byte) error {
 	ctx := context.Background()
 	client, err := storage.NewClient(ctx)
 	if err != nil {
-		log.Fatalf("Failed to create client: %v", err)
+		return err
 	}
-	name := strings.ReplaceAll(b.Name, "gs://", "")
-	wc := client.Bucket(name).Object(b.BucketPrefix(path) + "/default.tfstate").NewWriter(ctx)
+	defer client.Close()
+	wc := client.Bucket(b.bucketName()).Object(b.objectName(path)).NewWriter(ctx)
 	if _, err = wc.Write(file); err != nil {
 		return err
 	}
@@ -62,3 +84,21 @@ func (b BucketState) BucketUpload(path string, file []byte) error {
 	}
 	return nil
 }
+
+func (b BucketState) Download(path string) ([]byte, error) {
+	ctx := context.Background()
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	rc, err := client.Bucket(b.bucketName()).Object(b.objectName(path)).NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
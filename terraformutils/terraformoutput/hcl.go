@@ -22,14 +22,55 @@ import (
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
 
+	"github.com/hashicorp/terraform/providers"
 	"github.com/hashicorp/terraform/terraform"
 )
 
-func OutputHclFiles(resources []terraformutils.Resource, provider terraformutils.ProviderGenerator, path string, serviceName string, isCompact bool, output string, sort bool) error {
+// OutputHclFiles prints resources of a service to path, schema is used by hcl2 output to tell blocks from attributes
+func OutputHclFiles(resources []terraformutils.Resource, provider terraformutils.ProviderGenerator, path string, serviceName string, isCompact bool, output string, sort bool, schema *providers.GetSchemaResponse) error {
 	if err := os.MkdirAll(path, os.ModePerm); err != nil {
 		return err
 	}
 
+	if err := printProviderFile(provider, path, output, sort, schema); err != nil {
+		return err
+	}
+
+	// create outputs files
+	outputsByResource := resourcesOutputs(resources, provider, serviceName)
+	if len(outputsByResource) > 0 {
+		outputs := map[string]interface{}{
+			"output": outputsByResource,
+		}
+		outputsFile, err := terraformutils.Print(outputs, map[string]struct{}{}, output, sort)
+		if err != nil {
+			return err
+		}
+		PrintFile(path+"/outputs."+GetFileExtension(output), outputsFile)
+	}
+
+	// group by resource by type
+	typeOfServices := map[string][]terraformutils.Resource{}
+	for _, r := range resources {
+		typeOfServices[r.InstanceInfo.Type] = append(typeOfServices[r.InstanceInfo.Type], r)
+	}
+	if isCompact {
+		err := printFile(resources, "resources", path, output, sort, schema)
+		if err != nil {
+			return err
+		}
+	} else {
+		for k, v := range typeOfServices {
+			err := printFile(v, typeFileName(k), path, output, sort, schema)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func printProviderFile(provider terraformutils.ProviderGenerator, path string, output string, sort bool, schema *providers.GetSchemaResponse) error {
 	providerConfig := map[string]interface{}{
 		"version": providerwrapper.GetProviderVersion(provider.GetName()),
 	}
@@ -46,14 +87,16 @@ func OutputHclFiles(resources []terraformutils.Resource, provider terraformutils
 		}},
 	}
 
-	providerDataFile, err := terraformutils.Print(providerData, map[string]struct{}{}, output, sort)
+	providerDataFile, err := terraformutils.PrintWithSchema(providerData, map[string]struct{}{}, output, sort, schema)
 	if err != nil {
 		return err
 	}
 	PrintFile(path+"/provider."+GetFileExtension(output), providerDataFile)
+	return nil
+}
 
-	// create outputs files
-	outputs := map[string]interface{}{}
+// resourcesOutputs returns outputs used by other services to connect with resources, and sets them to resources state
+func resourcesOutputs(resources []terraformutils.Resource, provider terraformutils.ProviderGenerator, serviceName string) map[string]map[string]interface{} {
 	outputsByResource := map[string]map[string]interface{}{}
 
 	for i, r := range resources {
@@ -85,40 +128,48 @@ func OutputHclFiles(resources []terraformutils.Resource, provider terraformutils
 				}
 			}
 		}
+		for _, attribute := range r.LinkedAttributes {
+			linkKey := r.InstanceInfo.Type + "_" + r.ResourceName + "_" + attribute
+			value := r.InstanceState.Attributes[attribute]
+			if attribute == "id" {
+				value = r.InstanceState.ID
+			}
+			outputsByResource[linkKey] = map[string]interface{}{
+				"value": "${" + r.InstanceInfo.Type + "." + r.ResourceName + "." + attribute + "}",
+			}
+			outputState[linkKey] = &terraform.OutputState{
+				Type:  "string",
+				Value: value,
+			}
+		}
 		resources[i].Outputs = outputState
 	}
-	if len(outputsByResource) > 0 {
-		outputs["output"] = outputsByResource
-		outputsFile, err := terraformutils.Print(outputs, map[string]struct{}{}, output, sort)
-		if err != nil {
-			return err
-		}
-		PrintFile(path+"/outputs."+GetFileExtension(output), outputsFile)
+	return outputsByResource
+}
+
+// file name for resources of a type, without provider prefix
+func typeFileName(resourceType string) string {
+	return strings.ReplaceAll(resourceType, strings.Split(resourceType, "_")[0]+"_", "")
+}
+
+func printFile(v []terraformutils.Resource, fileName, path, output string, sort bool, schema *providers.GetSchemaResponse) error {
+	if err := printDataFiles(v, path); err != nil {
+		return err
 	}
 
-	// group by resource by type
-	typeOfServices := map[string][]terraformutils.Resource{}
-	for _, r := range resources {
-		typeOfServices[r.InstanceInfo.Type] = append(typeOfServices[r.InstanceInfo.Type], r)
+	tfFile, err := terraformutils.HclPrintResource(v, map[string]interface{}{}, output, sort, schema)
+	if err != nil {
+		return err
 	}
-	if isCompact {
-		err := printFile(resources, "resources", path, output, sort)
-		if err != nil {
-			return err
-		}
-	} else {
-		for k, v := range typeOfServices {
-			fileName := strings.ReplaceAll(k, strings.Split(k, "_")[0]+"_", "")
-			err := printFile(v, fileName, path, output, sort)
-			if err != nil {
-				return err
-			}
-		}
+	err = ioutil.WriteFile(path+"/"+fileName+"."+GetFileExtension(output), tfFile, os.ModePerm)
+	if err != nil {
+		return err
 	}
+
 	return nil
 }
 
-func printFile(v []terraformutils.Resource, fileName, path, output string, sort bool) error {
+func printDataFiles(v []terraformutils.Resource, path string) error {
 	for _, res := range v {
 		if res.DataFiles == nil {
 			continue
@@ -133,16 +184,6 @@ func printFile(v []terraformutils.Resource, fileName, path, output string, sort
 			}
 		}
 	}
-
-	tfFile, err := terraformutils.HclPrintResource(v, map[string]interface{}{}, output, sort)
-	if err != nil {
-		return err
-	}
-	err = ioutil.WriteFile(path+"/"+fileName+"."+GetFileExtension(output), tfFile, os.ModePerm)
-	if err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -0,0 +1,122 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformoutput
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// HTTPState stores state in a REST endpoint as used by the terraform http backend
+type HTTPState struct {
+	Address  string
+	Username string
+	Password string
+	Config   map[string]string
+}
+
+func NewHTTPState(settings map[string]string) (*HTTPState, error) {
+	address := settings["address"]
+	if address == "" {
+		return nil, errors.New("http state backend requires an address")
+	}
+	// every directory needs its own state
+	if !strings.Contains(address, PathPlaceholder) {
+		address = strings.TrimSuffix(address, "/") + "/" + PathPlaceholder
+	}
+	password := settings["password"]
+	if password == "" {
+		password = os.Getenv("TF_HTTP_PASSWORD")
+	}
+	return &HTTPState{
+		Address:  address,
+		Username: settings["username"],
+		Password: password,
+		Config:   settings,
+	}, nil
+}
+
+func (h HTTPState) BackendName() string {
+	return "http"
+}
+
+func (h HTTPState) BackendConfig(path string) map[string]interface{} {
+	return backendConfig(h.Config, path, map[string]interface{}{
+		"address": h.StateAddress(path),
+	})
+}
+
+func (h HTTPState) RemoteStateConfig(path, fromPath string) map[string]interface{} {
+	return h.BackendConfig(path)
+}
+
+func (h HTTPState) StateAddress(path string) string {
+	return expandPath(h.Address, path)
+}
+
+func (h HTTPState) Upload(path string, file []byte) error {
+	method := h.Config["update_method"]
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, h.StateAddress(path), bytes.NewReader(file))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Username != "" {
+		req.SetBasicAuth(h.Username, h.Password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to upload state to %s: %s", h.StateAddress(path), resp.Status)
+	}
+	return nil
+}
+
+func (h HTTPState) Download(path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, h.StateAddress(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	if h.Username != "" {
+		req.SetBasicAuth(h.Username, h.Password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to download state from %s: %s", h.StateAddress(path), resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformoutput
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const defaultStateFileName = "terraform.tfstate"
+
+// LocalState stores state on the filesystem, by default next to generated resources
+type LocalState struct {
+	// Path of the state file, relative to the current directory. Supports {path} placeholder.
+	Path string
+}
+
+func (l LocalState) BackendName() string {
+	return "local"
+}
+
+func (l LocalState) BackendConfig(path string) map[string]interface{} {
+	if l.Path == "" {
+		return nil
+	}
+	return map[string]interface{}{
+		"path": l.relativeStatePath(path, path),
+	}
+}
+
+func (l LocalState) RemoteStateConfig(path, fromPath string) map[string]interface{} {
+	return map[string]interface{}{
+		"path": l.relativeStatePath(path, fromPath),
+	}
+}
+
+func (l LocalState) StatePath(path string) string {
+	if l.Path == "" {
+		return filepath.Join(path, defaultStateFileName)
+	}
+	return filepath.Clean(expandPath(l.Path, path))
+}
+
+// terraform resolves state path from the directory it runs in
+func (l LocalState) relativeStatePath(path, fromPath string) string {
+	statePath := l.StatePath(path)
+	if filepath.IsAbs(statePath) != filepath.IsAbs(fromPath) {
+		return filepath.ToSlash(statePath)
+	}
+	relativePath, err := filepath.Rel(fromPath, statePath)
+	if err != nil {
+		return filepath.ToSlash(statePath)
+	}
+	return filepath.ToSlash(relativePath)
+}
+
+func (l LocalState) Upload(path string, file []byte) error {
+	statePath := l.StatePath(path)
+	if err := os.MkdirAll(filepath.Dir(statePath), os.ModePerm); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(statePath, file, os.ModePerm)
+}
+
+func (l LocalState) Download(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(l.StatePath(path))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
@@ -0,0 +1,301 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformoutput
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// StateReader is implemented by backends which can read back previously stored state
+type StateReader interface {
+	// Download returns the state stored for resources generated in path, nil if there is none
+	Download(path string) ([]byte, error)
+}
+
+// PreviousOutput is what a previous run generated in a directory
+type PreviousOutput struct {
+	// State is nil when there is no state or backend can't read it
+	State *terraformutils.StateV4
+	// Resources with IDs, from state and import blocks
+	Resources []terraformutils.ExistingResource
+	// Declared are addresses of resources declared in HCL files
+	Declared map[string]struct{}
+}
+
+type jsonConfigFile struct {
+	Resource map[string]map[string]interface{} `json:"resource"`
+	Import   []struct {
+		To string `json:"to"`
+		ID string `json:"id"`
+	} `json:"import"`
+}
+
+// ReadPreviousOutput reads state and HCL files generated in path by a previous run
+func ReadPreviousOutput(path string, backend StateBackend) (*PreviousOutput, error) {
+	previous := &PreviousOutput{
+		Declared: map[string]struct{}{},
+	}
+	known := map[string]struct{}{}
+	if reader, ok := backend.(StateReader); ok {
+		data, err := reader.Download(path)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			previous.State, err = terraformutils.ReadTfState(data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read state of %s: %v", path, err)
+			}
+			previous.Resources = previous.State.ExistingResources()
+			for _, r := range previous.Resources {
+				known[r.Address()] = struct{}{}
+			}
+		}
+	}
+
+	files, err := ioutil.ReadDir(path)
+	if os.IsNotExist(err) {
+		return previous, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		var imported []terraformutils.ExistingResource
+		fileName := filepath.Join(path, file.Name())
+		switch {
+		case strings.HasSuffix(file.Name(), ".tf.json"):
+			imported, err = readJSONConfigFile(fileName, previous.Declared)
+		case strings.HasSuffix(file.Name(), ".tf"):
+			imported, err = readHclConfigFile(fileName, previous.Declared)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range imported {
+			if _, exist := known[r.Address()]; !exist {
+				previous.Resources = append(previous.Resources, r)
+				known[r.Address()] = struct{}{}
+			}
+		}
+	}
+	return previous, nil
+}
+
+func readHclConfigFile(fileName string, declared map[string]struct{}) ([]terraformutils.ExistingResource, error) {
+	src, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	file, diags := hclsyntax.ParseConfig(src, fileName, hcl.Pos{Line: 1, Column: 1})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	var imported []terraformutils.ExistingResource
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		switch block.Type {
+		case "resource":
+			if len(block.Labels) == 2 {
+				declared[block.Labels[0]+"."+block.Labels[1]] = struct{}{}
+			}
+		case "import":
+			to, exist := block.Body.Attributes["to"]
+			if !exist {
+				continue
+			}
+			id, exist := block.Body.Attributes["id"]
+			if !exist {
+				continue
+			}
+			traversal, diags := hcl.AbsTraversalForExpr(to.Expr)
+			if diags.HasErrors() || len(traversal) != 2 {
+				continue
+			}
+			name, ok := traversal[1].(hcl.TraverseAttr)
+			if !ok {
+				continue
+			}
+			idValue, diags := id.Expr.Value(nil)
+			if diags.HasErrors() || idValue.Type() != cty.String || !idValue.IsKnown() || idValue.IsNull() {
+				continue
+			}
+			imported = append(imported, terraformutils.ExistingResource{
+				Type: traversal.RootName(),
+				Name: name.Name,
+				ID:   idValue.AsString(),
+			})
+		}
+	}
+	return imported, nil
+}
+
+func readJSONConfigFile(fileName string, declared map[string]struct{}) ([]terraformutils.ExistingResource, error) {
+	src, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	config := jsonConfigFile{}
+	if err := json.Unmarshal(src, &config); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", fileName, err)
+	}
+	for resourceType, resources := range config.Resource {
+		for name := range resources {
+			declared[resourceType+"."+name] = struct{}{}
+		}
+	}
+	var imported []terraformutils.ExistingResource
+	for _, block := range config.Import {
+		parts := strings.SplitN(block.To, ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		imported = append(imported, terraformutils.ExistingResource{
+			Type: parts[0],
+			Name: parts[1],
+			ID:   strings.NewReplacer("$${", "${", "%%{", "%{").Replace(block.ID),
+		})
+	}
+	return imported, nil
+}
+
+// MergeHclFiles adds resources new according to merge to HCL files in path. Existing files are never rewritten,
+// new resources and their outputs are appended.
+func MergeHclFiles(resources []terraformutils.Resource, merge *terraformutils.MergeResult, provider terraformutils.ProviderGenerator, path string, serviceName string, isCompact bool, output string, sort bool, schema *providers.GetSchemaResponse) error {
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path + "/provider." + GetFileExtension(output)); os.IsNotExist(err) {
+		if err := printProviderFile(provider, path, output, sort, schema); err != nil {
+			return err
+		}
+	}
+
+	// outputs of all resources are needed in state
+	resourcesOutputs(resources, provider, serviceName)
+
+	var newResources []terraformutils.Resource
+	for _, r := range resources {
+		if merge.IsNew(r) {
+			newResources = append(newResources, r)
+		}
+	}
+	if len(newResources) == 0 {
+		return nil
+	}
+	outputsByResource := resourcesOutputs(newResources, provider, serviceName)
+	if len(outputsByResource) > 0 {
+		outputs := map[string]interface{}{
+			"output": outputsByResource,
+		}
+		outputsFile, err := terraformutils.Print(outputs, map[string]struct{}{}, output, sort)
+		if err != nil {
+			return err
+		}
+		if err := appendFile(path+"/outputs."+GetFileExtension(output), outputsFile, output); err != nil {
+			return err
+		}
+	}
+
+	typeOfServices := map[string][]terraformutils.Resource{}
+	for _, r := range newResources {
+		fileName := typeFileName(r.InstanceInfo.Type)
+		if isCompact {
+			fileName = "resources"
+		}
+		typeOfServices[fileName] = append(typeOfServices[fileName], r)
+	}
+	for fileName, v := range typeOfServices {
+		if err := printDataFiles(v, path); err != nil {
+			return err
+		}
+		tfFile, err := terraformutils.HclPrintResource(v, map[string]interface{}{}, output, sort, schema)
+		if err != nil {
+			return err
+		}
+		if err := appendFile(path+"/"+fileName+"."+GetFileExtension(output), tfFile, output); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendFile appends HCL content to the file, JSON content is merged into the existing object
+func appendFile(fileName string, content []byte, output string) error {
+	existing, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return ioutil.WriteFile(fileName, content, os.ModePerm)
+	}
+	if err != nil {
+		return err
+	}
+	if output == "json" {
+		content, err = mergeJSON(existing, content)
+		if err != nil {
+			return fmt.Errorf("failed to merge %s: %v", fileName, err)
+		}
+		return ioutil.WriteFile(fileName, content, os.ModePerm)
+	}
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		existing = append(existing, '\n')
+	}
+	existing = append(existing, '\n')
+	return ioutil.WriteFile(fileName, append(existing, content...), os.ModePerm)
+}
+
+func mergeJSON(existing, content []byte) ([]byte, error) {
+	existingData := map[string]interface{}{}
+	if err := json.Unmarshal(existing, &existingData); err != nil {
+		return nil, err
+	}
+	contentData := map[string]interface{}{}
+	if err := json.Unmarshal(content, &contentData); err != nil {
+		return nil, err
+	}
+	mergeMaps(existingData, contentData)
+	return json.MarshalIndent(existingData, "", "  ")
+}
+
+// mergeMaps adds keys of src missing in dst, values already in dst win
+func mergeMaps(dst, src map[string]interface{}) {
+	for k, v := range src {
+		dstValue, exist := dst[k]
+		if !exist {
+			dst[k] = v
+			continue
+		}
+		dstMap, dstIsMap := dstValue.(map[string]interface{})
+		srcMap, srcIsMap := v.(map[string]interface{})
+		if dstIsMap && srcIsMap {
+			mergeMaps(dstMap, srcMap)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformoutput
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraformer/terraformutils"
+)
+
+func TestReadPreviousOutput(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"vpc.tf": `resource "aws_vpc" "tfer--main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_vpc" "tfer--vpc-2" {
+}
+`,
+		"imports.tf": `import {
+  to = aws_vpc.tfer--vpc-2
+  id = "vpc-2"
+}
+`,
+		"subnet.tf.json": `{"resource": {"aws_subnet": {"tfer--subnet-1": {}}}, "import": [{"to": "aws_subnet.tfer--subnet-1", "id": "subnet-1"}]}`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, err := terraformutils.PrintTfStateV4([]terraformutils.Resource{
+		terraformutils.NewSimpleResource("vpc-1", "main", "aws_vpc", "aws", []string{}),
+	}, "hashicorp/aws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend := LocalState{}
+	if err := backend.Upload(dir, state); err != nil {
+		t.Fatal(err)
+	}
+
+	previous, err := ReadPreviousOutput(dir, backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous.State == nil {
+		t.Fatal("expected previous state")
+	}
+	expected := map[string]string{
+		"aws_vpc.tfer--main":        "vpc-1",
+		"aws_vpc.tfer--vpc-2":       "vpc-2",
+		"aws_subnet.tfer--subnet-1": "subnet-1",
+	}
+	found := map[string]string{}
+	for _, r := range previous.Resources {
+		found[r.Address()] = r.ID
+	}
+	if !reflect.DeepEqual(found, expected) {
+		t.Errorf("expected %v, got %v", expected, found)
+	}
+	for _, address := range []string{"aws_vpc.tfer--main", "aws_vpc.tfer--vpc-2", "aws_subnet.tfer--subnet-1"} {
+		if _, exist := previous.Declared[address]; !exist {
+			t.Errorf("%s should be declared", address)
+		}
+	}
+}
+
+func TestAppendFile(t *testing.T) {
+	dir := t.TempDir()
+	hclFile := filepath.Join(dir, "vpc.tf")
+	if err := ioutil.WriteFile(hclFile, []byte(`resource "aws_vpc" "main" {}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendFile(hclFile, []byte(`resource "aws_vpc" "other" {}`+"\n"), "hcl"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadFile(hclFile)
+	if expected := "resource \"aws_vpc\" \"main\" {}\n\nresource \"aws_vpc\" \"other\" {}\n"; string(content) != expected {
+		t.Errorf("unexpected content %q", string(content))
+	}
+
+	jsonFile := filepath.Join(dir, "vpc.tf.json")
+	if err := ioutil.WriteFile(jsonFile, []byte(`{"resource": {"aws_vpc": {"main": {"cidr_block": "10.0.0.0/16"}}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendFile(jsonFile, []byte(`{"resource": {"aws_vpc": {"main": {}, "other": {}}}}`), "json"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ = ioutil.ReadFile(jsonFile)
+	merged := map[string]map[string]map[string]map[string]interface{}{}
+	if err := json.Unmarshal(content, &merged); err != nil {
+		t.Fatal(err)
+	}
+	vpcs := merged["resource"]["aws_vpc"]
+	if len(vpcs) != 2 || vpcs["main"]["cidr_block"] != "10.0.0.0/16" {
+		t.Errorf("unexpected merged content %s", string(content))
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformoutput
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3State stores state in an AWS S3 (or S3 compatible) bucket
+type S3State struct {
+	Bucket        string
+	Key           string
+	Region        string
+	DynamoDBTable string
+	Endpoint      string
+	Profile       string
+	Config        map[string]string
+}
+
+func NewS3State(bucket string, settings map[string]string) (*S3State, error) {
+	if settings["bucket"] != "" {
+		bucket = settings["bucket"]
+	}
+	bucket = strings.TrimPrefix(bucket, "s3://")
+	if bucket == "" {
+		return nil, errors.New("s3 state backend requires a bucket")
+	}
+	return &S3State{
+		Bucket:        bucket,
+		Key:           settings["key"],
+		Region:        settings["region"],
+		DynamoDBTable: settings["dynamodb_table"],
+		Endpoint:      settings["endpoint"],
+		Profile:       settings["profile"],
+		Config:        settings,
+	}, nil
+}
+
+func (s S3State) BackendName() string {
+	return "s3"
+}
+
+func (s S3State) BackendConfig(path string) map[string]interface{} {
+	defaults := map[string]interface{}{
+		"bucket": s.Bucket,
+		"key":    s.ObjectKey(path),
+	}
+	if s.Region != "" {
+		defaults["region"] = s.Region
+	}
+	if s.DynamoDBTable != "" {
+		defaults["dynamodb_table"] = s.DynamoDBTable
+	}
+	if s.Endpoint != "" {
+		defaults["endpoint"] = s.Endpoint
+		defaults["force_path_style"] = s.forcePathStyle()
+	}
+	return backendConfig(s.Config, path, defaults)
+}
+
+func (s S3State) RemoteStateConfig(path, fromPath string) map[string]interface{} {
+	return s.BackendConfig(path)
+}
+
+func (s S3State) ObjectKey(path string) string {
+	if s.Key != "" {
+		return expandPath(s.Key, path)
+	}
+	return trimPath(path) + "/terraform.tfstate"
+}
+
+// custom endpoints (MinIO, LocalStack...) mostly don't support virtual hosted buckets
+func (s S3State) forcePathStyle() bool {
+	if v, ok := s.Config["force_path_style"]; ok {
+		forcePathStyle, err := strconv.ParseBool(v)
+		return err != nil || forcePathStyle
+	}
+	return true
+}
+
+func (s S3State) client(ctx context.Context) (*s3.Client, error) {
+	var loadOptions []func(*config.LoadOptions) error
+	if s.Region != "" {
+		loadOptions = append(loadOptions, config.WithRegion(s.Region))
+	}
+	if s.Profile != "" {
+		loadOptions = append(loadOptions, config.WithSharedConfigProfile(s.Profile))
+	}
+	// static credentials of the backend settings win over the profile and the environment, as in the s3 backend
+	if s.Config["access_key"] != "" && s.Config["secret_key"] != "" {
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			s.Config["access_key"], s.Config["secret_key"], s.Config["token"])))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if s.Endpoint != "" {
+			o.EndpointResolver = s3.EndpointResolverFromURL(s.Endpoint)
+			o.UsePathStyle = s.forcePathStyle()
+		}
+	}), nil
+}
+
+func (s S3State) Upload(path string, file []byte) error {
+	ctx := context.Background()
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(s.ObjectKey(path)),
+		Body:        bytes.NewReader(file),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
+
+func (s S3State) Download(path string) ([]byte, error) {
+	ctx := context.Background()
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.ObjectKey(path)),
+	})
+	var responseError *awshttp.ResponseError
+	if errors.As(err, &responseError) && responseError.HTTPStatusCode() == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+	return ioutil.ReadAll(output.Body)
+}
@@ -0,0 +1,34 @@
+resource "aws_glue_job" "tfer--etl" {
+  default_arguments = {
+    "--TempDir"      = "s3://bucket/${path}"
+    "--job-language" = "python"
+  }
+  max_capacity = 2.5
+  name         = "etl"
+  command {
+    script_location = "s3://bucket/etl.py"
+  }
+  notification_property {
+    notify_delay_after = 5
+  }
+}
+
+resource "aws_security_group" "tfer--web" {
+  depends_on = [aws_vpc.tfer--main]
+  name       = "web"
+  tags = {
+    Name                         = "web"
+    "kubernetes.io/cluster/main" = "owned"
+  }
+  vpc_id = aws_vpc.tfer--main.id
+  ingress {
+    cidr_blocks = ["0.0.0.0/0"]
+    from_port   = 80
+    protocol    = "tcp"
+  }
+  ingress {
+    cidr_blocks = ["10.0.0.0/8"]
+    from_port   = 443
+    protocol    = "tcp"
+  }
+}
@@ -0,0 +1,40 @@
+resource "aws_iam_role" "tfer--app" {
+  assume_role_policy = jsonencode({
+    Statement = [{
+      Action = "sts:AssumeRole"
+      Effect = "Allow"
+      Principal = {
+        Service = "ec2.amazonaws.com"
+      }
+    }]
+    Version = "2012-10-17"
+  })
+  description = "line \"one\"\nline two"
+  name        = "app"
+}
+
+resource "aws_iam_role" "tfer--ci" {
+  assume_role_policy = aws_iam_role.tfer--app.assume_role_policy
+  description        = <<EOT
+Used by "ci" jobs
+  of ${var.team}
+EOT
+  name               = "ci"
+}
+
+resource "aws_iam_role_policy" "tfer--app_s3" {
+  policy = jsonencode({
+    Statement = [{
+      Action = "s3:GetObject"
+      Condition = {
+        StringEquals = {
+          "aws:SourceArn" = aws_iam_role.tfer--app.arn
+        }
+      }
+      Effect   = "Allow"
+      Resource = "arn:aws:s3:::bucket/$${aws:username}/*"
+    }]
+    Version = "2012-10-17"
+  })
+  role = aws_iam_role.tfer--app.name
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/hashicorp/terraform/terraform"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+const providerRegistryHost = "registry.terraform.io"
+const defaultProviderNamespace = "hashicorp"
+
+// StateV4 is the JSON representation of Terraform state format version 4, as read by Terraform 0.12+
+type StateV4 struct {
+	Version          int                      `json:"version"`
+	TerraformVersion string                   `json:"terraform_version"`
+	Serial           uint64                   `json:"serial"`
+	Lineage          string                   `json:"lineage"`
+	Outputs          map[string]OutputStateV4 `json:"outputs"`
+	Resources        []ResourceStateV4        `json:"resources"`
+}
+
+type OutputStateV4 struct {
+	Value     json.RawMessage `json:"value"`
+	Type      json.RawMessage `json:"type"`
+	Sensitive bool            `json:"sensitive,omitempty"`
+}
+
+type ResourceStateV4 struct {
+	Mode      string            `json:"mode"`
+	Type      string            `json:"type"`
+	Name      string            `json:"name"`
+	Provider  string            `json:"provider"`
+	Instances []InstanceStateV4 `json:"instances"`
+}
+
+type InstanceStateV4 struct {
+	SchemaVersion  uint64            `json:"schema_version"`
+	Attributes     json.RawMessage   `json:"attributes,omitempty"`
+	AttributesFlat map[string]string `json:"attributes_flat,omitempty"`
+	Dependencies   []string          `json:"dependencies,omitempty"`
+}
+
+// ProviderSource returns provider source address as used in required_providers, e.g. hashicorp/aws
+func ProviderSource(provider ProviderGenerator) string {
+	if providerWithSource, ok := provider.(ProviderWithSource); ok {
+		return providerWithSource.GetSource()
+	}
+	return defaultProviderNamespace + "/" + provider.GetName()
+}
+
+// ProviderAddress returns fully qualified provider config address used in state, e.g. provider["registry.terraform.io/hashicorp/aws"]
+func ProviderAddress(providerSource string) string {
+	return fmt.Sprintf(`provider["%s/%s"]`, providerRegistryHost, providerSource)
+}
+
+// NewTfStateV4 builds a version 4 state. Attributes are typed with the provider schema,
+// resources without schema fall back to flatmap attributes.
+func NewTfStateV4(resources []Resource, providerSource string, schema *providers.GetSchemaResponse) (*StateV4, error) {
+	lineage, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	state := &StateV4{
+		Version:          4,
+		TerraformVersion: terraform.VersionString(), //nolint
+		Serial:           1,
+		Lineage:          lineage,
+		Outputs:          map[string]OutputStateV4{},
+		Resources:        []ResourceStateV4{},
+	}
+	for _, r := range resources {
+		for k, v := range r.Outputs {
+			value, err := json.Marshal(v.Value)
+			if err != nil {
+				return nil, err
+			}
+			state.Outputs[k] = OutputStateV4{
+				Value:     value,
+				Type:      json.RawMessage(`"string"`),
+				Sensitive: v.Sensitive,
+			}
+		}
+	}
+	for _, r := range resources {
+		instance, err := newInstanceStateV4(r, schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert state of %s.%s: %v", r.InstanceInfo.Type, r.ResourceName, err)
+		}
+		state.Resources = append(state.Resources, ResourceStateV4{
+			Mode:      "managed",
+			Type:      r.InstanceInfo.Type,
+			Name:      r.ResourceName,
+			Provider:  ProviderAddress(providerSource),
+			Instances: []InstanceStateV4{instance},
+		})
+	}
+	state.sortResources()
+	return state, nil
+}
+
+func (s *StateV4) sortResources() {
+	sort.SliceStable(s.Resources, func(i, j int) bool {
+		if s.Resources[i].Type != s.Resources[j].Type {
+			return s.Resources[i].Type < s.Resources[j].Type
+		}
+		return s.Resources[i].Name < s.Resources[j].Name
+	})
+}
+
+func (s *StateV4) Print() ([]byte, error) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ID returns id attribute of the instance
+func (i InstanceStateV4) ID() string {
+	if i.AttributesFlat != nil {
+		return i.AttributesFlat["id"]
+	}
+	attributes := map[string]interface{}{}
+	if err := json.Unmarshal(i.Attributes, &attributes); err != nil {
+		return ""
+	}
+	id, _ := attributes["id"].(string)
+	return id
+}
+
+func newInstanceStateV4(r Resource, schema *providers.GetSchemaResponse) (InstanceStateV4, error) {
+	instance := InstanceStateV4{}
+	var resourceSchema providers.Schema
+	exist := false
+	if schema != nil {
+		resourceSchema, exist = schema.ResourceTypes[r.InstanceInfo.Type]
+	}
+	if !exist || resourceSchema.Block == nil {
+		instance.AttributesFlat = flatAttributes(r)
+		return instance, nil
+	}
+	instance.SchemaVersion = uint64(resourceSchema.Version)
+	impliedType := resourceSchema.Block.ImpliedType()
+	value, err := r.InstanceState.AttrsAsObjectValue(impliedType)
+	if err != nil {
+		return instance, err
+	}
+	attributes, err := ctyjson.Marshal(value, impliedType)
+	if err != nil {
+		return instance, err
+	}
+	instance.Attributes = attributes
+	return instance, nil
+}
+
+// flatAttributes returns flatmap attributes including id, as terraform keeps it in flatmap state
+func flatAttributes(r Resource) map[string]string {
+	if _, exist := r.InstanceState.Attributes["id"]; exist || r.InstanceState.ID == "" {
+		return r.InstanceState.Attributes
+	}
+	attributes := map[string]string{"id": r.InstanceState.ID}
+	for k, v := range r.InstanceState.Attributes {
+		attributes[k] = v
+	}
+	return attributes
+}
+
+func PrintTfStateV4(resources []Resource, providerSource string, schema *providers.GetSchemaResponse) ([]byte, error) {
+	state, err := NewTfStateV4(resources, providerSource, schema)
+	if err != nil {
+		return nil, err
+	}
+	return state.Print()
+}
+
+type stateV3 struct {
+	Version int `json:"version"`
+	Serial  uint64
+	Lineage string
+	Modules []struct {
+		Path      []string
+		Resources map[string]struct {
+			Type    string
+			Primary struct {
+				ID         string
+				Attributes map[string]string
+			}
+		}
+	}
+}
+
+// ReadTfState reads state written by Terraformer or Terraform. Legacy version 3 states are converted to
+// version 4 with flatmap attributes and without provider.
+func ReadTfState(data []byte) (*StateV4, error) {
+	header := struct {
+		Version int `json:"version"`
+	}{}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	switch header.Version {
+	case 4:
+		state := &StateV4{}
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, err
+		}
+		return state, nil
+	case 3:
+		legacy := stateV3{}
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return nil, err
+		}
+		state := &StateV4{
+			Version:   4,
+			Serial:    legacy.Serial,
+			Lineage:   legacy.Lineage,
+			Outputs:   map[string]OutputStateV4{},
+			Resources: []ResourceStateV4{},
+		}
+		for _, module := range legacy.Modules {
+			if len(module.Path) != 1 || module.Path[0] != "root" {
+				continue
+			}
+			for key, r := range module.Resources {
+				attributes := r.Primary.Attributes
+				if attributes == nil {
+					attributes = map[string]string{}
+				}
+				attributes["id"] = r.Primary.ID
+				state.Resources = append(state.Resources, ResourceStateV4{
+					Mode:      "managed",
+					Type:      r.Type,
+					Name:      strings.TrimPrefix(key, r.Type+"."),
+					Instances: []InstanceStateV4{{AttributesFlat: attributes}},
+				})
+			}
+		}
+		state.sortResources()
+		return state, nil
+	}
+	return nil, fmt.Errorf("unsupported state version %d", header.Version)
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 The Terraformer Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraformutils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/configs/configschema"
+	"github.com/hashicorp/terraform/providers"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func testSchema() *providers.GetSchemaResponse {
+	return &providers.GetSchemaResponse{
+		ResourceTypes: map[string]providers.Schema{
+			"aws_vpc": {
+				Version: 1,
+				Block: &configschema.Block{
+					Attributes: map[string]*configschema.Attribute{
+						"id":                   {Type: cty.String, Computed: true},
+						"cidr_block":           {Type: cty.String, Optional: true},
+						"enable_dns_hostnames": {Type: cty.Bool, Optional: true},
+						"tags":                 {Type: cty.Map(cty.String), Optional: true},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPrintTfStateV4(t *testing.T) {
+	resources := []Resource{
+		NewResource("vpc-1", "vpc-1", "aws_vpc", "aws", map[string]string{
+			"cidr_block":           "10.0.0.0/16",
+			"enable_dns_hostnames": "true",
+			"tags.%":               "1",
+			"tags.Name":            "main",
+		}, []string{}, map[string]interface{}{}),
+		NewResource("sg-1", "sg-1", "aws_security_group", "aws", map[string]string{
+			"name": "default",
+		}, []string{}, map[string]interface{}{}),
+	}
+	data, err := PrintTfStateV4(resources, "hashicorp/aws", testSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := StateV4{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Version != 4 || state.Lineage == "" || state.Serial != 1 {
+		t.Errorf("unexpected state header %s", string(data))
+	}
+	if len(state.Resources) != 2 {
+		t.Fatalf("expected 2 resources, got %d", len(state.Resources))
+	}
+
+	sg := state.Resources[0]
+	if sg.Type != "aws_security_group" || sg.Instances[0].AttributesFlat["name"] != "default" || sg.Instances[0].Attributes != nil {
+		t.Errorf("resource without schema should keep flat attributes, got %+v", sg)
+	}
+
+	vpc := state.Resources[1]
+	if vpc.Mode != "managed" || vpc.Name != "tfer--vpc-1" || vpc.Provider != `provider["registry.terraform.io/hashicorp/aws"]` {
+		t.Errorf("unexpected resource %+v", vpc)
+	}
+	if vpc.Instances[0].SchemaVersion != 1 {
+		t.Errorf("expected schema version 1, got %d", vpc.Instances[0].SchemaVersion)
+	}
+	attributes := map[string]interface{}{}
+	if err := json.Unmarshal(vpc.Instances[0].Attributes, &attributes); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"id":                   "vpc-1",
+		"cidr_block":           "10.0.0.0/16",
+		"enable_dns_hostnames": true,
+		"tags":                 map[string]interface{}{"Name": "main"},
+	}
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("unexpected attributes %v", attributes)
+	}
+}
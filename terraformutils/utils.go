@@ -15,69 +15,39 @@
 package terraformutils
 
 import (
-	"bytes"
+	"errors"
 	"log"
+	"strings"
 	"sync"
 
 	"github.com/GoogleCloudPlatform/terraformer/terraformutils/providerwrapper"
-
-	"github.com/hashicorp/terraform/terraform"
 )
 
 type BaseResource struct {
 	Tags map[string]string `json:"tags,omitempty"`
 }
 
-func NewTfState(resources []Resource) *terraform.State {
-	tfstate := &terraform.State{
-		Version:   terraform.StateVersion,
-		TFVersion: terraform.VersionString(), //nolint
-		Serial:    1,
-	}
-	outputs := map[string]*terraform.OutputState{}
-	for _, r := range resources {
-		for k, v := range r.Outputs {
-			outputs[k] = v
-		}
-	}
-	tfstate.Modules = []*terraform.ModuleState{
-		{
-			Path:      []string{"root"},
-			Resources: map[string]*terraform.ResourceState{},
-			Outputs:   outputs,
-		},
-	}
-	for _, resource := range resources {
-		resourceState := &terraform.ResourceState{
-			Type:     resource.InstanceInfo.Type,
-			Primary:  resource.InstanceState,
-			Provider: "provider." + resource.Provider,
-		}
-		tfstate.Modules[0].Resources[resource.InstanceInfo.Type+"."+resource.ResourceName] = resourceState
-	}
-	return tfstate
-}
-
-func PrintTfState(resources []Resource) ([]byte, error) {
-	state := NewTfState(resources)
-	var buf bytes.Buffer
-	err := terraform.WriteState(state, &buf)
-	return buf.Bytes(), err
+// RefreshResources refreshes resources, resources which can't be refreshed are dropped and returned as RefreshErrors
+// along with the refreshed ones
+func RefreshResources(resources []*Resource, provider *providerwrapper.ProviderWrapper, slowProcessingResources [][]*Resource, scheduler *Scheduler) ([]*Resource, error) {
+	return refreshResources(resources, func(r *Resource) error {
+		return r.Refresh(provider)
+	}, slowProcessingResources, scheduler)
 }
 
-func RefreshResources(resources []*Resource, provider *providerwrapper.ProviderWrapper, slowProcessingResources [][]*Resource) ([]*Resource, error) {
+func refreshResources(resources []*Resource, refresh func(*Resource) error, slowProcessingResources [][]*Resource, scheduler *Scheduler) ([]*Resource, error) {
 	refreshedResources := []*Resource{}
+	errs := &refreshErrors{errors: map[*Resource]*RefreshError{}}
 	input := make(chan *Resource, len(resources))
 	var wg sync.WaitGroup
-	poolSize := 15
 	for i := range resources {
 		wg.Add(1)
 		input <- resources[i]
 	}
 	close(input)
 
-	for i := 0; i < poolSize; i++ {
-		go RefreshResourceWorker(input, &wg, provider)
+	for i := 0; i < scheduler.Parallelism(); i++ {
+		go RefreshResourceWorker(input, &wg, refresh, scheduler, errs)
 	}
 
 	spInputs := []chan *Resource{}
@@ -91,32 +61,55 @@ func RefreshResources(resources []*Resource, provider *providerwrapper.ProviderW
 
 	for i := 0; i < len(spInputs); i++ {
 		wg.Add(len(slowProcessingResources[i]))
-		go RefreshResourceWorker(spInputs[i], &wg, provider)
+		go slowRefreshResourceWorker(spInputs[i], &wg, refresh, scheduler, errs)
 	}
 
 	wg.Wait()
-	for _, r := range resources {
-		if r.InstanceState != nil && r.InstanceState.ID != "" {
-			refreshedResources = append(refreshedResources, r)
-		} else {
-			log.Printf("ERROR: Unable to refresh resource %s", r.ResourceName)
+	var dropped RefreshErrors
+	keep := func(r *Resource) {
+		if err, failed := errs.errors[r]; failed {
+			log.Printf("ERROR: Unable to refresh resource %s: %v", r.ResourceName, err.Err)
+			dropped = append(dropped, err)
+			return
 		}
+		refreshedResources = append(refreshedResources, r)
+	}
+	for _, r := range resources {
+		keep(r)
 	}
-
 	for _, resourceGroup := range slowProcessingResources {
 		for i := range resourceGroup {
-			r := resourceGroup[i]
-			if r.InstanceState != nil && r.InstanceState.ID != "" {
-				refreshedResources = append(refreshedResources, r)
-			} else {
-				log.Printf("ERROR: Unable to refresh resource %s", r.ResourceName)
-			}
+			keep(resourceGroup[i])
 		}
 	}
+	if len(dropped) > 0 {
+		return refreshedResources, dropped
+	}
 	return refreshedResources, nil
 }
 
-func RefreshResourcesByProvider(providersMapping *ProvidersMapping, providerWrapper *providerwrapper.ProviderWrapper) error {
+// refreshErrors collects errors of resources refreshed concurrently
+type refreshErrors struct {
+	sync.Mutex
+	errors map[*Resource]*RefreshError
+}
+
+func (e *refreshErrors) add(r *Resource, err error) {
+	if err == nil {
+		return
+	}
+	refreshError, ok := err.(*RefreshError)
+	if !ok {
+		refreshError = newRefreshError(r, err)
+	}
+	e.Lock()
+	defer e.Unlock()
+	e.errors[r] = refreshError
+}
+
+// RefreshResourcesByProvider refreshes resources of all services, dropped resources are returned as RefreshErrors
+// with their service, the mapping keeps the refreshed ones. Refreshed states are cached in cache, if any.
+func RefreshResourcesByProvider(providersMapping *ProvidersMapping, providerWrapper *providerwrapper.ProviderWrapper, scheduler *Scheduler, cache *RefreshCache) error {
 	allResources := providersMapping.ShuffleResources()
 	slowProcessingResources := make(map[ProviderGenerator][]*Resource)
 	regularResources := []*Resource{}
@@ -138,19 +131,43 @@ func RefreshResourcesByProvider(providersMapping *ProvidersMapping, providerWrap
 		spResourcesList = append(spResourcesList, slowProcessingResources[p])
 	}
 
-	refreshedResources, err := RefreshResources(regularResources, providerWrapper, spResourcesList)
-	if err != nil {
+	refresh := func(r *Resource) error {
+		provider := providersMapping.MatchProvider(r)
+		return cache.Refresh(r, CacheScope(provider, providersMapping.providerToService[provider]), providerWrapper)
+	}
+	refreshedResources, err := refreshResources(regularResources, refresh, spResourcesList, scheduler)
+	var dropped RefreshErrors
+	if errors.As(err, &dropped) {
+		for _, refreshError := range dropped {
+			refreshError.Service = providersMapping.providerToService[providersMapping.MatchProvider(refreshError.resource)]
+		}
+	} else if err != nil {
 		return err
 	}
 
 	providersMapping.SetResources(refreshedResources)
+	if len(dropped) > 0 {
+		return dropped
+	}
 	return nil
 }
 
-func RefreshResourceWorker(input chan *Resource, wg *sync.WaitGroup, provider *providerwrapper.ProviderWrapper) {
+func RefreshResourceWorker(input chan *Resource, wg *sync.WaitGroup, refresh func(*Resource) error, scheduler *Scheduler, errs *refreshErrors) {
 	for r := range input {
+		scheduler.Run(func() {
+			log.Println("Refreshing state...", r.InstanceInfo.Id)
+			errs.add(r, refresh(r))
+		})
+		wg.Done()
+	}
+}
+
+// resources requiring slow queries are refreshed one by one, outside of the parallelism bound
+func slowRefreshResourceWorker(input chan *Resource, wg *sync.WaitGroup, refresh func(*Resource) error, scheduler *Scheduler, errs *refreshErrors) {
+	for r := range input {
+		scheduler.Wait()
 		log.Println("Refreshing state...", r.InstanceInfo.Id)
-		r.Refresh(provider)
+		errs.add(r, refresh(r))
 		wg.Done()
 	}
 }
@@ -213,6 +230,28 @@ func FilterCleanup(s *Service, isInitial bool) {
 	s.Resources = newListOfResources
 }
 
+// SelectResources keeps resources passing all filters like FilterCleanup does, with exclude it drops
+// resources which match all filters applicable to them instead
+func SelectResources(resources []Resource, filters []ResourceFilter, exclude bool) []Resource {
+	var selected []Resource
+	for _, resource := range resources {
+		serviceName := strings.TrimPrefix(resource.InstanceInfo.Type, resource.Provider+"_")
+		applicable, matched := false, true
+		for _, filter := range filters {
+			if filter.Expression == nil && !filter.IsApplicable(serviceName) {
+				continue
+			}
+			applicable = true
+			matched = matched && filter.Filter(resource)
+		}
+		if exclude && applicable && matched || !exclude && !matched {
+			continue
+		}
+		selected = append(selected, resource)
+	}
+	return selected
+}
+
 func ContainsResource(s []Resource, e Resource) bool {
 	for _, a := range s {
 		if a.InstanceInfo.Id == e.InstanceInfo.Id {